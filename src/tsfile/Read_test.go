@@ -21,7 +21,10 @@ func TestRead(t *testing.T) {
 
 	file := "D:/test.ts"
 	f := new(read.TsFileSequenceReader)
-	f.Open(file)
+	if err := f.Open(file); err != nil {
+		log.Println("Error:", err)
+		return
+	}
 	defer f.Close()
 
 	headerString := f.ReadHeadMagic()
@@ -30,22 +33,42 @@ func TestRead(t *testing.T) {
 	tailerString := f.ReadTailMagic()
 	log.Println("Tail string: " + tailerString)
 
-	fileMetadata := f.ReadFileMetadata()
+	fileMetadata, err := f.ReadFileMetadata()
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
 	log.Println("File version: " + strconv.Itoa(fileMetadata.GetCurrentVersion()))
 
 	for f.HasNextRowGroup() {
-		groupHeader := f.ReadRowGroupHeader()
+		groupHeader, err := f.ReadRowGroupHeader()
+		if err != nil {
+			log.Println("Error:", err)
+			return
+		}
 		log.Println("row group: " + groupHeader.GetDevice() + ", chunk number: " + strconv.Itoa(int(groupHeader.GetNumberOfChunks())) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
 		for i := 0; i < int(groupHeader.GetNumberOfChunks()); i++ {
-			chunkHeader := f.ReadChunkHeader()
+			chunkHeader, err := f.ReadChunkHeader()
+			if err != nil {
+				log.Println("Error:", err)
+				return
+			}
 			log.Println("  chunk: " + chunkHeader.GetSensor() + ", page number: " + strconv.Itoa(chunkHeader.GetNumberOfPages()) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
 			defaultTimeDecoder := decoder.CreateDecoder(constant.PLAIN, constant.INT64)
 			valueDecoder := decoder.CreateDecoder(chunkHeader.GetEncodingType(), chunkHeader.GetDataType())
 			for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
-				pageHeader := f.ReadPageHeader(chunkHeader.GetDataType())
+				pageHeader, err := f.ReadPageHeader(chunkHeader.GetDataType())
+				if err != nil {
+					log.Println("Error:", err)
+					return
+				}
 				log.Println("    page dps: " + strconv.Itoa(int(pageHeader.GetNumberOfValues())) + ", page data size: " + strconv.Itoa(int(pageHeader.GetCompressedSize())) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
 
-				pageData := f.ReadPage(pageHeader, chunkHeader.GetCompressionType())
+				pageData, err := f.ReadPage(pageHeader, chunkHeader.GetCompressionType())
+				if err != nil {
+					log.Println("Error:", err)
+					return
+				}
 				reader1 := &basic.PageDataReader{DataType: chunkHeader.GetDataType(), ValueDecoder: valueDecoder, TimeDecoder: defaultTimeDecoder}
 				reader1.Read(pageData)
 				for reader1.HasNext() {
@@ -23,7 +23,10 @@ func TestRead(strPath string) {
 
 	//file := "goout/output1.ts"
 	f := new(read.TsFileSequenceReader)
-	f.Open(strPath)
+	if err := f.Open(strPath); err != nil {
+		log.Println("Error:", err)
+		return
+	}
 	defer f.Close()
 
 	headerString := f.ReadHeadMagic()
@@ -32,22 +35,42 @@ func TestRead(strPath string) {
 	tailerString := f.ReadTailMagic()
 	log.Println("Tail string: " + tailerString)
 
-	fileMetadata := f.ReadFileMetadata()
+	fileMetadata, err := f.ReadFileMetadata()
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
 	log.Println("File version: " + strconv.Itoa(fileMetadata.GetCurrentVersion()))
 
 	for f.HasNextRowGroup() {
-		groupHeader := f.ReadRowGroupHeader()
+		groupHeader, err := f.ReadRowGroupHeader()
+		if err != nil {
+			log.Println("Error:", err)
+			return
+		}
 		log.Println("row group: " + groupHeader.GetDevice() + ", chunk number: " + strconv.Itoa(int(groupHeader.GetNumberOfChunks())) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
 		for i := 0; i < int(groupHeader.GetNumberOfChunks()); i++ {
-			chunkHeader := f.ReadChunkHeader()
+			chunkHeader, err := f.ReadChunkHeader()
+			if err != nil {
+				log.Println("Error:", err)
+				return
+			}
 			log.Println("  chunk: " + chunkHeader.GetSensor() + ", page number: " + strconv.Itoa(chunkHeader.GetNumberOfPages()) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
 			defaultTimeDecoder := decoder.CreateDecoder(constant.TS_2DIFF, constant.INT64)
 			valueDecoder := decoder.CreateDecoder(chunkHeader.GetEncodingType(), chunkHeader.GetDataType())
 			for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
-				pageHeader := f.ReadPageHeader(chunkHeader.GetDataType())
+				pageHeader, err := f.ReadPageHeader(chunkHeader.GetDataType())
+				if err != nil {
+					log.Println("Error:", err)
+					return
+				}
 				log.Println("    page dps: " + strconv.Itoa(int(pageHeader.GetNumberOfValues())) + ", page data size: " + strconv.Itoa(int(pageHeader.GetCompressedSize())) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
 
-				pageData := f.ReadPage(pageHeader, chunkHeader.GetCompressionType())
+				pageData, err := f.ReadPage(pageHeader, chunkHeader.GetCompressionType())
+				if err != nil {
+					log.Println("Error:", err)
+					return
+				}
 				reader1 := &basic.PageDataReader{DataType: chunkHeader.GetDataType(), ValueDecoder: valueDecoder, TimeDecoder: defaultTimeDecoder}
 				reader1.Read(pageData)
 				for reader1.HasNext() {
@@ -69,7 +92,10 @@ func TestReadFile(ts *ReadTestResult, bDebugValue bool) time.Duration {
 
 	tsCurNew := time.Now()
 	f := new(read.TsFileSequenceReader)
-	f.Open(ts.StrTsFile)
+	if err := f.Open(ts.StrTsFile); err != nil {
+		log.Println("Error:", err)
+		return time.Since(tsCurNew)
+	}
 	defer f.Close()
 
 	_ = f.ReadHeadMagic()
@@ -78,30 +104,49 @@ func TestReadFile(ts *ReadTestResult, bDebugValue bool) time.Duration {
 	_ = f.ReadTailMagic()
 	//log.Println("Tail string: " + tailerString)
 
-	_ = f.ReadFileMetadata()
+	if _, err := f.ReadFileMetadata(); err != nil {
+		log.Println("Error:", err)
+		return time.Since(tsCurNew)
+	}
 	//log.Println("File version: " + strconv.Itoa(fileMetadata.GetCurrentVersion()))
 
 	var pair *datatype.TimeValuePair = &datatype.TimeValuePair{}
 	//var curTime time.Time
 	for f.HasNextRowGroup() {
 		//curTime = time.Now()
-		groupHeader := f.ReadRowGroupHeader()
+		groupHeader, err := f.ReadRowGroupHeader()
+		if err != nil {
+			log.Println("Error:", err)
+			return time.Since(tsCurNew)
+		}
 		//ts.CostTimeTest1 += time.Since(curTime).Nanoseconds()
 
 		//log.Println("row group: " + groupHeader.GetDevice() + ", chunk number: " + strconv.Itoa(int(groupHeader.GetNumberOfChunks())) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
 		for i := 0; i < int(groupHeader.GetNumberOfChunks()); i++ {
 			//curTime = time.Now()
-			chunkHeader := f.ReadChunkHeader()
+			chunkHeader, err := f.ReadChunkHeader()
+			if err != nil {
+				log.Println("Error:", err)
+				return time.Since(tsCurNew)
+			}
 			//log.Println("  chunk: " + chunkHeader.GetSensor() + ", page number: " + strconv.Itoa(chunkHeader.GetNumberOfPages()) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
 			defaultTimeDecoder := decoder.CreateDecoder(constant.TS_2DIFF, constant.INT64)
 			valueDecoder := decoder.CreateDecoder(chunkHeader.GetEncodingType(), chunkHeader.GetDataType())
 			//ts.CostTimeTest2 += time.Since(curTime).Nanoseconds()
 			for j := 0; j < chunkHeader.GetNumberOfPages(); j++ {
 				//curTime = time.Now()
-				pageHeader := f.ReadPageHeader(chunkHeader.GetDataType())
+				pageHeader, err := f.ReadPageHeader(chunkHeader.GetDataType())
+				if err != nil {
+					log.Println("Error:", err)
+					return time.Since(tsCurNew)
+				}
 				//log.Println("    page dps: " + strconv.Itoa(int(pageHeader.GetNumberOfValues())) + ", page data size: " + strconv.Itoa(int(pageHeader.GetCompressedSize())) + ", end posistion: " + strconv.FormatInt(f.Pos(), 10))
 
-				pageData := f.ReadPage(pageHeader, chunkHeader.GetCompressionType())
+				pageData, err := f.ReadPage(pageHeader, chunkHeader.GetCompressionType())
+				if err != nil {
+					log.Println("Error:", err)
+					return time.Since(tsCurNew)
+				}
 				reader1 := &basic.PageDataReader{DataType: chunkHeader.GetDataType(), ValueDecoder: valueDecoder, TimeDecoder: defaultTimeDecoder}
 				reader1.Read(pageData)
 				//ts.CostTimeTest3 += time.Since(curTime).Nanoseconds()
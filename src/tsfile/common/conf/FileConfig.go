@@ -1,10 +1,8 @@
 package conf
 
 import (
-	"bufio"
 	"log"
 	"os"
-	"strconv"
 	"strings"
 )
 
@@ -56,6 +54,10 @@ var Compressor string = "UNCOMPRESSED"
 // Default block size of two-diff. delta encoding is 128
 var DeltaBlockSize = 128
 
+// Whether the digests of numeric chunks also store extended statistics: the count, sum of squares, a histogram and a
+// quantile sketch of their values, see metadata.ExtendedStatistics. Default value is false.
+var ExtendedDigest bool = false
+
 // Current version is 3
 var CurrentVersion = 3
 
@@ -68,43 +70,31 @@ func init() {
 	loadProperties()
 }
 
+// loadProperties sets the package level variables to the settings of tsfile-format.properties, keeping their default
+// values if the file is missing or invalid.
 func loadProperties() {
 	file, err := os.Open(CONFIG_FILE_NAME)
-	defer file.Close()
 	if err != nil {
 		log.Println("Warn:", err)
 		return
 	}
+	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		if k, v := loadItem(scanner.Text()); v != "" {
-			switch {
-			case k == "group_size_in_byte":
-				GroupSizeInByte, _ = strconv.Atoi(v)
-			case k == "page_size_in_byte":
-				PageSizeInByte, _ = strconv.Atoi(v)
-			case k == "max_number_of_points_in_page":
-				MaxNumberOfPointsInPage, _ = strconv.Atoi(v)
-			case k == "time_series_data_type":
-				TimeSeriesDataType = v
-			case k == "max_string_length":
-				MaxStringLength, _ = strconv.Atoi(v)
-			case k == "float_precision":
-				FloatPrecision, _ = strconv.Atoi(v)
-			case k == "time_series_encoder":
-				TimeSeriesEncoder = v
-			case k == "value_encoder":
-				ValueEncoder = v
-			case k == "compressor":
-				Compressor = v
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+	config, err := LoadProperties(file)
+	if err != nil {
+		log.Println("Warn:", err)
+		return
 	}
+	GroupSizeInByte = config.GroupSizeInByte
+	PageSizeInByte = config.PageSizeInByte
+	MaxNumberOfPointsInPage = config.MaxNumberOfPointsInPage
+	TimeSeriesDataType = config.TimeSeriesDataType
+	MaxStringLength = config.MaxStringLength
+	FloatPrecision = config.FloatPrecision
+	TimeSeriesEncoder = config.TimeSeriesEncoder
+	ValueEncoder = config.ValueEncoder
+	Compressor = config.Compressor
+	ExtendedDigest = config.ExtendedDigest
 }
 
 func loadItem(text string) (key string, value string) {
@@ -0,0 +1,276 @@
+package conf
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"tsfile/common/constant"
+)
+
+// WriterConfig holds the settings of a TsFile writer, so that writers of the same process may use different ones. Its
+// keys, in properties, JSON and YAML files as well as in environment variables, are those of tsfile-format.properties.
+// The package level variables are the settings of the writers created without a config, see DefaultWriterConfig.
+type WriterConfig struct {
+	// Memory size threshold for flushing a row group
+	GroupSizeInByte int `json:"group_size_in_byte"`
+	// The memory size for each series writer to pack page
+	PageSizeInByte int `json:"page_size_in_byte"`
+	// The maximum number of data points in a page
+	MaxNumberOfPointsInPage int `json:"max_number_of_points_in_page"`
+	// Data type for input timestamp, only INT64 is supported
+	TimeSeriesDataType string `json:"time_series_data_type"`
+	// Max length limitation of input string
+	MaxStringLength int `json:"max_string_length"`
+	// Number of decimal digits kept by the RLE and TS_2DIFF encoders of floating-point values
+	FloatPrecision int `json:"float_precision"`
+	// Encoder of time series: TS_2DIFF, PLAIN or RLE
+	TimeSeriesEncoder string `json:"time_series_encoder"`
+	// Default encoder of value series, see sensorDescriptor.NewWithConfig
+	ValueEncoder string `json:"value_encoder"`
+	// Default compressor of value series: UNCOMPRESSED or SNAPPY, see sensorDescriptor.NewWithConfig
+	Compressor string `json:"compressor"`
+	// Whether the digests of numeric chunks also store extended statistics
+	ExtendedDigest bool `json:"extended_digest"`
+}
+
+// ErrUnknownKey rejects a key that is not one of a WriterConfig.
+var ErrUnknownKey = errors.New("unknown key")
+
+// ConfigError rejects the value of a key of a WriterConfig, Key being the name of the environment variable if the
+// value was read from one.
+type ConfigError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid writer config %s=%q: %v", e.Key, e.Value, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// writerConfigKeys are the keys of a WriterConfig, in the order of its fields.
+var writerConfigKeys = []string{"group_size_in_byte", "page_size_in_byte", "max_number_of_points_in_page",
+	"time_series_data_type", "max_string_length", "float_precision", "time_series_encoder", "value_encoder",
+	"compressor", "extended_digest"}
+
+// DefaultWriterConfig returns a config holding the current values of the package level variables, those of
+// tsfile-format.properties if the working directory holds one.
+func DefaultWriterConfig() *WriterConfig {
+	return &WriterConfig{
+		GroupSizeInByte:         GroupSizeInByte,
+		PageSizeInByte:          PageSizeInByte,
+		MaxNumberOfPointsInPage: MaxNumberOfPointsInPage,
+		TimeSeriesDataType:      TimeSeriesDataType,
+		MaxStringLength:         MaxStringLength,
+		FloatPrecision:          FloatPrecision,
+		TimeSeriesEncoder:       TimeSeriesEncoder,
+		ValueEncoder:            ValueEncoder,
+		Compressor:              Compressor,
+		ExtendedDigest:          ExtendedDigest,
+	}
+}
+
+// Set parses the value of a key, e.g. "page_size_in_byte", returning a ConfigError if the key is unknown or the value
+// cannot be parsed. The value is checked by Validate only.
+func (c *WriterConfig) Set(key string, value string) error {
+	var err error
+	switch key {
+	case "group_size_in_byte":
+		c.GroupSizeInByte, err = strconv.Atoi(value)
+	case "page_size_in_byte":
+		c.PageSizeInByte, err = strconv.Atoi(value)
+	case "max_number_of_points_in_page":
+		c.MaxNumberOfPointsInPage, err = strconv.Atoi(value)
+	case "time_series_data_type":
+		c.TimeSeriesDataType = value
+	case "max_string_length":
+		c.MaxStringLength, err = strconv.Atoi(value)
+	case "float_precision":
+		c.FloatPrecision, err = strconv.Atoi(value)
+	case "time_series_encoder":
+		c.TimeSeriesEncoder = value
+	case "value_encoder":
+		c.ValueEncoder = value
+	case "compressor":
+		c.Compressor = value
+	case "extended_digest":
+		c.ExtendedDigest, err = strconv.ParseBool(value)
+	default:
+		err = ErrUnknownKey
+	}
+	if err != nil {
+		return &ConfigError{Key: key, Value: value, Err: err}
+	}
+	return nil
+}
+
+// Validate returns a ConfigError naming the first key whose value cannot be written with.
+func (c *WriterConfig) Validate() error {
+	invalid := func(key string, value interface{}, format string, a ...interface{}) error {
+		return &ConfigError{Key: key, Value: fmt.Sprint(value), Err: fmt.Errorf(format, a...)}
+	}
+	switch {
+	case c.GroupSizeInByte <= 0:
+		return invalid("group_size_in_byte", c.GroupSizeInByte, "must be positive")
+	case c.PageSizeInByte <= 0:
+		return invalid("page_size_in_byte", c.PageSizeInByte, "must be positive")
+	case c.MaxNumberOfPointsInPage <= 0:
+		return invalid("max_number_of_points_in_page", c.MaxNumberOfPointsInPage, "must be positive")
+	case c.TimeSeriesDataType != constant.INT64.String():
+		return invalid("time_series_data_type", c.TimeSeriesDataType, "must be INT64")
+	case c.MaxStringLength <= 0:
+		return invalid("max_string_length", c.MaxStringLength, "must be positive")
+	case c.FloatPrecision < 0:
+		return invalid("float_precision", c.FloatPrecision, "must not be negative")
+	}
+
+	// the encodings having an encoder of INT64 values, resp. of those of any data type
+	if encoding, ok := constant.LookupEncoding(c.TimeSeriesEncoder); !ok ||
+		(encoding != constant.PLAIN && encoding != constant.RLE && encoding != constant.TS_2DIFF) {
+		return invalid("time_series_encoder", c.TimeSeriesEncoder, "must be one of PLAIN, RLE and TS_2DIFF")
+	}
+	if encoding, ok := constant.LookupEncoding(c.ValueEncoder); !ok || (encoding != constant.PLAIN &&
+		encoding != constant.RLE && encoding != constant.TS_2DIFF && encoding != constant.GORILLA) {
+		return invalid("value_encoder", c.ValueEncoder, "must be one of PLAIN, RLE, TS_2DIFF and GORILLA")
+	}
+	if compression, ok := constant.LookupCompression(c.Compressor); !ok ||
+		(compression != constant.UNCOMPRESSED && compression != constant.SNAPPY) {
+		return invalid("compressor", c.Compressor, "must be one of UNCOMPRESSED and SNAPPY")
+	}
+	return nil
+}
+
+// LoadWriterConfig reads a config from a properties, JSON or YAML file, depending on the extension of its name:
+// .properties, .json, .yaml or .yml.
+func LoadWriterConfig(path string) (*WriterConfig, error) {
+	var load func(io.Reader) (*WriterConfig, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".properties":
+		load = LoadProperties
+	case ".json":
+		load = LoadJSON
+	case ".yaml", ".yml":
+		load = LoadYAML
+	default:
+		return nil, fmt.Errorf("unknown writer config format of %s", path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	config, err := load(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// LoadProperties reads a config from key=value lines, e.g. those of tsfile-format.properties, the keys missing
+// keeping their default value. Unknown keys are ignored, as such files may hold the settings of other components.
+func LoadProperties(r io.Reader) (*WriterConfig, error) {
+	config := DefaultWriterConfig()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if k, v := loadItem(scanner.Text()); v != "" {
+			if err := config.Set(k, v); err != nil && !errors.Is(err, ErrUnknownKey) {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return validated(config)
+}
+
+// LoadJSON reads a config from a JSON object, e.g. {"page_size_in_byte": 65536, "compressor": "SNAPPY"}, the keys
+// missing keeping their default value. Unknown keys are rejected.
+func LoadJSON(r io.Reader) (*WriterConfig, error) {
+	config := DefaultWriterConfig()
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, &ConfigError{Key: typeErr.Field, Value: typeErr.Value, Err: err}
+		}
+		return nil, fmt.Errorf("invalid writer config: %w", err)
+	}
+	return validated(config)
+}
+
+// LoadYAML reads a config from a YAML mapping of keys to scalars, e.g. "compressor: SNAPPY" lines, the keys missing
+// keeping their default value. Unknown keys are rejected, as are nested values.
+func LoadYAML(r io.Reader) (*WriterConfig, error) {
+	config := DefaultWriterConfig()
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if trimmed != text[:len(trimmed)] {
+			return nil, fmt.Errorf("invalid writer config: line %d: nested values are not supported", line)
+		}
+		i := strings.Index(text, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid writer config: line %d: expected key: value", line)
+		}
+		key, value := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		if n := len(value); n >= 2 && (value[0] == '"' || value[0] == '\'') && value[n-1] == value[0] {
+			value = value[1 : n-1]
+		} else if j := strings.Index(value, " #"); j >= 0 {
+			value = strings.TrimSpace(value[:j])
+		}
+		if err := config.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return validated(config)
+}
+
+// LoadEnv reads a config from the environment variables named after the keys prefixed, e.g. TSFILE_PAGE_SIZE_IN_BYTE
+// for the prefix "TSFILE_", the keys missing keeping their default value.
+func LoadEnv(prefix string) (*WriterConfig, error) {
+	config := DefaultWriterConfig()
+	if err := config.ApplyEnv(prefix); err != nil {
+		return nil, err
+	}
+	return validated(config)
+}
+
+// ApplyEnv overrides the keys of the config with the environment variables named after them prefixed, see LoadEnv.
+func (c *WriterConfig) ApplyEnv(prefix string) error {
+	for _, key := range writerConfigKeys {
+		name := prefix + strings.ToUpper(key)
+		if value, ok := os.LookupEnv(name); ok {
+			if err := c.Set(key, value); err != nil {
+				err.(*ConfigError).Key = name
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validated(config *WriterConfig) (*WriterConfig, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
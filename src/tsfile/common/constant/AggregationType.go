@@ -0,0 +1,55 @@
+package constant
+
+import "strings"
+
+type AggregationType int8
+
+const (
+	COUNT       AggregationType = 0
+	SUM         AggregationType = 1
+	MIN_VALUE   AggregationType = 2
+	MAX_VALUE   AggregationType = 3
+	FIRST_VALUE AggregationType = 4
+	LAST_VALUE  AggregationType = 5
+	AVG         AggregationType = 6
+	MIN_TIME    AggregationType = 7
+	MAX_TIME    AggregationType = 8
+	// the percentiles and the number of distinct values are approximate, computed from sketches of the points
+	P50                   AggregationType = 9
+	P95                   AggregationType = 10
+	P99                   AggregationType = 11
+	APPROX_COUNT_DISTINCT AggregationType = 12
+	// the population variance and standard deviation
+	VARIANCE AggregationType = 13
+	STDDEV   AggregationType = 14
+)
+
+var aggregationNames = []string{"COUNT", "SUM", "MIN_VALUE", "MAX_VALUE", "FIRST_VALUE", "LAST_VALUE", "AVG",
+	"MIN_TIME", "MAX_TIME", "P50", "P95", "P99", "APPROX_COUNT_DISTINCT",
+	"VARIANCE", "STDDEV"}
+
+func (a AggregationType) String() string {
+	if a < 0 || int(a) >= len(aggregationNames) {
+		return "UNKNOWN"
+	}
+	return aggregationNames[a]
+}
+
+// GetAggregationByName is case insensitive, e.g. both "count" and "COUNT" give COUNT.
+func GetAggregationByName(name string) AggregationType {
+	if aggregation, ok := LookupAggregation(name); ok {
+		return aggregation
+	}
+	panic("No aggregation found: " + name)
+}
+
+// LookupAggregation is GetAggregationByName that reports unknown names with ok instead of panicking.
+func LookupAggregation(name string) (aggregation AggregationType, ok bool) {
+	upper := strings.ToUpper(name)
+	for i, n := range aggregationNames {
+		if n == upper {
+			return AggregationType(i), true
+		}
+	}
+	return 0, false
+}
@@ -11,3 +11,22 @@ const (
 	PAA          CompressionType = 5
 	PLA          CompressionType = 6
 )
+
+var compressionNames = []string{"UNCOMPRESSED", "SNAPPY", "GZIP", "LZO", "SDT", "PAA", "PLA"}
+
+func (c CompressionType) String() string {
+	if c < 0 || int(c) >= len(compressionNames) {
+		return "UNKNOWN"
+	}
+	return compressionNames[c]
+}
+
+// LookupCompression returns the compression type of a name, e.g. "SNAPPY", ok being false if it is unknown.
+func LookupCompression(name string) (compression CompressionType, ok bool) {
+	for i, n := range compressionNames {
+		if n == name {
+			return CompressionType(i), true
+		}
+	}
+	return 0, false
+}
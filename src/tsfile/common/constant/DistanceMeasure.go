@@ -0,0 +1,11 @@
+package constant
+
+// DistanceMeasure is how the distance between the pattern of a similarity search and a window of a series is measured.
+// EUCLIDEAN compares the points of both one to one, DTW (dynamic time warping) lets points match points shifted in
+// time, so that patterns stretched or compressed in time still match.
+type DistanceMeasure int8
+
+const (
+	EUCLIDEAN DistanceMeasure = 0
+	DTW       DistanceMeasure = 1
+)
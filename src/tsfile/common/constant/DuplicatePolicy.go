@@ -0,0 +1,10 @@
+package constant
+
+// DuplicatePolicy decides which file a point comes from when several files queried together hold a point of the same
+// series at the same timestamp.
+type DuplicatePolicy int8
+
+const (
+	NEWEST_FILE_WINS DuplicatePolicy = 0
+	OLDEST_FILE_WINS DuplicatePolicy = 1
+)
@@ -0,0 +1,9 @@
+package constant
+
+type FillType int8
+
+const (
+	PREVIOUS_FILL FillType = 0
+	LINEAR_FILL   FillType = 1
+	CONSTANT_FILL FillType = 2
+)
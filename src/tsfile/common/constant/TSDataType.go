@@ -12,6 +12,15 @@ const (
 	INVALID TSDataType = -1
 )
 
+var dataTypeNames = []string{"BOOLEAN", "INT32", "INT64", "FLOAT", "DOUBLE", "TEXT"}
+
+func (t TSDataType) String() string {
+	if t < 0 || int(t) >= len(dataTypeNames) {
+		return "INVALID"
+	}
+	return dataTypeNames[t]
+}
+
 const (
 	BOOLEAN_LEN int = 1
 	SHORT_LEN   int = 2
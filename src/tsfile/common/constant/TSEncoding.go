@@ -12,23 +12,28 @@ const (
 	GORILLA          TSEncoding = 6
 )
 
+var encodingNames = []string{"PLAIN", "PLAIN_DICTIONARY", "RLE", "DIFF", "TS_2DIFF", "BITMAP", "GORILLA"}
+
+func (e TSEncoding) String() string {
+	if e < 0 || int(e) >= len(encodingNames) {
+		return "UNKNOWN"
+	}
+	return encodingNames[e]
+}
+
 func GetEncodingByName(name string) TSEncoding {
-	switch name {
-	case "PLAIN":
-		return PLAIN
-	case "PLAIN_DICTIONARY":
-		return PLAIN_DICTIONARY
-	case "RLE":
-		return RLE
-	case "DIFF":
-		return DIFF
-	case "TS_2DIFF":
-		return TS_2DIFF
-	case "BITMAP":
-		return BITMAP
-	case "GORILLA":
-		return GORILLA
-	default:
-		panic("No encoding found: " + name)
+	if encoding, ok := LookupEncoding(name); ok {
+		return encoding
+	}
+	panic("No encoding found: " + name)
+}
+
+// LookupEncoding is GetEncodingByName that reports unknown names with ok instead of panicking.
+func LookupEncoding(name string) (encoding TSEncoding, ok bool) {
+	for i, n := range encodingNames {
+		if n == name {
+			return TSEncoding(i), true
+		}
 	}
+	return 0, false
 }
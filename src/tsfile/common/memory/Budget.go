@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+)
+
+// Estimated sizes of the structures of a query accounted to a budget besides page buffers.
+const (
+	// PairSize is the size of a decoded point, a TimeValuePair with its boxed value
+	PairSize = 48
+	// ValueSize is the size of a boxed value in a row
+	ValueSize = 24
+)
+
+// Budget accounts the memory held by one query against a limit. Once a reservation would exceed the limit, the budget
+// cancels its context with a *BudgetExceededError, which stops the readers of the query like any other cancellation
+// and is returned by context.Cause. A Budget is used by the goroutine running the query only. The methods of a nil
+// Budget do nothing, so code accounting memory does not check whether there is a budget.
+type Budget struct {
+	limit  int64
+	used   int64
+	cancel context.CancelCauseFunc
+}
+
+// WithBudget returns a budget of limit bytes and a context derived from parent which is cancelled when the budget is
+// exceeded.
+func WithBudget(parent context.Context, limit int64) (context.Context, *Budget) {
+	ctx, cancel := context.WithCancelCause(parent)
+	return ctx, &Budget{limit: limit, cancel: cancel}
+}
+
+// Reserve accounts size more bytes, or fails with a *BudgetExceededError and cancels the context of the budget if
+// they do not fit into it, in which case nothing is reserved.
+func (b *Budget) Reserve(size int64) error {
+	if b == nil {
+		return nil
+	}
+	if b.used+size > b.limit {
+		err := &BudgetExceededError{Limit: b.limit, Used: b.used, Requested: size}
+		b.cancel(err)
+		return err
+	}
+	b.used += size
+	return nil
+}
+
+// Release gives back size bytes reserved before.
+func (b *Budget) Release(size int64) {
+	if b != nil {
+		b.used -= size
+	}
+}
+
+// Used returns the number of bytes reserved and not released yet.
+func (b *Budget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.used
+}
+
+// BudgetExceededError fails a query which would hold more memory than its budget.
+type BudgetExceededError struct {
+	Limit     int64
+	Used      int64
+	Requested int64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("query memory budget of %d bytes exceeded: %d bytes in use, %d more requested", e.Limit,
+		e.Used, e.Requested)
+}
@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBudget(t *testing.T) {
+	ctx, budget := WithBudget(context.Background(), 100)
+	if err := budget.Reserve(60); err != nil {
+		t.Fatal(err)
+	}
+	budget.Release(20)
+	if err := budget.Reserve(60); err != nil || budget.Used() != 100 {
+		t.Fatal(fmt.Sprintf("Expected the budget to be used up got %d, %v", budget.Used(), err))
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Expected the context to be live while the reservations fit")
+	}
+
+	// a reservation not fitting is not made and cancels the context with the error
+	var exceeded *BudgetExceededError
+	if err := budget.Reserve(1); !errors.As(err, &exceeded) || exceeded.Limit != 100 || exceeded.Used != 100 ||
+		exceeded.Requested != 1 {
+		t.Fatal(fmt.Sprintf("Expected the budget to be exceeded got %v", err))
+	}
+	if budget.Used() != 100 {
+		t.Fatal(fmt.Sprintf("Expected 100 bytes reserved got %d", budget.Used()))
+	}
+	if ctx.Err() == nil || !errors.As(context.Cause(ctx), &exceeded) {
+		t.Fatal(fmt.Sprintf("Expected the context to be cancelled by the budget got %v", context.Cause(ctx)))
+	}
+
+	// a nil budget accounts nothing
+	var none *Budget
+	if err := none.Reserve(1 << 40); err != nil || none.Used() != 0 {
+		t.Fatal(fmt.Sprintf("Expected a nil budget to accept anything got %v", err))
+	}
+	none.Release(1)
+}
@@ -0,0 +1,118 @@
+package sketch
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// hyperLogLogPrecision is the number of bits of a hash choosing its register, for estimates within about 1.6%.
+const hyperLogLogPrecision = 12
+
+// HyperLogLogSize is the memory held by a HyperLogLog sketch.
+const HyperLogLogSize = 1 << hyperLogLogPrecision
+
+// HyperLogLog estimates the number of distinct values of a stream (Flajolet et al., "HyperLogLog: the analysis of a
+// near-optimal cardinality estimation algorithm"). Every register keeps the longest run of leading zeros, plus one, of
+// the hashes it has seen. Two sketches merge into the sketch of both streams by keeping the larger registers.
+type HyperLogLog struct {
+	registers [HyperLogLogSize]uint8
+}
+
+// Add adds a value of any data type.
+func (s *HyperLogLog) Add(value interface{}) {
+	hash := hashValue(value)
+	register := hash >> (64 - hyperLogLogPrecision)
+	rest := hash<<hyperLogLogPrecision | 1<<(hyperLogLogPrecision-1)
+	if zeros := uint8(bits.LeadingZeros64(rest)) + 1; zeros > s.registers[register] {
+		s.registers[register] = zeros
+	}
+}
+
+// Merge adds the values of another sketch.
+func (s *HyperLogLog) Merge(other *HyperLogLog) {
+	for i, r := range other.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+}
+
+// Estimate returns the number of distinct values. It uses the improved estimator of Ertl ("New cardinality estimation algorithms for HyperLogLog sketches"),
+// which needs no bias correction for small or large cardinalities.
+func (s *HyperLogLog) Estimate() int64 {
+	const q = 64 - hyperLogLogPrecision
+	m := float64(HyperLogLogSize)
+	var counts [q + 2]float64
+	for _, r := range s.registers {
+		counts[r]++
+	}
+	z := m * hyperLogLogTau(1-counts[q+1]/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + counts[k])
+	}
+	z += m * hyperLogLogSigma(counts[0]/m)
+	return int64(math.Round(m * m / (2 * math.Ln2 * z)))
+}
+
+func hyperLogLogSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		previous := z
+		z += x * y
+		y += y
+		if z == previous {
+			return z
+		}
+	}
+}
+
+func hyperLogLogTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		previous := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == previous {
+			return z / 3
+		}
+	}
+}
+
+// hashValue hashes a value of any data type, mixing the bits so that close numbers have unrelated hashes.
+func hashValue(value interface{}) uint64 {
+	var x uint64
+	switch v := value.(type) {
+	case int32:
+		x = uint64(v)
+	case int64:
+		x = uint64(v)
+	case float32:
+		x = uint64(math.Float32bits(v))
+	case float64:
+		x = math.Float64bits(v)
+	case bool:
+		if v {
+			x = 1
+		}
+	case string:
+		h := fnv.New64a()
+		h.Write([]byte(v))
+		x = h.Sum64()
+	}
+	// the finalizer of splitmix64
+	x ^= x >> 30
+	x *= 0xBF58476D1CE4E5B9
+	x ^= x >> 27
+	x *= 0x94D049BB133111EB
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,49 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	s := new(HyperLogLog)
+	if s.Estimate() != 0 {
+		t.Fatal(fmt.Sprintf("Expected no value got %d", s.Estimate()))
+	}
+	// repeated values are counted once
+	for i := 0; i < 1000; i++ {
+		s.Add(int32(i % 10))
+	}
+	if s.Estimate() != 10 {
+		t.Fatal(fmt.Sprintf("Expected 10 distinct values got %d", s.Estimate()))
+	}
+
+	// estimates within 3%, for a sketch and for the merge of two sketches of overlapping values
+	s, a, b := new(HyperLogLog), new(HyperLogLog), new(HyperLogLog)
+	for i := 0; i < 100000; i++ {
+		s.Add(int64(i))
+		if i < 60000 {
+			a.Add(int64(i))
+		}
+		if i >= 40000 {
+			b.Add(int64(i))
+		}
+	}
+	a.Merge(b)
+	for _, sketch := range []*HyperLogLog{s, a} {
+		if got := sketch.Estimate(); math.Abs(float64(got)-100000) > 3000 {
+			t.Fatal(fmt.Sprintf("Expected 100000 distinct values within 3000 got %d", got))
+		}
+	}
+
+	// values of every data type
+	s = new(HyperLogLog)
+	for _, v := range []interface{}{false, int32(1), int64(2), float32(3), 4.0, "5"} {
+		s.Add(v)
+		s.Add(v)
+	}
+	if s.Estimate() != 6 {
+		t.Fatal(fmt.Sprintf("Expected 6 distinct values got %d", s.Estimate()))
+	}
+}
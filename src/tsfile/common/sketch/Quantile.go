@@ -0,0 +1,169 @@
+package sketch
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"sort"
+	"tsfile/common/utils"
+)
+
+// quantileK is the size of the largest compactor of quantile sketches, for ranks within about 1.7% of the number of
+// values.
+const quantileK = 200
+
+// QuantileSize is an estimate of the memory held by a quantile sketch, about 3k values.
+const QuantileSize = 3 * quantileK * 8
+
+// Quantile is a KLL sketch (Karnin, Lang and Liberty, "Optimal Quantile Approximation in Streams") of a stream
+// of values. compactors[h] holds values standing for 2^h values each. Once a compactor is full, it is sorted and every
+// other value, starting at random with the first or the second, is promoted to the next one. A sketch of fewer values
+// than k is exact, and two sketches merge into the sketch of both streams.
+type Quantile struct {
+	compactors [][]float64
+	size       int
+	maxSize    int
+	random     uint64
+}
+
+func NewQuantile() *Quantile {
+	s := &Quantile{random: 0x9E3779B97F4A7C15}
+	s.grow()
+	return s
+}
+
+// capacity of the compactor at height h, which shrinks by 2/3 per level below the top one.
+func (s *Quantile) capacity(h int) int {
+	depth := len(s.compactors) - h - 1
+	return int(math.Ceil(math.Pow(2.0/3.0, float64(depth))*quantileK)) + 1
+}
+
+func (s *Quantile) grow() {
+	s.compactors = append(s.compactors, nil)
+	s.maxSize = 0
+	for h := range s.compactors {
+		s.maxSize += s.capacity(h)
+	}
+}
+
+func (s *Quantile) Add(value float64) {
+	s.compactors[0] = append(s.compactors[0], value)
+	s.size++
+	if s.size >= s.maxSize {
+		s.compress()
+	}
+}
+
+// compress compacts the lowest full compactor into the next one.
+func (s *Quantile) compress() {
+	for h := 0; h < len(s.compactors); h++ {
+		compactor := s.compactors[h]
+		if len(compactor) < s.capacity(h) {
+			continue
+		}
+		if h+1 == len(s.compactors) {
+			s.grow()
+		}
+		sort.Float64s(compactor)
+		// xorshift, good enough for coin flips
+		s.random ^= s.random << 13
+		s.random ^= s.random >> 7
+		s.random ^= s.random << 17
+		offset := int(s.random & 1)
+		kept := len(compactor) % 2
+		for i := offset; i < len(compactor)-kept; i += 2 {
+			s.compactors[h+1] = append(s.compactors[h+1], compactor[i])
+		}
+		// an odd value out stays at this level
+		s.compactors[h] = append(compactor[:0], compactor[len(compactor)-kept:]...)
+		s.size = 0
+		for _, c := range s.compactors {
+			s.size += len(c)
+		}
+		return
+	}
+}
+
+// Merge adds the values of another sketch.
+func (s *Quantile) Merge(other *Quantile) {
+	for len(s.compactors) < len(other.compactors) {
+		s.grow()
+	}
+	for h, compactor := range other.compactors {
+		s.compactors[h] = append(s.compactors[h], compactor...)
+		s.size += len(compactor)
+	}
+	for s.size >= s.maxSize {
+		s.compress()
+	}
+}
+
+// Quantile returns the smallest value whose rank is at least q times the number of values, e.g. the median for 0.5,
+// NaN if there is none.
+func (s *Quantile) Quantile(q float64) float64 {
+	type weighted struct {
+		value  float64
+		weight int64
+	}
+	var values []weighted
+	var total int64
+	for h, compactor := range s.compactors {
+		for _, v := range compactor {
+			values = append(values, weighted{v, 1 << uint(h)})
+			total += 1 << uint(h)
+		}
+	}
+	if total == 0 {
+		return math.NaN()
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].value < values[j].value
+	})
+	rank := int64(math.Ceil(q * float64(total)))
+	var cumulative int64
+	for _, v := range values {
+		cumulative += v.weight
+		if cumulative >= rank {
+			return v.value
+		}
+	}
+	return values[len(values)-1].value
+}
+
+// Serialize writes the compactors of the sketch, each as its number of values followed by its values.
+func (s *Quantile) Serialize(buf *bytes.Buffer) {
+	buf.Write(utils.Int32ToByte(int32(len(s.compactors)), 0))
+	for _, compactor := range s.compactors {
+		buf.Write(utils.Int32ToByte(int32(len(compactor)), 0))
+		for _, v := range compactor {
+			buf.Write(utils.Float64ToByte(v, 0))
+		}
+	}
+}
+
+// DeserializeQuantile reads a sketch written by Serialize.
+func DeserializeQuantile(reader *utils.BytesReader) (*Quantile, error) {
+	s := &Quantile{random: 0x9E3779B97F4A7C15}
+	levels := reader.ReadInt()
+	// a level holds at least its length
+	if levels <= 0 || levels > reader.Len()/4 {
+		return nil, errors.New("invalid quantile sketch")
+	}
+	for h := int32(0); h < levels; h++ {
+		s.grow()
+		if reader.Len() < 4 {
+			return nil, errors.New("invalid quantile sketch")
+		}
+		n := reader.ReadInt()
+		if n < 0 || n > reader.Len()/8 {
+			return nil, errors.New("invalid quantile sketch")
+		}
+		compactor := make([]float64, n)
+		for i := range compactor {
+			compactor[i] = math.Float64frombits(uint64(reader.ReadLong()))
+		}
+		s.compactors[h] = compactor
+		s.size += len(compactor)
+	}
+	return s, nil
+}
@@ -0,0 +1,60 @@
+package sketch
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+	"tsfile/common/utils"
+)
+
+func TestQuantile(t *testing.T) {
+	// fewer values than the largest compactor holds give exact quantiles
+	s := NewQuantile()
+	if !math.IsNaN(s.Quantile(0.5)) {
+		t.Fatal("Expected no quantile of an empty sketch")
+	}
+	for i := 100; i >= 1; i-- {
+		s.Add(float64(i))
+	}
+	if s.Quantile(0.5) != 50 || s.Quantile(0.95) != 95 || s.Quantile(0) != 1 || s.Quantile(1) != 100 {
+		t.Fatal(fmt.Sprintf("Expected exact quantiles got %v, %v", s.Quantile(0.5), s.Quantile(0.95)))
+	}
+
+	// ranks within 2% of the number of values, for a sketch and for the merge of two sketches of half the values
+	s, a, b := NewQuantile(), NewQuantile(), NewQuantile()
+	for i := 0; i < 100000; i++ {
+		v := float64(i * 7919 % 100000)
+		s.Add(v)
+		if i%2 == 0 {
+			a.Add(v)
+		} else {
+			b.Add(v)
+		}
+	}
+	a.Merge(b)
+	for _, q := range []float64{0.01, 0.5, 0.99} {
+		for _, sketch := range []*Quantile{s, a} {
+			if got := sketch.Quantile(q); math.Abs(got-q*100000) > 2000 {
+				t.Fatal(fmt.Sprintf("Expected the %v quantile within 2000 of %v got %v", q, q*100000, got))
+			}
+		}
+	}
+
+	// a sketch reads back as written
+	buf := new(bytes.Buffer)
+	s.Serialize(buf)
+	read, err := DeserializeQuantile(utils.NewBytesReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []float64{0.01, 0.5, 0.99} {
+		if read.Quantile(q) != s.Quantile(q) {
+			t.Fatal(fmt.Sprintf("Expected the %v quantile %v got %v", q, s.Quantile(q), read.Quantile(q)))
+		}
+	}
+	// but not truncated
+	if _, err := DeserializeQuantile(utils.NewBytesReader(buf.Bytes()[:buf.Len()/2])); err == nil {
+		t.Fatal("Expected a truncated sketch to be rejected")
+	}
+}
@@ -2,18 +2,22 @@ package utils
 
 import (
 	"encoding/binary"
+	"fmt"
+	"io"
 	_ "log"
 	"math"
 )
 
 // bytes slice reader, result is the reference of source
+// Reading past the end or a negative length does not panic: the reader fails, see Err.
 type BytesReader struct {
 	buf []byte
 	pos int32
+	err error
 }
 
 func NewBytesReader(data []byte) *BytesReader {
-	return &BytesReader{data, 0}
+	return &BytesReader{buf: data}
 }
 
 func (r *BytesReader) Pos() int32 {
@@ -28,7 +32,41 @@ func (r *BytesReader) Remaining() []byte {
 	return r.buf[r.pos:]
 }
 
+// Err returns the error reading failed with, nil unless the data is truncated or corrupted. Once reading failed, the
+// reader is exhausted and returns zero values.
+func (r *BytesReader) Err() error {
+	return r.err
+}
+
+// Fail makes reading fail at the current position, e.g. once a decoder found a value it cannot decode.
+func (r *BytesReader) Fail(format string, a ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf("position %d: %s", r.pos, fmt.Sprintf(format, a...))
+	}
+	r.pos = int32(len(r.buf))
+}
+
+// fits tells whether length more bytes can be read, failing otherwise.
+func (r *BytesReader) fits(length int32) bool {
+	if length >= 0 && length <= r.Len() {
+		return true
+	}
+	if length < 0 {
+		r.Fail("invalid length %d", length)
+	} else {
+		if r.err == nil {
+			r.err = fmt.Errorf("cannot read %d bytes at position %d of %d: %w", length, r.pos, len(r.buf),
+				io.ErrUnexpectedEOF)
+		}
+		r.pos = int32(len(r.buf))
+	}
+	return false
+}
+
 func (r *BytesReader) ReadBool() bool {
+	if !r.fits(1) {
+		return false
+	}
 	result := (r.buf[r.pos] == 1)
 	r.pos += 1
 
@@ -36,6 +74,9 @@ func (r *BytesReader) ReadBool() bool {
 }
 
 func (r *BytesReader) ReadShort() int16 {
+	if !r.fits(2) {
+		return 0
+	}
 	result := int16(binary.BigEndian.Uint16(r.buf[r.pos : r.pos+2]))
 	r.pos += 2
 
@@ -43,24 +84,36 @@ func (r *BytesReader) ReadShort() int16 {
 }
 
 func (r *BytesReader) ReadInt() int32 {
+	if !r.fits(4) {
+		return 0
+	}
 	bytes := r.buf[r.pos : r.pos+4]
 	r.pos += 4
 	return int32(binary.BigEndian.Uint32(bytes))
 }
 
 func (r *BytesReader) ReadLong() int64 {
+	if !r.fits(8) {
+		return 0
+	}
 	result := int64(binary.BigEndian.Uint64(r.buf[r.pos : r.pos+8]))
 	r.pos += 8
 	return result
 }
 
 func (r *BytesReader) ReadFloat() float32 {
+	if !r.fits(4) {
+		return 0
+	}
 	bits := binary.LittleEndian.Uint32(r.buf[r.pos : r.pos+4])
 	r.pos += 4
 	return math.Float32frombits(bits)
 }
 
 func (r *BytesReader) ReadDouble() float64 {
+	if !r.fits(8) {
+		return 0
+	}
 	bits := binary.LittleEndian.Uint64(r.buf[r.pos : r.pos+8])
 	r.pos += 8
 	return math.Float64frombits(bits)
@@ -68,6 +121,9 @@ func (r *BytesReader) ReadDouble() float64 {
 
 func (r *BytesReader) ReadString() string {
 	length := r.ReadInt()
+	if !r.fits(length) {
+		return ""
+	}
 	result := string(r.buf[r.pos : r.pos+length])
 	r.pos += length
 
@@ -75,6 +131,9 @@ func (r *BytesReader) ReadString() string {
 }
 
 func (r *BytesReader) ReadBytes(length int32) []byte {
+	if !r.fits(length) {
+		return nil
+	}
 	dst := make([]byte, length)
 	copy(dst, r.buf[r.pos:r.pos+length])
 
@@ -86,15 +145,14 @@ func (r *BytesReader) ReadBytes(length int32) []byte {
 func (r *BytesReader) ReadStringBinary() []byte {
 	length := r.ReadInt()
 
-	dst := make([]byte, length)
-	copy(dst, r.buf[r.pos:r.pos+length])
-
-	r.pos += length
-
-	return dst
+	return r.ReadBytes(length)
 }
 
+// ReadSlice returns the next length bytes, nil if there are not as many.
 func (r *BytesReader) ReadSlice(length int32) []byte {
+	if !r.fits(length) {
+		return nil
+	}
 	result := r.buf[r.pos : r.pos+length]
 	r.pos += length
 	return result
@@ -102,13 +160,13 @@ func (r *BytesReader) ReadSlice(length int32) []byte {
 
 // read a byte
 func (r *BytesReader) Read() int32 {
-	result := r.buf[r.pos]
-	r.pos++
-
-	return int32(result)
+	return int32(r.ReadByte())
 }
 
 func (r *BytesReader) ReadByte() byte {
+	if !r.fits(1) {
+		return 0
+	}
 	result := r.buf[r.pos]
 	r.pos++
 
@@ -120,17 +178,19 @@ func (r *BytesReader) ReadUnsignedVarInt() int32 {
 	var value int32 = 0
 	var i uint32 = 0
 
-	b := r.buf[r.pos]
-	r.pos++
-	var iLen = int32(len(r.buf))
-
-	for r.pos <= iLen && (b&0x80) != 0 {
+	for r.fits(1) {
+		b := r.buf[r.pos]
+		r.pos++
+		if (b & 0x80) == 0 {
+			return (value | int32(b)<<i)
+		}
+		if i >= 28 {
+			r.Fail("varint longer than 5 bytes")
+			break
+		}
 		value |= int32(b&0x7F) << i
 		i += 7
-
-		b = r.buf[r.pos]
-		r.pos++
 	}
 
-	return (value | int32(b)<<i)
+	return 0
 }
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 	_ "log"
 	"math"
@@ -10,18 +11,34 @@ import (
 )
 
 // file stream reader with buffer, supports random reading
+// Reading past the end of the file or a failing read does not panic: the reader fails until the next Seek, see Err.
 const SIZE_BUF = 1024 * 8
 
 type FileReader struct {
-	reader *os.File
-	pos    int64  // file position
-	b      []byte // buffer
-	l      int    // buffer len
-	p      int    // buffer read position
+	reader *io.SectionReader
+	closer io.Closer // the file read, nil if the reader was given
+	pos    int64     // file position
+	b      []byte    // buffer
+	l      int       // buffer len
+	p      int       // buffer read position
+	read   int64     // bytes returned by ReadSlice and ReadAt so far
+	err    error     // the error reading failed with since the last seek
 }
 
 func NewFileReader(reader *os.File) *FileReader {
-	f := &FileReader{reader: reader}
+	size := int64(math.MaxInt64)
+	if stat, err := reader.Stat(); err == nil {
+		size = stat.Size()
+	}
+	f := NewReaderAtFileReader(reader, size)
+	f.closer = reader
+	return f
+}
+
+// NewReaderAtFileReader returns a FileReader of the size bytes of reader, e.g. a blob, a member of an archive or data
+// received over the network.
+func NewReaderAtFileReader(reader io.ReaderAt, size int64) *FileReader {
+	f := &FileReader{reader: io.NewSectionReader(reader, 0, size)}
 	f.pos = 0
 	f.l = 0
 	f.p = 0
@@ -29,17 +46,45 @@ func NewFileReader(reader *os.File) *FileReader {
 	if n, err := f.reader.Read(f.b); err == nil || err == io.EOF {
 		f.l = n
 	} else {
-		panic(err)
+		f.err = err
 	}
 
 	return f
 }
 
+// Err returns the error reading failed with since the last seek, nil unless the file is truncated or cannot be read.
+// Once reading failed, the reads return zero values.
+func (f *FileReader) Err() error {
+	return f.err
+}
+
+// fits tells whether length more bytes can be read at pos, failing otherwise.
+func (f *FileReader) fits(length int, pos int64) bool {
+	if f.err != nil {
+		return false
+	}
+	if length < 0 {
+		f.err = fmt.Errorf("invalid length %d at offset %d", length, pos)
+	} else if pos < 0 || pos+int64(length) > f.reader.Size() {
+		f.err = fmt.Errorf("cannot read %d bytes at offset %d of %d: %w", length, pos, f.reader.Size(),
+			io.ErrUnexpectedEOF)
+	}
+	return f.err == nil
+}
+
 func (f *FileReader) Close() error {
-	return f.reader.Close()
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
 }
 
+// ReadSlice returns the next length bytes, nil if there are not as many.
 func (f *FileReader) ReadSlice(length int) []byte {
+	if !f.fits(length, f.pos) {
+		return nil
+	}
+	f.read += int64(length)
 	if length <= SIZE_BUF { // buffer size greater than reading size, so we get data from buffer
 		// buffer remaining is not enough, we needs to read data from file into buffer first
 		if f.l-f.p < length {
@@ -49,13 +94,11 @@ func (f *FileReader) ReadSlice(length int) []byte {
 			f.l -= f.p
 			f.p = 0
 
-			if n, err := f.reader.Read(f.b[f.l:]); err == nil || err == io.EOF {
+			if n, err := io.ReadAtLeast(f.reader, f.b[f.l:], length-f.l); err == nil {
 				f.l += n
-				if f.l < length {
-					panic("file has no enough data to read")
-				}
 			} else {
-				panic(err)
+				f.err = fmt.Errorf("cannot read %d bytes at offset %d: %w", length, f.pos, err)
+				return nil
 			}
 		}
 
@@ -71,12 +114,13 @@ func (f *FileReader) ReadSlice(length int) []byte {
 		}
 
 		remaining := f.l - f.p
-		if n, err := f.reader.Read(result[remaining:]); err == nil || err == io.EOF {
+		if n, err := io.ReadFull(f.reader, result[remaining:]); err == nil {
 			f.l = 0
 			f.p = 0
 			f.pos += int64(n + remaining)
 		} else {
-			panic(err)
+			f.err = fmt.Errorf("cannot read %d bytes at offset %d: %w", length, f.pos, err)
+			return nil
 		}
 
 		return result
@@ -85,6 +129,9 @@ func (f *FileReader) ReadSlice(length int) []byte {
 
 func (f *FileReader) ReadBool() bool {
 	buf := f.ReadSlice(constant.BOOLEAN_LEN)
+	if buf == nil {
+		return false
+	}
 	result := (buf[0] == 1)
 
 	return result
@@ -92,6 +139,9 @@ func (f *FileReader) ReadBool() bool {
 
 func (f *FileReader) ReadShort() int16 {
 	buf := f.ReadSlice(constant.SHORT_LEN)
+	if buf == nil {
+		return 0
+	}
 	result := int16(binary.BigEndian.Uint16(buf))
 
 	return result
@@ -99,6 +149,9 @@ func (f *FileReader) ReadShort() int16 {
 
 func (f *FileReader) ReadInt() int32 {
 	buf := f.ReadSlice(constant.INT_LEN)
+	if buf == nil {
+		return 0
+	}
 	result := int32(binary.BigEndian.Uint32(buf)) //to int32, then to int('cause int==int64 on x64)
 
 	return result
@@ -106,6 +159,9 @@ func (f *FileReader) ReadInt() int32 {
 
 func (f *FileReader) ReadLong() int64 {
 	buf := f.ReadSlice(constant.LONG_LEN)
+	if buf == nil {
+		return 0
+	}
 	result := int64(binary.BigEndian.Uint64(buf))
 
 	return result
@@ -113,6 +169,9 @@ func (f *FileReader) ReadLong() int64 {
 
 func (f *FileReader) ReadFloat() float32 {
 	buf := f.ReadSlice(constant.FLOAT_LEN)
+	if buf == nil {
+		return 0
+	}
 	bits := binary.BigEndian.Uint32(buf)
 	result := math.Float32frombits(bits)
 
@@ -121,6 +180,9 @@ func (f *FileReader) ReadFloat() float32 {
 
 func (f *FileReader) ReadDouble() float64 {
 	buf := f.ReadSlice(constant.DOUBLE_LEN)
+	if buf == nil {
+		return 0
+	}
 	bits := binary.BigEndian.Uint64(buf)
 	result := math.Float64frombits(bits)
 
@@ -138,20 +200,27 @@ func (f *FileReader) ReadString() string {
 func (f *FileReader) ReadStringBinary() []byte {
 	length := int(f.ReadInt())
 
-	dst := make([]byte, length)
 	buf := f.ReadSlice(length)
-
+	if buf == nil {
+		return nil
+	}
+	dst := make([]byte, length)
 	copy(dst, buf)
 
 	return dst
 }
 
 // this func does not change file pointer position and buffer
+// It returns nil if the file holds no length bytes at pos.
 func (f *FileReader) ReadAt(length int, pos int64) []byte {
+	if !f.fits(length, pos) {
+		return nil
+	}
+	f.read += int64(length)
 	buf := make([]byte, length)
-	n, err := f.reader.ReadAt(buf, pos)
-	if err != nil && err != io.EOF && n != length {
-		panic(err)
+	if n, err := f.reader.ReadAt(buf, pos); n < length {
+		f.err = fmt.Errorf("cannot read %d bytes at offset %d: %w", length, pos, err)
+		return nil
 	}
 	//f.pos += int64(length)
 
@@ -159,15 +228,22 @@ func (f *FileReader) ReadAt(length int, pos int64) []byte {
 }
 
 // buffer will be unavailable after seek
+// Seeking clears the error reading failed with.
 func (f *FileReader) Seek(pos int64, whence int) (ret int64, err error) {
 	var e error
 	f.pos, e = f.reader.Seek(pos, whence)
 	f.l = 0
 	f.p = 0
+	f.err = nil
 
 	return f.pos, e
 }
 
+// BytesRead returns the number of bytes read so far, whether they came from the buffer or from the file.
+func (f *FileReader) BytesRead() int64 {
+	return f.read
+}
+
 func (f *FileReader) Pos() int64 {
 	return f.pos
 }
@@ -3,6 +3,14 @@ package utils
 import (
 	"sort"
 	"strings"
+	"tsfile/common/constant"
+)
+
+const (
+	// ONE_LEVEL matches exactly one level of a path in a path pattern
+	ONE_LEVEL = "*"
+	// ANY_LEVELS matches one or more levels of a path in a path pattern
+	ANY_LEVELS = "**"
 )
 
 // MergeStrings sorts and merges two string lists in ascent order.
@@ -64,3 +72,40 @@ func TestCommonStrs(strsA []string, strsB []string) bool {
 	}
 	return false
 }
+
+// IsPathPattern tells whether the path has wildcard levels, e.g. "root.*.s0" or "root.**.temperature".
+func IsPathPattern(path string) bool {
+	for _, level := range strings.Split(path, constant.PATH_SEPARATOR) {
+		if level == ONE_LEVEL || level == ANY_LEVELS {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPath tells whether the full path matches the pattern level by level, see ONE_LEVEL and ANY_LEVELS.
+func MatchPath(pattern string, path string) bool {
+	return matchLevels(strings.Split(pattern, constant.PATH_SEPARATOR), strings.Split(path, constant.PATH_SEPARATOR))
+}
+
+func matchLevels(pattern []string, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if len(path) == 0 {
+		return false
+	}
+	switch pattern[0] {
+	case ANY_LEVELS:
+		for i := 1; i <= len(path); i++ {
+			if matchLevels(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	case ONE_LEVEL:
+		return matchLevels(pattern[1:], path[1:])
+	default:
+		return pattern[0] == path[0] && matchLevels(pattern[1:], path[1:])
+	}
+}
@@ -0,0 +1,20 @@
+package utils
+
+import "fmt"
+
+// CorruptedError reports a structure of a TsFile that cannot be read, the file being truncated or corrupted.
+type CorruptedError struct {
+	// Structure names what was read, e.g. "ChunkHeader" or "FileMetaData"
+	Structure string
+	// Offset is the position of the structure in the file
+	Offset int64
+	Err    error
+}
+
+func (e *CorruptedError) Error() string {
+	return fmt.Sprintf("corrupted %s at offset %d: %v", e.Structure, e.Offset, e.Err)
+}
+
+func (e *CorruptedError) Unwrap() error {
+	return e.Err
+}
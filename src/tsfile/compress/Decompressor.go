@@ -1,6 +1,7 @@
 package compress
 
 import (
+	"fmt"
 	"tsfile/common/constant"
 )
 
@@ -9,7 +10,8 @@ type Decompressor interface {
 	Decompress(compressed []byte) ([]byte, error)
 }
 
-func GetDecompressor(name constant.CompressionType) Decompressor {
+// NewDecompressor returns the decompressor of a compression type, an error if it is unknown.
+func NewDecompressor(name constant.CompressionType) (Decompressor, error) {
 	var decompressor Decompressor
 	switch {
 	case name == constant.UNCOMPRESSED:
@@ -17,7 +19,17 @@ func GetDecompressor(name constant.CompressionType) Decompressor {
 	case name == constant.SNAPPY:
 		decompressor = new(SnappyDecompressor)
 	default:
-		panic("Decompressor not found")
+		return nil, fmt.Errorf("Decompressor not found, compression:%d", name)
+	}
+
+	return decompressor, nil
+}
+
+// GetDecompressor is NewDecompressor panicking on unknown compression types.
+func GetDecompressor(name constant.CompressionType) Decompressor {
+	decompressor, err := NewDecompressor(name)
+	if err != nil {
+		panic(err)
 	}
 
 	return decompressor
@@ -1,15 +1,28 @@
 package compress
 
 import (
+	"fmt"
 	"github.com/golang/snappy"
 )
 
+// maxExpansion bounds the ratio of the decoded to the encoded length of snappy data, a copy of 64 bytes taking 3 bytes.
+const maxExpansion = 22
+
 type SnappyDecompressor struct{}
 
 func (n *SnappyDecompressor) GetDecompressedLength(data []byte) (int, error) {
 	return snappy.DecodedLen(data)
 }
 
+// Decompress rejects data claiming a decoded length it cannot hold before allocating it, corrupted pages claiming up
+// to 4GB.
 func (n *SnappyDecompressor) Decompress(compressed []byte) ([]byte, error) {
+	length, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxExpansion*len(compressed) {
+		return nil, fmt.Errorf("snappy: decoded length %d too large for %d bytes", length, len(compressed))
+	}
 	return snappy.Decode(nil, compressed)
 }
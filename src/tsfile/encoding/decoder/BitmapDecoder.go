@@ -53,7 +53,7 @@ func (d *BitmapDecoder) Next() interface{} {
 	index := (d.number - d.currentCount) / 8
 	offset := 7 - ((d.number - d.currentCount) % 8)
 	for k, v := range d.buffer {
-		if v[index]&(1<<uint(offset)) != 0 {
+		if index >= 0 && index < int32(len(v)) && v[index]&(1<<uint(offset)) != 0 {
 			result = k
 			break
 		}
@@ -2,8 +2,8 @@ package decoder
 
 import (
 	_ "bytes"
+	"errors"
 	_ "os"
-	"strconv"
 	"tsfile/common/constant"
 )
 
@@ -17,15 +17,31 @@ type Decoder interface {
 	HasNext() bool
 	Next() interface{}
 	NextInt64() int64
+	// Err returns the error decoding failed with, nil unless the data is truncated or corrupted. Once decoding failed,
+	// HasNext returns false.
+	Err() error
 }
 
+// CreateDecoder is NewDecoder panicking if the encoding does not support the data type.
 func CreateDecoder(encoding constant.TSEncoding, dataType constant.TSDataType) Decoder {
+	decoder, err := NewDecoder(encoding, dataType)
+	if err != nil {
+		panic(err)
+	}
+	return decoder
+}
+
+// NewDecoder returns a decoder of values of the data type encoded with the encoding, an error if the encoding does
+// not support the data type.
+func NewDecoder(encoding constant.TSEncoding, dataType constant.TSDataType) (Decoder, error) {
 	// PLA and DFT encoding are not supported in current version
 	var decoder Decoder
 
 	switch {
 	case encoding == constant.PLAIN:
-		decoder = &PlainDecoder{dataType: dataType}
+		if dataType >= constant.BOOLEAN && dataType <= constant.TEXT {
+			decoder = &PlainDecoder{dataType: dataType}
+		}
 	case encoding == constant.RLE:
 		if dataType == constant.BOOLEAN {
 			decoder = NewIntRleDecoder(dataType)
@@ -54,9 +70,10 @@ func CreateDecoder(encoding constant.TSEncoding, dataType constant.TSDataType) D
 		} else if dataType == constant.DOUBLE {
 			decoder = NewDoublePrecisionDecoder(dataType)
 		}
-	default:
-		panic("Decoder not found, encoding:" + strconv.Itoa(int(encoding)) + ", dataType:" + strconv.Itoa(int(dataType)))
 	}
 
-	return decoder
+	if decoder == nil {
+		return nil, errors.New("Decoder not found, encoding:" + encoding.String() + ", dataType:" + dataType.String())
+	}
+	return decoder, nil
 }
@@ -0,0 +1,172 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"tsfile/common/constant"
+	"tsfile/encoding/encoder"
+)
+
+// fuzzWork is the number of values decoded from a fuzzed input at most, a stream decoding to up to 8 values per byte
+// and runs repeating a value any number of times.
+const fuzzWork = 10000
+
+// encodedValue returns the i-th value of a stream of the data type.
+func encodedValue(dataType constant.TSDataType, i int64) interface{} {
+	switch dataType {
+	case constant.BOOLEAN:
+		return i%3 == 0
+	case constant.INT32:
+		return int32(i * i)
+	case constant.INT64:
+		return i * 1000
+	case constant.FLOAT:
+		return float32(i) * 1.5
+	case constant.DOUBLE:
+		return float64(i) * 0.25
+	}
+	return fmt.Sprintf("v%d", i)
+}
+
+// encodedStream returns the first 30 values of the data type encoded with the encoding, nil if it does not support the
+// data type.
+func encodedStream(encoding constant.TSEncoding, dataType constant.TSDataType) []byte {
+	valueEncoder, err := encoder.NewEncoder(int16(encoding), int16(dataType))
+	if err != nil {
+		return nil
+	}
+	buf := new(bytes.Buffer)
+	for i := int64(1); i <= 30; i++ {
+		valueEncoder.Encode(encodedValue(dataType, i), buf)
+	}
+	valueEncoder.Flush(buf)
+	return buf.Bytes()
+}
+
+func TestDecoder(t *testing.T) {
+	// every encoding of every data type decodes back
+	for encoding := constant.PLAIN; encoding <= constant.GORILLA; encoding++ {
+		for dataType := constant.BOOLEAN; dataType <= constant.TEXT; dataType++ {
+			data := encodedStream(encoding, dataType)
+			if data == nil {
+				continue
+			}
+			valueDecoder, err := NewDecoder(encoding, dataType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			valueDecoder.Init(data)
+			var decoded []interface{}
+			for valueDecoder.HasNext() {
+				decoded = append(decoded, valueDecoder.Next())
+			}
+			if valueDecoder.Err() != nil || len(decoded) != 30 {
+				t.Fatal(fmt.Sprintf("%v of %v: expected 30 values got %d, %v", encoding, dataType, len(decoded),
+					valueDecoder.Err()))
+			}
+			for i, v := range decoded {
+				if fmt.Sprint(v) != fmt.Sprint(encodedValue(dataType, int64(i+1))) {
+					t.Fatal(fmt.Sprintf("%v of %v: expected %v got %v", encoding, dataType,
+						encodedValue(dataType, int64(i+1)), v))
+				}
+			}
+		}
+	}
+}
+
+func TestRleDecoder(t *testing.T) {
+	// bit-packed groups of distinct values, between runs of repeated ones for INT32
+	var values, runs []int64
+	for i := 0; i < 100; i++ {
+		values = append(values, int64(i*7%13))
+		if i/20%2 == 0 {
+			runs = append(runs, int64(i*7%13))
+		} else {
+			runs = append(runs, int64(i/20))
+		}
+	}
+	cases := []struct {
+		dataType constant.TSDataType
+		values   []int64
+	}{
+		{constant.INT32, values},
+		{constant.INT32, runs},
+		{constant.INT64, values},
+	}
+	for _, c := range cases {
+		rleEncoder := encoder.NewRleEncoder(c.dataType)
+		buf := new(bytes.Buffer)
+		for _, v := range c.values {
+			if c.dataType == constant.INT32 {
+				rleEncoder.Encode(int32(v), buf)
+			} else {
+				rleEncoder.Encode(v, buf)
+			}
+		}
+		rleEncoder.Flush(buf)
+
+		rleDecoder, err := NewDecoder(constant.RLE, c.dataType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rleDecoder.Init(buf.Bytes())
+		var decoded []int64
+		for rleDecoder.HasNext() {
+			switch v := rleDecoder.Next().(type) {
+			case int32:
+				decoded = append(decoded, int64(v))
+			case int64:
+				decoded = append(decoded, v)
+			}
+		}
+		if rleDecoder.Err() != nil || fmt.Sprint(decoded) != fmt.Sprint(c.values) {
+			t.Fatal(fmt.Sprintf("%s: expected %v got %v, %v", c.dataType, c.values, decoded, rleDecoder.Err()))
+		}
+	}
+}
+
+func TestDecoderCorruptedCounts(t *testing.T) {
+	// packs of values 0 bits wide claiming a billion values
+	intPack := new(bytes.Buffer)
+	binary.Write(intPack, binary.BigEndian, []int32{1 << 30, 0, 1, 1})
+	longPack := new(bytes.Buffer)
+	binary.Write(longPack, binary.BigEndian, []int32{1 << 30, 0})
+	binary.Write(longPack, binary.BigEndian, []int64{1, 1})
+	for _, c := range []struct {
+		decoder Decoder
+		pack    []byte
+	}{{NewIntDeltaDecoder(constant.INT32), intPack.Bytes()}, {NewLongDeltaDecoder(constant.INT64), longPack.Bytes()}} {
+		c.decoder.Init(c.pack)
+		n := 0
+		for ; n <= 8*len(c.pack) && c.decoder.HasNext(); n++ {
+			c.decoder.Next()
+		}
+		if n > 8*len(c.pack) || c.decoder.Err() == nil {
+			t.Fatal(fmt.Sprintf("Expected the pack to be rejected got %d values, %v", n, c.decoder.Err()))
+		}
+	}
+}
+
+func FuzzDecoder(f *testing.F) {
+	// the streams of every encoding of every data type
+	for encoding := constant.PLAIN; encoding <= constant.GORILLA; encoding++ {
+		for dataType := constant.BOOLEAN; dataType <= constant.TEXT; dataType++ {
+			if data := encodedStream(encoding, dataType); data != nil {
+				f.Add(byte(encoding), byte(dataType), data)
+			}
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, encoding byte, dataType byte, data []byte) {
+		valueDecoder, err := NewDecoder(constant.TSEncoding(encoding%8), constant.TSDataType(dataType%6))
+		if err != nil {
+			return
+		}
+		valueDecoder.Init(data)
+		for values := 0; values < fuzzWork && valueDecoder.HasNext(); values++ {
+			valueDecoder.Next()
+		}
+	})
+}
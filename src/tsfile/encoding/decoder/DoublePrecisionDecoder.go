@@ -28,6 +28,13 @@ func (d *DoublePrecisionDecoder) HasNext() bool {
 	return d.reader.Len() > 0
 }
 
+func (d *DoublePrecisionDecoder) Err() error {
+	if d.reader == nil {
+		return nil
+	}
+	return d.reader.Err()
+}
+
 func (d *DoublePrecisionDecoder) NextInt64() int64 {
 	return 0
 }
@@ -38,6 +45,9 @@ func (d *DoublePrecisionDecoder) Next() interface{} {
 		d.flag = true
 
 		ch := reader.ReadSlice(8)
+		if ch == nil {
+			return float64(0)
+		}
 
 		res := uint64(ch[0]) + (uint64(ch[1]) << 8) + (uint64(ch[2]) << 16) + (uint64(ch[3]) << 24) +
 			(uint64(ch[4]) << 32) + (uint64(ch[5]) << 40) + (uint64(ch[6]) << 48) + (uint64(ch[7]) << 56)
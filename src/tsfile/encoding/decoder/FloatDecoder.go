@@ -42,7 +42,17 @@ func (d *FloatDecoder) HasNext() bool {
 	if d.baseDecoder == nil {
 		return false
 	}
-	return d.baseDecoder.HasNext()
+	return d.Err() == nil && d.baseDecoder.HasNext()
+}
+
+func (d *FloatDecoder) Err() error {
+	if d.reader != nil && d.reader.Err() != nil {
+		return d.reader.Err()
+	}
+	if d.baseDecoder == nil {
+		return nil
+	}
+	return d.baseDecoder.Err()
 }
 
 func (d *FloatDecoder) NextInt64() int64 {
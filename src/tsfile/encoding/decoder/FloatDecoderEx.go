@@ -32,7 +32,17 @@ func (d *FloatDeltaDecoder) HasNext() bool {
 	if d.base == nil {
 		return false
 	}
-	return d.base.HasNext()
+	return d.Err() == nil && d.base.HasNext()
+}
+
+func (d *FloatDeltaDecoder) Err() error {
+	if d.reader != nil && d.reader.Err() != nil {
+		return d.reader.Err()
+	}
+	if d.base == nil {
+		return nil
+	}
+	return d.base.Err()
 }
 
 func (d *FloatDeltaDecoder) NextInt64() int64 {
@@ -68,7 +78,17 @@ func (d *DoubleDeltaDecoder) HasNext() bool {
 	if d.base == nil {
 		return false
 	}
-	return d.base.HasNext()
+	return d.Err() == nil && d.base.HasNext()
+}
+
+func (d *DoubleDeltaDecoder) Err() error {
+	if d.reader != nil && d.reader.Err() != nil {
+		return d.reader.Err()
+	}
+	if d.base == nil {
+		return nil
+	}
+	return d.base.Err()
 }
 
 func (d *DoubleDeltaDecoder) NextInt64() int64 {
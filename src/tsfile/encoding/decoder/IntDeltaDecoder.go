@@ -3,11 +3,13 @@ package decoder
 import (
 	_ "bytes"
 	_ "encoding/binary"
-	"math"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
 )
 
+// intDeltaPackHeaderSize is the size of the count, width, base value and first value of a pack
+const intDeltaPackHeaderSize = int64(4 * constant.INT_LEN)
+
 // This package is a decoder for decoding the byte array that encoded by DeltaBinaryDecoder just supports integer and long values.
 // 0-3 bits int32 存储数值个数
 // 4-7 bits int32 存储单个数值宽度
@@ -24,11 +26,21 @@ type IntDeltaDecoder struct {
 
 	baseValue     int32
 	firstValue    int32
-	decodedValues []int32
+	previousValue int32
+	// valueBuffer holds the values of the pack, decoded one at a time
+	valueBuffer []byte
 }
 
 func (d *IntDeltaDecoder) Init(data []byte) {
 	d.reader = utils.NewBytesReader(data)
+	d.count, d.index = 0, 0
+}
+
+func (d *IntDeltaDecoder) Err() error {
+	if d.reader == nil {
+		return nil
+	}
+	return d.reader.Err()
 }
 
 func (d *IntDeltaDecoder) HasNext() bool {
@@ -39,10 +51,7 @@ func (d *IntDeltaDecoder) Next() interface{} {
 	if d.index == d.count {
 		return d.loadPack()
 	} else {
-		result := d.decodedValues[d.index]
-		d.index++
-
-		return int32(result)
+		return d.nextValue()
 	}
 }
 
@@ -54,10 +63,7 @@ func (d *IntDeltaDecoder) NextEx() int32 {
 	if d.index == d.count {
 		return d.loadPack()
 	} else {
-		result := d.decodedValues[d.index]
-		d.index++
-
-		return int32(result)
+		return d.nextValue()
 	}
 }
 
@@ -70,44 +76,38 @@ func (d *IntDeltaDecoder) loadPack() int32 {
 	d.index = 0
 
 	//how many bytes data takes after encoding
-	encodingLength := int32(math.Ceil(float64(d.count*d.width) / 8.0))
-	valueBuffer := d.reader.ReadSlice(encodingLength)
+	encodingLength := (int64(d.count)*int64(d.width) + 7) / 8
+	// a pack holds at most as many values as it has bits, even when they are all 0 bits wide, so that a page never
+	// decodes to more values than 8 times its size
+	if d.count < 0 || d.width < 0 || d.width > 32 || encodingLength > int64(d.reader.Len()) ||
+		int64(d.count) > 8*(intDeltaPackHeaderSize+encodingLength) {
+		d.reader.Fail("invalid pack of %d values of %d bits", d.count, d.width)
+	}
+	d.valueBuffer = d.reader.ReadSlice(int32(encodingLength))
+	if d.reader.Err() != nil {
+		d.count = 0
+	}
+	d.previousValue = d.firstValue
 
-	previousValue := d.firstValue
-	d.decodedValues = make([]int32, d.count)
+	return d.firstValue
+}
 
+// nextValue decodes the value at index of the pack, whose width bits are stored from the most significant one.
+func (d *IntDeltaDecoder) nextValue() int32 {
 	var value int32
-
-	var iCount int32
 	var width int32 = d.width
-	var offset int32 = width - 1
-	var index int32 = 0
+	var index int32 = (d.index+1)*width - 1
 	var i int32
-	for iCount = 0; iCount < d.count; iCount++ {
-		////pos = width * iCount
-		//value = utils.BytesToInt(valueBuffer, pos, width)
-		value = 0
-		//offset = pos + width - 1
-		index = offset
-		for i = 0; i < width; i++ {
-			//index := offset - i
-			//value = setIntN(value, i, getByteN(data[index/8], index))
-
-			if (valueBuffer[index/8] & (1 << uint32(7-index&7))) != 0 {
-				value = (value | (1 << uint32(i&0x1f)))
-			} else {
-				value = (value & ^(1 << uint32(i&0x1f)))
-			}
-			index--
+	for i = 0; i < width; i++ {
+		if (d.valueBuffer[index/8] & (1 << uint32(7-index&7))) != 0 {
+			value = (value | (1 << uint32(i&0x1f)))
 		}
-
-		d.decodedValues[iCount] = previousValue + d.baseValue + value
-		previousValue = d.decodedValues[iCount]
-
-		offset += width
+		index--
 	}
+	d.index++
 
-	return d.firstValue
+	d.previousValue = d.previousValue + d.baseValue + value
+	return d.previousValue
 }
 
 func NewIntDeltaDecoder(dataType constant.TSDataType) *IntDeltaDecoder {
@@ -49,18 +49,32 @@ type LongRleDecoder struct {
 
 func (d *LongRleDecoder) Init(data []byte) {
 	d.reader = utils.NewBytesReader(data)
+	d.packageReader = nil
 	d.currentCount = 0
 	d.currentValue = 0
 	d.isReadingBegan = false
 }
 
 func (d *LongRleDecoder) HasNext() bool {
-	if d.currentCount > 0 || d.reader.Len() > 0 || d.packageReader.Len() > 0 {
+	if d.Err() != nil {
+		return false
+	}
+	if d.currentCount > 0 || d.reader.Len() > 0 || (d.packageReader != nil && d.packageReader.Len() > 0) {
 		return true
 	}
 	return false
 }
 
+func (d *LongRleDecoder) Err() error {
+	if d.reader != nil && d.reader.Err() != nil {
+		return d.reader.Err()
+	}
+	if d.packageReader != nil {
+		return d.packageReader.Err()
+	}
+	return nil
+}
+
 func (d *LongRleDecoder) NextInt64() int64 {
 	if !d.isReadingBegan {
 		// read length and bit width of current package before we decode number
@@ -68,6 +82,9 @@ func (d *LongRleDecoder) NextInt64() int64 {
 
 		d.packageReader = utils.NewBytesReader(d.reader.ReadSlice(d.length))
 		d.bitWidth = d.packageReader.Read()
+		if d.bitWidth > 64 {
+			d.packageReader.Fail("invalid bit width %d", d.bitWidth)
+		}
 
 		d.packer = &bitpacking.LongPacker{BitWidth: d.bitWidth}
 
@@ -77,6 +94,10 @@ func (d *LongRleDecoder) NextInt64() int64 {
 	if d.currentCount == 0 {
 		d.readPackage()
 	}
+	if d.Err() != nil {
+		d.currentCount = 0
+		return int64(0)
+	}
 
 	d.currentCount--
 
@@ -106,6 +127,9 @@ func (d *LongRleDecoder) Next() interface{} {
 
 		d.packageReader = utils.NewBytesReader(d.reader.ReadSlice(d.length))
 		d.bitWidth = d.packageReader.Read()
+		if d.bitWidth > 64 {
+			d.packageReader.Fail("invalid bit width %d", d.bitWidth)
+		}
 
 		d.packer = &bitpacking.LongPacker{BitWidth: d.bitWidth}
 
@@ -115,6 +139,10 @@ func (d *LongRleDecoder) Next() interface{} {
 	if d.currentCount == 0 {
 		d.readPackage()
 	}
+	if d.Err() != nil {
+		d.currentCount = 0
+		return int64(0)
+	}
 
 	d.currentCount--
 
@@ -148,17 +176,30 @@ func (d *LongRleDecoder) readPackage() {
 	switch d.mode {
 	case RLE:
 		d.currentCount = header >> 1
+		if d.currentCount <= 0 {
+			d.packageReader.Fail("invalid run length %d", d.currentCount)
+			return
+		}
 		d.currentValue = d.readLongLittleEndianPaddedOnBitWidth(d.packageReader, d.bitWidth)
 
 	case BIT_PACKED:
 		bitPackedGroupCount := header >> 1
 		// in last bit-packing group, there may be some useless value, lastBitPackedNum indicates how many values is useful
 		lastBitPackedNum := d.packageReader.Read()
-		if bitPackedGroupCount > 0 {
+		if bitPackedGroupCount > 0 && lastBitPackedNum <= conf.RLE_MIN_REPEATED_NUM &&
+			(bitPackedGroupCount > 1 || lastBitPackedNum > 0) {
 			d.currentCount = (bitPackedGroupCount-1)*conf.RLE_MIN_REPEATED_NUM + lastBitPackedNum
 			d.bitPackingNum = d.currentCount
 		} else {
-			panic("tsfile-encoding LongRleDecoder: bitPackedGroupCount smaller than 1")
+			d.packageReader.Fail("invalid bit-packed run of %d groups, %d values in the last one",
+				bitPackedGroupCount, lastBitPackedNum)
+			return
+		}
+		if d.bitWidth == 0 {
+			// every value is 0
+			d.mode = RLE
+			d.currentValue = 0
+			return
 		}
 
 		d.readBitPackingBuffer(bitPackedGroupCount, lastBitPackedNum, d.bitWidth)
@@ -169,10 +210,11 @@ func (d *LongRleDecoder) readPackage() {
 
 // unpack all values from packageReader into decodedValues
 func (d *LongRleDecoder) readBitPackingBuffer(bitPackedGroupCount int32, lastBitPackedNum int32, bitWidth int32) {
-	bytesToRead := bitPackedGroupCount * bitWidth
-	if bytesToRead > d.packageReader.Len() {
-		bytesToRead = d.packageReader.Len()
+	if int64(bitPackedGroupCount)*int64(bitWidth) > int64(d.packageReader.Len()) {
+		d.packageReader.Fail("%d bit-packed groups of %d bytes past the end", bitPackedGroupCount, bitWidth)
+		return
 	}
+	bytesToRead := bitPackedGroupCount * bitWidth
 	bytes := d.packageReader.ReadSlice(bytesToRead)
 
 	d.decodedValues = make([]int64, bitPackedGroupCount*(conf.RLE_MIN_REPEATED_NUM))
@@ -21,10 +21,20 @@ func (d *PlainDecoder) HasNext() bool {
 	return d.reader.Len() > 0
 }
 
+func (d *PlainDecoder) Err() error {
+	if d.reader == nil {
+		return nil
+	}
+	return d.reader.Err()
+}
+
 func (d *PlainDecoder) NextInt64() int64 {
 	switch {
 	case d.dataType == constant.INT64:
 		result := d.reader.ReadSlice(8)
+		if result == nil {
+			return 0
+		}
 		return int64(binary.LittleEndian.Uint64(result))
 	case d.dataType == constant.BOOLEAN:
 	case d.dataType == constant.INT32:
@@ -43,9 +53,15 @@ func (d *PlainDecoder) Next() interface{} {
 		return d.reader.ReadBool()
 	case d.dataType == constant.INT32:
 		result := d.reader.ReadSlice(4)
+		if result == nil {
+			return int32(0)
+		}
 		return int32(binary.LittleEndian.Uint32(result))
 	case d.dataType == constant.INT64:
 		result := d.reader.ReadSlice(8)
+		if result == nil {
+			return int64(0)
+		}
 		return int64(binary.LittleEndian.Uint64(result))
 	case d.dataType == constant.FLOAT:
 		return d.reader.ReadFloat()
@@ -53,8 +69,11 @@ func (d *PlainDecoder) Next() interface{} {
 		return d.reader.ReadDouble()
 	case d.dataType == constant.TEXT:
 		//len_bytes := d.reader.ReadSlice(4)
-		len1 := int32(binary.LittleEndian.Uint32(d.reader.ReadSlice(4)))
-		return string(d.reader.ReadSlice(len1))
+		length := d.reader.ReadSlice(4)
+		if length == nil {
+			return ""
+		}
+		return string(d.reader.ReadSlice(int32(binary.LittleEndian.Uint32(length))))
 	default:
 		panic("ReadValue not supported: " + strconv.Itoa(int(d.dataType)))
 	}
@@ -29,6 +29,13 @@ func (d *SinglePrecisionDecoder) HasNext() bool {
 	return d.reader.Len() > 0
 }
 
+func (d *SinglePrecisionDecoder) Err() error {
+	if d.reader == nil {
+		return nil
+	}
+	return d.reader.Err()
+}
+
 func (d *SinglePrecisionDecoder) NextInt64() int64 {
 	return 0
 }
@@ -39,6 +46,9 @@ func (d *SinglePrecisionDecoder) Next() interface{} {
 		reader := d.reader
 
 		ch := reader.ReadSlice(4)
+		if ch == nil {
+			return float32(0)
+		}
 		d.preValue = uint32(ch[0]) + uint32(ch[1])<<8 + uint32(ch[2])<<16 + uint32(ch[3])<<24
 		d.leadingZeroNum = NumberOfLeadingZeros(d.preValue)
 		d.tailingZeroNum = NumberOfTrailingZeros(d.preValue)
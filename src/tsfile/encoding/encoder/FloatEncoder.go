@@ -37,8 +37,10 @@ func (d *FloatEncoder) Encode(v interface{}, buffer *bytes.Buffer) {
 	}
 }
 
+// Flush ends a page, the next one starting with the number of decimal places again.
 func (d *FloatEncoder) Flush(buffer *bytes.Buffer) {
 	d.baseEncoder.Flush(buffer)
+	d.maxPointNumberSavedFlag = false
 }
 
 func (d *FloatEncoder) GetMaxByteSize() int64 {
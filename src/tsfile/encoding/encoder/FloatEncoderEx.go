@@ -45,8 +45,10 @@ func (d *FloatDeltaEncoder) Encode(v interface{}, buffer *bytes.Buffer) {
 	d.baseEncoder.Encode(value, buffer)
 }
 
+// Flush ends a page, the next one starting with the number of decimal places again.
 func (d *FloatDeltaEncoder) Flush(buffer *bytes.Buffer) {
 	d.baseEncoder.Flush(buffer)
+	d.maxPointNumberSavedFlag = false
 }
 
 func (d *FloatDeltaEncoder) GetMaxByteSize() int64 {
@@ -95,8 +97,10 @@ func (d *DoubleDeltaEncoder) Encode(v interface{}, buffer *bytes.Buffer) {
 	d.baseEncoder.Encode((int64)(math.Round(v.(float64)*d.maxPointValue)), buffer)
 }
 
+// Flush ends a page, the next one starting with the number of decimal places again.
 func (d *DoubleDeltaEncoder) Flush(buffer *bytes.Buffer) {
 	d.baseEncoder.Flush(buffer)
+	d.maxPointNumberSavedFlag = false
 }
 
 func (d *DoubleDeltaEncoder) GetMaxByteSize() int64 {
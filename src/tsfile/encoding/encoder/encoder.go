@@ -2,7 +2,7 @@ package encoder
 
 import (
 	"bytes"
-	"strconv"
+	"errors"
 	"tsfile/common/conf"
 	"tsfile/common/constant"
 )
@@ -22,21 +22,40 @@ type Encoder interface {
 	GetMaxByteSize() int64
 }
 
+// GetEncoder is NewEncoder panicking if the encoding does not support the data type.
 func GetEncoder(et int16, tdt int16) Encoder {
+	encoder, err := NewEncoder(et, tdt)
+	if err != nil {
+		panic(err)
+	}
+	return encoder
+}
+
+// NewEncoder returns an encoder of values of the data type tdt with the encoding et, an error if the encoding does
+// not support the data type.
+func NewEncoder(et int16, tdt int16) (Encoder, error) {
+	return NewEncoderWithConfig(et, tdt, conf.DefaultWriterConfig())
+}
+
+// NewEncoderWithConfig is NewEncoder with the float precision and max string length of config instead of the package
+// level ones.
+func NewEncoderWithConfig(et int16, tdt int16, config *conf.WriterConfig) (Encoder, error) {
 	encoding := constant.TSEncoding(et)
 	dataType := constant.TSDataType(tdt)
 
 	var encoder Encoder
 	switch {
 	case encoding == constant.PLAIN:
-		encoder, _ = NewPlainEncoder(dataType)
+		if dataType >= constant.BOOLEAN && dataType <= constant.TEXT {
+			encoder = newPlainEncoder(dataType, config.MaxStringLength)
+		}
 	case encoding == constant.RLE:
 		if dataType == constant.INT32 {
 			encoder = NewRleEncoder(constant.INT32)
 		} else if dataType == constant.INT64 {
 			encoder = NewRleEncoder(constant.INT64)
 		} else if dataType == constant.FLOAT || dataType == constant.DOUBLE {
-			encoder = NewFloatEncoder(encoding, int32(conf.FloatPrecision), dataType)
+			encoder = NewFloatEncoder(encoding, int32(config.FloatPrecision), dataType)
 		}
 	case encoding == constant.TS_2DIFF:
 		if dataType == constant.INT32 {
@@ -44,11 +63,11 @@ func GetEncoder(et int16, tdt int16) Encoder {
 		} else if dataType == constant.INT64 {
 			encoder = NewLongDeltaEncoder(constant.INT32)
 		} else if dataType == constant.DOUBLE {
-			encoder = NewDoubleDeltaEncoder(encoding, conf.FloatPrecision, dataType)
-			//encoder = NewFloatEncoder(encoding, conf.FloatPrecision, dataType)
+			encoder = NewDoubleDeltaEncoder(encoding, config.FloatPrecision, dataType)
+			//encoder = NewFloatEncoder(encoding, config.FloatPrecision, dataType)
 		} else if dataType == constant.FLOAT {
-			encoder = NewFloatDeltaEncoder(encoding, conf.FloatPrecision, dataType)
-			//encoder = NewFloatEncoder(encoding, conf.FloatPrecision, dataType)
+			encoder = NewFloatDeltaEncoder(encoding, config.FloatPrecision, dataType)
+			//encoder = NewFloatEncoder(encoding, config.FloatPrecision, dataType)
 		}
 	case encoding == constant.GORILLA:
 		if dataType == constant.FLOAT {
@@ -56,10 +75,10 @@ func GetEncoder(et int16, tdt int16) Encoder {
 		} else if dataType == constant.DOUBLE {
 			encoder = NewDoublePrecisionEncoder(dataType)
 		}
-
-	default:
-		panic("Encoder not found, encoding:" + strconv.Itoa(int(encoding)) + ", dataType:" + strconv.Itoa(int(dataType)))
 	}
 
-	return encoder
+	if encoder == nil {
+		return nil, errors.New("Encoder not found, encoding:" + encoding.String() + ", dataType:" + dataType.String())
+	}
+	return encoder, nil
 }
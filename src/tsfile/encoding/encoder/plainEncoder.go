@@ -18,8 +18,9 @@ import (
  */
 
 type PlainEncoder struct {
-	tsDataType   constant.TSDataType
-	encodeEndian int8
+	tsDataType      constant.TSDataType
+	encodeEndian    int8
+	maxStringLength int
 	//valueCount   int
 }
 
@@ -66,7 +67,7 @@ func (p *PlainEncoder) GetOneItemMaxSize() int {
 	case constant.DOUBLE:
 		return 8
 	case constant.TEXT:
-		return 4 + conf.BYTE_SIZE_PER_CHAR*conf.MaxStringLength
+		return 4 + conf.BYTE_SIZE_PER_CHAR*p.maxStringLength
 	default:
 		log.Error("invalid input dataType in plainEncoder. tsDataType: %d", p.tsDataType)
 
@@ -75,9 +76,14 @@ func (p *PlainEncoder) GetOneItemMaxSize() int {
 }
 
 func NewPlainEncoder(dataType constant.TSDataType) (*PlainEncoder, error) {
+	return newPlainEncoder(dataType, conf.MaxStringLength), nil
+}
+
+func newPlainEncoder(dataType constant.TSDataType, maxStringLength int) *PlainEncoder {
 	return &PlainEncoder{
-		tsDataType:   dataType,
-		encodeEndian: 1,
+		tsDataType:      dataType,
+		encodeEndian:    1,
+		maxStringLength: maxStringLength,
 		//valueCount:   -1,
-	}, nil
+	}
 }
@@ -123,7 +123,7 @@ func (this *RleEncoder) writeRleRun() {
 		break
 	}
 	this.repeatCount = 0
-	this.numBufferedValues = 0
+	this.clearBufferedValues()
 }
 
 func (this *RleEncoder) convertBuffer() {
@@ -165,7 +165,7 @@ func (this *RleEncoder) writeOrAppendBitPackedRun() {
 		this.isBitPackRun = true
 	}
 	this.convertBuffer()
-	this.numBufferedValues = 0
+	this.clearBufferedValues()
 	this.repeatCount = 0
 	this.bitPackedGroupCount = this.bitPackedGroupCount + 1
 }
@@ -244,8 +244,15 @@ func (this *RleEncoder) clearBuffer() {
 	}
 }
 
-func (this *RleEncoder) reset() {
+// clearBufferedValues drops the values of the group that has just been written, the next group starts from index 0
+func (this *RleEncoder) clearBufferedValues() {
 	this.numBufferedValues = 0
+	this.bufferedValues_32 = this.bufferedValues_32[0:0]
+	this.bufferedValues_64 = this.bufferedValues_64[0:0]
+}
+
+func (this *RleEncoder) reset() {
+	this.clearBufferedValues()
 	this.repeatCount = 0
 	this.bitPackedGroupCount = 0
 	this.bytesBuffer = this.bytesBuffer[0:0]
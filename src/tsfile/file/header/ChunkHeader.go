@@ -3,6 +3,7 @@ package header
 import (
 	_ "bufio"
 	"bytes"
+	"fmt"
 	_ "log"
 	_ "os"
 	"tsfile/common/constant"
@@ -20,16 +21,41 @@ type ChunkHeader struct {
 	serializedSize   int
 }
 
-func (h *ChunkHeader) Deserialize(reader *utils.FileReader) {
+// Deserialize reads the header at the position of reader, a *utils.CorruptedError if it is truncated or holds values
+// out of range.
+func (h *ChunkHeader) Deserialize(reader *utils.FileReader) error {
+	offset := reader.Pos()
 	h.sensor = reader.ReadString()
 	h.dataSize = int(reader.ReadInt())
-	h.dataType = constant.TSDataType(reader.ReadShort())
+	dataType := reader.ReadShort()
 	h.numberOfPages = int(reader.ReadInt())
-	h.compressionType = constant.CompressionType(reader.ReadShort())
-	h.encodingType = constant.TSEncoding(reader.ReadShort())
+	compressionType := reader.ReadShort()
+	encodingType := reader.ReadShort()
 	h.maxTombstoneTime = reader.ReadLong()
+	h.dataType = constant.TSDataType(dataType)
+	h.compressionType = constant.CompressionType(compressionType)
+	h.encodingType = constant.TSEncoding(encodingType)
 
 	h.serializedSize = (constant.INT_LEN + len(h.sensor) + constant.INT_LEN + constant.SHORT_LEN + constant.INT_LEN + constant.SHORT_LEN + constant.SHORT_LEN + constant.LONG_LEN)
+
+	err := reader.Err()
+	switch {
+	case err != nil:
+	case h.dataSize < 0:
+		err = fmt.Errorf("invalid data size %d", h.dataSize)
+	case h.numberOfPages < 0:
+		err = fmt.Errorf("invalid number of pages %d", h.numberOfPages)
+	case dataType < int16(constant.BOOLEAN) || dataType > int16(constant.TEXT):
+		err = fmt.Errorf("unknown data type %d", dataType)
+	case compressionType != int16(constant.UNCOMPRESSED) && compressionType != int16(constant.SNAPPY):
+		err = fmt.Errorf("unsupported compression %d", compressionType)
+	case encodingType < int16(constant.PLAIN) || encodingType > int16(constant.GORILLA):
+		err = fmt.Errorf("unknown encoding %d", encodingType)
+	}
+	if err != nil {
+		return &utils.CorruptedError{Structure: "ChunkHeader", Offset: offset, Err: err}
+	}
+	return nil
 }
 
 func (h *ChunkHeader) GetSensor() string {
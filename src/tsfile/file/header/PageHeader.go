@@ -3,6 +3,7 @@ package header
 import (
 	_ "bufio"
 	"bytes"
+	"fmt"
 	_ "log"
 	_ "os"
 	"tsfile/common/constant"
@@ -32,15 +33,36 @@ func (p *PageHeader) GetStatistics() *statistics.Statistics {
 	return &(p.statistics)
 }
 
-func (h *PageHeader) Deserialize(reader *utils.FileReader, dataType constant.TSDataType) {
+// Deserialize reads the header of a page of the given data type at the position of reader, a *utils.CorruptedError if
+// it is truncated or holds values out of range.
+func (h *PageHeader) Deserialize(reader *utils.FileReader, dataType constant.TSDataType) error {
+	offset := reader.Pos()
 	h.uncompressedSize = reader.ReadInt()
 	h.compressedSize = reader.ReadInt()
 	h.numberOfValues = reader.ReadInt()
 	h.max_timestamp = reader.ReadLong()
 	h.min_timestamp = reader.ReadLong()
-	h.statistics = statistics.Deserialize(reader, dataType)
+	stats, err := statistics.Deserialize(reader, dataType)
+	if err == nil {
+		h.statistics = stats
+		h.serializedSize = int32(3*constant.INT_LEN + 2*constant.LONG_LEN + h.statistics.GetSerializedSize())
+		err = reader.Err()
+	}
 
-	h.serializedSize = int32(3*constant.INT_LEN + 2*constant.LONG_LEN + h.statistics.GetSerializedSize())
+	switch {
+	case err != nil:
+	case h.uncompressedSize < 0 || h.compressedSize < 0:
+		err = fmt.Errorf("invalid page size %d, %d compressed", h.uncompressedSize, h.compressedSize)
+	case h.numberOfValues < 0:
+		err = fmt.Errorf("invalid number of values %d", h.numberOfValues)
+	case int64(h.numberOfValues) > 8*int64(h.uncompressedSize):
+		// every timestamp takes a bit at least
+		err = fmt.Errorf("%d values cannot fit into %d bytes", h.numberOfValues, h.uncompressedSize)
+	}
+	if err != nil {
+		return &utils.CorruptedError{Structure: "PageHeader", Offset: offset, Err: err}
+	}
+	return nil
 }
 
 func (h *PageHeader) GetUncompressedSize() int32 {
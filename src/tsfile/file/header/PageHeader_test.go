@@ -0,0 +1,43 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/file/metadata/statistics"
+)
+
+func TestPageHeader(t *testing.T) {
+	stats := statistics.GetStatsByType(int16(constant.INT32))
+	for _, v := range []int32{3, 1, 2} {
+		stats.UpdateStats(v)
+	}
+	pageHeader, _ := NewPageHeader(100, 80, 3, stats, 30, 10, int16(constant.INT32))
+	buffer := new(bytes.Buffer)
+	pageHeader.PageHeaderToMemory(buffer, int16(constant.INT32))
+	reader := utils.NewReaderAtFileReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	read := new(PageHeader)
+	if err := read.Deserialize(reader, constant.INT32); err != nil {
+		t.Fatal(err)
+	}
+	if read.GetUncompressedSize() != 100 || read.GetCompressedSize() != 80 || read.GetNumberOfValues() != 3 ||
+		read.Min_timestamp() != 10 || read.Max_timestamp() != 30 ||
+		read.GetSerializedSize() != pageHeader.GetSerializedSize() {
+		t.Fatal(fmt.Sprintf("Expected %+v got %+v", pageHeader, read))
+	}
+
+	// a page header claiming more values than the page can hold
+	pageHeader, _ = NewPageHeader(10, 10, 1<<30, statistics.GetStatsByType(int16(constant.INT32)), 1, 1,
+		int16(constant.INT32))
+	buffer = new(bytes.Buffer)
+	pageHeader.PageHeaderToMemory(buffer, int16(constant.INT32))
+	reader = utils.NewReaderAtFileReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	var corrupted *utils.CorruptedError
+	if err := new(PageHeader).Deserialize(reader, constant.INT32); !errors.As(err, &corrupted) ||
+		corrupted.Structure != "PageHeader" {
+		t.Fatal(fmt.Sprintf("Expected a corrupted page header got %v", err))
+	}
+}
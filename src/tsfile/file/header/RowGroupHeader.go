@@ -3,6 +3,7 @@ package header
 import (
 	_ "bufio"
 	"bytes"
+	"fmt"
 	_ "log"
 	_ "os"
 	"tsfile/common/constant"
@@ -16,12 +17,28 @@ type RowGroupHeader struct {
 	serializedSize int32
 }
 
-func (h *RowGroupHeader) Deserialize(reader *utils.FileReader) {
+// Deserialize reads the header at the position of reader, a *utils.CorruptedError if it is truncated or holds values
+// out of range.
+func (h *RowGroupHeader) Deserialize(reader *utils.FileReader) error {
+	offset := reader.Pos()
 	h.device = reader.ReadString()
 	h.dataSize = reader.ReadLong()
 	h.numberOfChunks = reader.ReadInt()
 
 	h.serializedSize = int32(constant.INT_LEN + len(h.device) + constant.LONG_LEN + constant.INT_LEN)
+
+	err := reader.Err()
+	switch {
+	case err != nil:
+	case h.dataSize < 0:
+		err = fmt.Errorf("invalid data size %d", h.dataSize)
+	case h.numberOfChunks < 0:
+		err = fmt.Errorf("invalid number of chunks %d", h.numberOfChunks)
+	}
+	if err != nil {
+		return &utils.CorruptedError{Structure: "RowGroupHeader", Offset: offset, Err: err}
+	}
+	return nil
 }
 
 func (h *RowGroupHeader) GetDevice() string {
@@ -2,7 +2,9 @@ package metadata
 
 import (
 	"bytes"
+	"fmt"
 	_ "log"
+	"math"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
 )
@@ -29,18 +31,41 @@ func (c *ChunkMetaData) FileOffsetOfCorrespondingData() int64 {
 	return c.fileOffsetOfCorrespondingData
 }
 
-func (f *ChunkMetaData) Deserialize(reader *utils.BytesReader) {
+func (f *ChunkMetaData) Deserialize(reader *utils.BytesReader) error {
+	start := reader.Pos()
 	f.sensor = reader.ReadString()
 	f.fileOffsetOfCorrespondingData = reader.ReadLong()
 	f.numOfPoints = reader.ReadLong()
 	f.totalByteSizeOfPagesOnDisk = reader.ReadLong()
 	f.startTime = reader.ReadLong()
 	f.endTime = reader.ReadLong()
+	if err := corrupted(reader, "ChunkMetaData", start, nil); err != nil {
+		return err
+	}
+	if f.fileOffsetOfCorrespondingData < 0 || f.numOfPoints < 0 || f.totalByteSizeOfPagesOnDisk < 0 {
+		return corrupted(reader, "ChunkMetaData", start, fmt.Errorf("invalid offset %d, %d points in %d bytes",
+			f.fileOffsetOfCorrespondingData, f.numOfPoints, f.totalByteSizeOfPagesOnDisk))
+	}
 
 	digest := new(TsDigest)
-	digest.Deserialize(reader)
+	if err := digest.Deserialize(reader); err != nil {
+		return err
+	}
 
 	f.valuesStatistics = digest
+	if unknownTimeRange(f.startTime, f.endTime) {
+		// the digest of such a chunk holds zeros, its pages are to be read instead
+		f.startTime, f.endTime = math.MinInt64, math.MaxInt64
+		f.valuesStatistics = nil
+	}
+	return nil
+}
+
+// unknownTimeRange tells whether a time range was left at [0, 0] or [-1, 0] by a writer that did not record the time
+// range and the statistics of chunks. A range truly holding time 0 (and -1) only is taken for unknown as well, which
+// costs reading its pages but gives the same results.
+func unknownTimeRange(startTime int64, endTime int64) bool {
+	return endTime == 0 && (startTime == 0 || startTime == -1)
 }
 
 func (f *ChunkMetaData) GetSerializedSize() int {
@@ -64,6 +89,14 @@ func (t *ChunkMetaData) GetEndTime() int64 {
 	return t.endTime
 }
 
+func (t *ChunkMetaData) GetNumOfPoints() int64 {
+	return t.numOfPoints
+}
+
+func (t *ChunkMetaData) GetDigest() *TsDigest {
+	return t.valuesStatistics
+}
+
 func (t *ChunkMetaData) SetTotalByteSizeOfPagesOnDisk(size int64) {
 	t.totalByteSizeOfPagesOnDisk = size
 }
@@ -2,7 +2,9 @@ package metadata
 
 import (
 	"bytes"
+	"fmt"
 	_ "log"
+	"math"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
 )
@@ -15,20 +17,30 @@ type DeviceMetaData struct {
 	sizeOfRowGroupMetaDataSli int
 }
 
-func (f *DeviceMetaData) Deserialize(reader *utils.BytesReader) {
+func (f *DeviceMetaData) Deserialize(reader *utils.BytesReader) error {
+	start := reader.Pos()
 	f.startTime = reader.ReadLong()
 	f.endTime = reader.ReadLong()
+	if unknownTimeRange(f.startTime, f.endTime) {
+		f.startTime, f.endTime = math.MinInt64, math.MaxInt64
+	}
 
 	size := int(reader.ReadInt())
+	if size < 0 {
+		return corrupted(reader, "DeviceMetaData", start, fmt.Errorf("invalid number of row groups %d", size))
+	}
 	if size > 0 {
 		f.rowGroupMetadataSli = make([]*RowGroupMetaData, 0)
 		for i := 0; i < size; i++ {
 			rowGroupMetaData := new(RowGroupMetaData)
-			rowGroupMetaData.Deserialize(reader)
+			if err := rowGroupMetaData.Deserialize(reader); err != nil {
+				return err
+			}
 
 			f.rowGroupMetadataSli = append(f.rowGroupMetadataSli, rowGroupMetaData)
 		}
 	}
+	return corrupted(reader, "DeviceMetaData", start, nil)
 }
 
 func (f *DeviceMetaData) GetSerializedSize() int {
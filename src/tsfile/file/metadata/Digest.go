@@ -2,11 +2,22 @@ package metadata
 
 import (
 	"bytes"
+	"fmt"
 	_ "log"
+	"math"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
 )
 
+// keys of the chunk statistics stored in a TsDigest
+const (
+	MAX_VALUE = "max_value"
+	MIN_VALUE = "min_value"
+	FIRST     = "first"
+	SUM       = "sum"
+	LAST      = "last"
+)
+
 type TsDigest struct {
 	//statistics     map[string][]byte
 	statistics     map[string]*bytes.Buffer
@@ -14,19 +25,23 @@ type TsDigest struct {
 	sizeOfList     int
 }
 
-func (f *TsDigest) Deserialize(reader *utils.BytesReader) {
+func (f *TsDigest) Deserialize(reader *utils.BytesReader) error {
+	start := reader.Pos()
 	f.serializedSize = constant.INT_LEN
 
 	f.statistics = make(map[string]*bytes.Buffer)
-	if size := int(reader.ReadInt()); size > 0 {
-		for i := 0; i < size; i++ {
-			key := reader.ReadString()
-			value := reader.ReadStringBinary()
+	size := int(reader.ReadInt())
+	if size < 0 {
+		return corrupted(reader, "TsDigest", start, fmt.Errorf("invalid number of statistics %d", size))
+	}
+	for i := 0; i < size && reader.Err() == nil; i++ {
+		key := reader.ReadString()
+		value := reader.ReadStringBinary()
 
-			f.statistics[key] = bytes.NewBuffer(value)
-			f.serializedSize += constant.INT_LEN + len(key) + constant.INT_LEN + len(value)
-		}
+		f.statistics[key] = bytes.NewBuffer(value)
+		f.serializedSize += constant.INT_LEN + len(key) + constant.INT_LEN + len(value)
 	}
+	return corrupted(reader, "TsDigest", start, nil)
 }
 
 func (t *TsDigest) SetStatistics(statistics map[string]*bytes.Buffer) {
@@ -49,6 +64,63 @@ func (t *TsDigest) ReCalculateSerializedSize() {
 	}
 }
 
+// GetValue decodes the statistic stored under key. MAX_VALUE, MIN_VALUE, FIRST and LAST are returned as the Go type of
+// dataType (string for TEXT), SUM always as float64. The second return value is false if the digest has no such key,
+// or its value is too short for the data type.
+func (t *TsDigest) GetValue(key string, dataType constant.TSDataType) (interface{}, bool) {
+	buf, ok := t.statistics[key]
+	if !ok || buf == nil || buf.Len() == 0 {
+		return nil, false
+	}
+
+	// floating point values are big-endian in the digest, unlike the BytesReader default
+	reader := utils.NewBytesReader(buf.Bytes())
+	var value interface{}
+	if key == SUM {
+		value = math.Float64frombits(uint64(reader.ReadLong()))
+	} else {
+		switch dataType {
+		case constant.BOOLEAN:
+			value = reader.ReadBool()
+		case constant.INT32:
+			value = reader.ReadInt()
+		case constant.INT64:
+			value = reader.ReadLong()
+		case constant.FLOAT:
+			value = math.Float32frombits(uint32(reader.ReadInt()))
+		case constant.DOUBLE:
+			value = math.Float64frombits(uint64(reader.ReadLong()))
+		case constant.TEXT:
+			value = string(buf.Bytes())
+		default:
+			return nil, false
+		}
+	}
+	if reader.Err() != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// SetExtendedStatistics adds extended statistics to those of the digest.
+func (t *TsDigest) SetExtendedStatistics(extended *ExtendedStatistics) {
+	if t.statistics == nil {
+		t.statistics = make(map[string]*bytes.Buffer)
+	}
+	extended.serializeTo(t.statistics)
+	t.ReCalculateSerializedSize()
+}
+
+// GetExtendedStatistics decodes the extended statistics of the digest. The second return value is false if the chunk
+// was written without them, or if the digest is nil.
+func (t *TsDigest) GetExtendedStatistics() (*ExtendedStatistics, bool) {
+	if t == nil {
+		return nil, false
+	}
+	extended := deserializeExtendedStatistics(t.statistics)
+	return extended, extended != nil
+}
+
 func (t *TsDigest) GetNullDigestSize() int {
 	return 4
 }
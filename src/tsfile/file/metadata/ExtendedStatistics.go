@@ -0,0 +1,127 @@
+package metadata
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"tsfile/common/sketch"
+	"tsfile/common/utils"
+)
+
+// keys of the extended statistics stored in a TsDigest, see ExtendedStatistics
+const (
+	COUNT          = "count"
+	SUM_OF_SQUARES = "sum_of_squares"
+	HISTOGRAM      = "histogram"
+	QUANTILES      = "quantiles"
+)
+
+// HistogramOffset is added to the binary exponents of values to make their histogram buckets positive.
+const HistogramOffset = 1100
+
+// ExtendedStatistics are statistics of the values of a numeric chunk beyond those its pages have, which the writer
+// stores in the digest of the chunk when conf.ExtendedDigest is set. Readers not knowing their keys ignore them.
+type ExtendedStatistics struct {
+	Count        int64
+	SumOfSquares float64
+	// Histogram counts the values by bucket, see HistogramBucket
+	Histogram map[int32]int64
+	Quantiles *sketch.Quantile
+}
+
+func NewExtendedStatistics() *ExtendedStatistics {
+	return &ExtendedStatistics{Histogram: make(map[int32]int64), Quantiles: sketch.NewQuantile()}
+}
+
+// HistogramBucket returns the histogram bucket of a value: 0 for 0, e + HistogramOffset for the positive values v with
+// 2^(e-1) <= v < 2^e, and -(e + HistogramOffset) for the negative values v with 2^(e-1) <= -v < 2^e.
+func HistogramBucket(value float64) int32 {
+	if value == 0 || math.IsNaN(value) {
+		return 0
+	}
+	_, exp := math.Frexp(value)
+	if value < 0 {
+		return -int32(exp + HistogramOffset)
+	}
+	return int32(exp + HistogramOffset)
+}
+
+// Update adds a value of a numeric series, other values are ignored.
+func (s *ExtendedStatistics) Update(value interface{}) {
+	var v float64
+	switch value := value.(type) {
+	case int32:
+		v = float64(value)
+	case int64:
+		v = float64(value)
+	case float32:
+		v = float64(value)
+	case float64:
+		v = value
+	default:
+		return
+	}
+	s.Count++
+	s.SumOfSquares += v * v
+	s.Histogram[HistogramBucket(v)]++
+	s.Quantiles.Add(v)
+}
+
+// serializeTo adds the statistics to those of a digest.
+func (s *ExtendedStatistics) serializeTo(statistics map[string]*bytes.Buffer) {
+	statistics[COUNT] = bytes.NewBuffer(utils.Int64ToByte(s.Count, 0))
+	statistics[SUM_OF_SQUARES] = bytes.NewBuffer(utils.Float64ToByte(s.SumOfSquares, 0))
+
+	buckets := make([]int32, 0, len(s.Histogram))
+	for bucket := range s.Histogram {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i] < buckets[j]
+	})
+	histogram := bytes.NewBuffer(utils.Int32ToByte(int32(len(buckets)), 0))
+	for _, bucket := range buckets {
+		histogram.Write(utils.Int32ToByte(bucket, 0))
+		histogram.Write(utils.Int64ToByte(s.Histogram[bucket], 0))
+	}
+	statistics[HISTOGRAM] = histogram
+
+	quantiles := new(bytes.Buffer)
+	s.Quantiles.Serialize(quantiles)
+	statistics[QUANTILES] = quantiles
+}
+
+// deserializeExtendedStatistics reads the statistics from those of a digest, nil if any is missing or invalid.
+func deserializeExtendedStatistics(statistics map[string]*bytes.Buffer) *ExtendedStatistics {
+	for _, key := range []string{COUNT, SUM_OF_SQUARES, HISTOGRAM, QUANTILES} {
+		if buf, ok := statistics[key]; !ok || buf == nil {
+			return nil
+		}
+	}
+	count, sumOfSquares := statistics[COUNT].Bytes(), statistics[SUM_OF_SQUARES].Bytes()
+	if len(count) != 8 || len(sumOfSquares) != 8 {
+		return nil
+	}
+	s := &ExtendedStatistics{Count: utils.NewBytesReader(count).ReadLong(),
+		SumOfSquares: math.Float64frombits(uint64(utils.NewBytesReader(sumOfSquares).ReadLong()))}
+
+	reader := utils.NewBytesReader(statistics[HISTOGRAM].Bytes())
+	if reader.Len() < 4 {
+		return nil
+	}
+	n := reader.ReadInt()
+	if n < 0 || n != reader.Len()/12 || reader.Len()%12 != 0 {
+		return nil
+	}
+	s.Histogram = make(map[int32]int64, n)
+	for i := int32(0); i < n; i++ {
+		bucket := reader.ReadInt()
+		s.Histogram[bucket] = reader.ReadLong()
+	}
+
+	var err error
+	if s.Quantiles, err = sketch.DeserializeQuantile(utils.NewBytesReader(statistics[QUANTILES].Bytes())); err != nil {
+		return nil
+	}
+	return s
+}
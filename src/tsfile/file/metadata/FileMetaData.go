@@ -3,6 +3,7 @@ package metadata
 import (
 	"bytes"
 	_ "encoding/binary"
+	"fmt"
 	_ "log"
 	"tsfile/common/utils"
 )
@@ -27,29 +28,39 @@ func (f *FileMetaData) DeviceMap() map[string]*DeviceMetaData {
 	return f.deviceMap
 }
 
-func (f *FileMetaData) Deserialize(metadata []byte) {
+// Deserialize reads the metadata, a *utils.CorruptedError if it is truncated or holds values out of range. The offset of
+// the error is relative to the start of metadata.
+func (f *FileMetaData) Deserialize(metadata []byte) error {
 	reader := utils.NewBytesReader(metadata)
 
 	f.deviceMap = make(map[string]*DeviceMetaData)
-	if size := int(reader.ReadInt()); size > 0 {
-		for i := 0; i < size; i++ {
-			key := reader.ReadString()
-
-			value := new(DeviceMetaData)
-			value.Deserialize(reader)
+	size := int(reader.ReadInt())
+	if size < 0 {
+		return corrupted(reader, "FileMetaData", 0, fmt.Errorf("invalid number of devices %d", size))
+	}
+	for i := 0; i < size; i++ {
+		key := reader.ReadString()
 
-			f.deviceMap[key] = value
+		value := new(DeviceMetaData)
+		if err := value.Deserialize(reader); err != nil {
+			return err
 		}
+
+		f.deviceMap[key] = value
 	}
 
 	f.timeSeriesMetadataMap = make(map[string]*TimeSeriesMetaData)
-	if size := int(reader.ReadInt()); size > 0 {
-		for i := 0; i < size; i++ {
-			value := new(TimeSeriesMetaData)
-			value.Deserialize(reader)
-
-			f.timeSeriesMetadataMap[value.GetSensor()] = value
+	size = int(reader.ReadInt())
+	if size < 0 {
+		return corrupted(reader, "FileMetaData", 0, fmt.Errorf("invalid number of series %d", size))
+	}
+	for i := 0; i < size; i++ {
+		value := new(TimeSeriesMetaData)
+		if err := value.Deserialize(reader); err != nil {
+			return err
 		}
+
+		f.timeSeriesMetadataMap[value.GetSensor()] = value
 	}
 
 	f.currentVersion = int(reader.ReadInt())
@@ -60,6 +71,19 @@ func (f *FileMetaData) Deserialize(metadata []byte) {
 	f.lastTimeSeriesMetadataOffset = reader.ReadLong()
 	f.firstTsDeltaObjectMetadataOffset = reader.ReadLong()
 	f.lastTsDeltaObjectMetadataOffset = reader.ReadLong()
+	return corrupted(reader, "FileMetaData", 0, nil)
+}
+
+// corrupted returns a *utils.CorruptedError of the structure read from start if err is not nil or reading failed, nil
+// otherwise.
+func corrupted(reader *utils.BytesReader, structure string, start int32, err error) error {
+	if err == nil {
+		err = reader.Err()
+	}
+	if err == nil {
+		return nil
+	}
+	return &utils.CorruptedError{Structure: structure, Offset: int64(start), Err: err}
 }
 
 func (f *FileMetaData) GetCurrentVersion() int {
@@ -3,6 +3,7 @@ package metadata
 import (
 	//_ "log"
 	"bytes"
+	"fmt"
 	"tsfile/common/constant"
 	"tsfile/common/log"
 	"tsfile/common/utils"
@@ -17,21 +18,28 @@ type RowGroupMetaData struct {
 	sizeOfChunkSli                int
 }
 
-func (f *RowGroupMetaData) Deserialize(reader *utils.BytesReader) {
+func (f *RowGroupMetaData) Deserialize(reader *utils.BytesReader) error {
+	start := reader.Pos()
 	f.device = reader.ReadString()
 	f.totalByteSize = reader.ReadLong()
 	f.fileOffsetOfCorrespondingData = reader.ReadLong()
 	size := int(reader.ReadInt())
+	if size < 0 {
+		return corrupted(reader, "RowGroupMetaData", start, fmt.Errorf("invalid number of chunks %d", size))
+	}
 
 	f.serializedSize = constant.INT_LEN + len(f.device) + constant.LONG_LEN + constant.INT_LEN
 
 	f.ChunkMetaDataSli = make([]*ChunkMetaData, 0)
 	for i := 0; i < size; i++ {
 		chunkMetaData := new(ChunkMetaData)
-		chunkMetaData.Deserialize(reader)
+		if err := chunkMetaData.Deserialize(reader); err != nil {
+			return err
+		}
 		f.ChunkMetaDataSli = append(f.ChunkMetaDataSli, chunkMetaData)
 		f.serializedSize += chunkMetaData.GetSerializedSize()
 	}
+	return corrupted(reader, "RowGroupMetaData", start, nil)
 }
 
 func (f *RowGroupMetaData) GetSerializedSize() int {
@@ -2,6 +2,7 @@ package metadata
 
 import (
 	"bytes"
+	"fmt"
 	_ "log"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
@@ -16,14 +17,20 @@ func (t *TimeSeriesMetaData) DataType() constant.TSDataType {
 	return t.dataType
 }
 
-func (f *TimeSeriesMetaData) Deserialize(reader *utils.BytesReader) {
+func (f *TimeSeriesMetaData) Deserialize(reader *utils.BytesReader) error {
+	start := reader.Pos()
 	if reader.ReadBool() {
 		f.sensor = reader.ReadString()
 	}
 
 	if reader.ReadBool() {
-		f.dataType = constant.TSDataType(reader.ReadShort())
+		dataType := reader.ReadShort()
+		if reader.Err() == nil && (dataType < int16(constant.BOOLEAN) || dataType > int16(constant.TEXT)) {
+			return corrupted(reader, "TimeSeriesMetaData", start, fmt.Errorf("unknown data type %d", dataType))
+		}
+		f.dataType = constant.TSDataType(dataType)
 	}
+	return corrupted(reader, "TimeSeriesMetaData", start, nil)
 }
 
 func (f *TimeSeriesMetaData) GetSensor() string {
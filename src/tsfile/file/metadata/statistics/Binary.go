@@ -25,6 +25,10 @@ func (s *Binary) Deserialize(reader *utils.FileReader) {
 	s.first = reader.ReadStringBinary()
 	s.last = reader.ReadStringBinary()
 	s.sum = reader.ReadDouble()
+	// written max first, see Serialize
+	if bytes.Compare(s.min, s.max) > 0 {
+		s.min, s.max = s.max, s.min
+	}
 }
 
 func (b *Binary) SizeOfDaum() int {
@@ -86,3 +90,36 @@ func (b *Binary) InitializeStats(max []byte, min []byte, first []byte, last []by
 func (s *Binary) GetSerializedSize() int {
 	return 4*4 + len(s.max) + len(s.min) + len(s.first) + len(s.last)
 }
+
+func (b *Binary) GetMax() interface{} {
+	return string(b.max)
+}
+
+func (b *Binary) GetMin() interface{} {
+	return string(b.min)
+}
+
+func (b *Binary) GetFirst() interface{} {
+	return string(b.first)
+}
+
+func (b *Binary) GetLast() interface{} {
+	return string(b.last)
+}
+
+func (b *Binary) GetSum() float64 {
+	return b.sum
+}
+
+func (b *Binary) Merge(stats Statistics) {
+	other := stats.(*Binary)
+	if !other.isEmpty {
+		return
+	}
+	if !b.isEmpty {
+		b.InitializeStats(other.max, other.min, other.first, other.last, other.sum)
+		b.isEmpty = true
+	} else {
+		b.UpdateValue(other.max, other.min, other.first, other.last, other.sum)
+	}
+}
@@ -20,6 +20,10 @@ func (s *Boolean) Deserialize(reader *utils.FileReader) {
 	s.first = reader.ReadBool()
 	s.last = reader.ReadBool()
 	s.sum = reader.ReadDouble()
+	// written max first, see Serialize
+	if s.min && !s.max {
+		s.min, s.max = s.max, s.min
+	}
 }
 
 func (b *Boolean) SizeOfDaum() int {
@@ -53,7 +57,6 @@ func (b *Boolean) UpdateStats(iValue interface{}) {
 		b.isEmpty = true
 	} else {
 		b.UpdateValue(value, value, value, value, 0)
-		b.isEmpty = false
 	}
 }
 
@@ -84,3 +87,36 @@ func (s *Boolean) GetSerializedSize() int {
 //		isEmpty:true,
 //	},nil
 //}
+
+func (b *Boolean) GetMax() interface{} {
+	return b.max
+}
+
+func (b *Boolean) GetMin() interface{} {
+	return b.min
+}
+
+func (b *Boolean) GetFirst() interface{} {
+	return b.first
+}
+
+func (b *Boolean) GetLast() interface{} {
+	return b.last
+}
+
+func (b *Boolean) GetSum() float64 {
+	return b.sum
+}
+
+func (b *Boolean) Merge(stats Statistics) {
+	other := stats.(*Boolean)
+	if !other.isEmpty {
+		return
+	}
+	if !b.isEmpty {
+		b.InitializeStats(other.max, other.min, other.first, other.last, other.sum)
+		b.isEmpty = true
+	} else {
+		b.UpdateValue(other.max, other.min, other.first, other.last, other.sum)
+	}
+}
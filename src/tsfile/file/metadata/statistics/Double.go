@@ -20,6 +20,10 @@ func (s *Double) Deserialize(reader *utils.FileReader) {
 	s.first = reader.ReadDouble()
 	s.last = reader.ReadDouble()
 	s.sum = reader.ReadDouble()
+	// written max first, see Serialize
+	if s.min > s.max {
+		s.min, s.max = s.max, s.min
+	}
 }
 
 func (d *Double) SizeOfDaum() int {
@@ -78,3 +82,36 @@ func (d *Double) InitializeStats(max float64, min float64, first float64, last f
 func (s *Double) GetSerializedSize() int {
 	return constant.DOUBLE_LEN * 5
 }
+
+func (d *Double) GetMax() interface{} {
+	return d.max
+}
+
+func (d *Double) GetMin() interface{} {
+	return d.min
+}
+
+func (d *Double) GetFirst() interface{} {
+	return d.first
+}
+
+func (d *Double) GetLast() interface{} {
+	return d.last
+}
+
+func (d *Double) GetSum() float64 {
+	return d.sum
+}
+
+func (d *Double) Merge(stats Statistics) {
+	other := stats.(*Double)
+	if !other.isEmpty {
+		return
+	}
+	if !d.isEmpty {
+		d.InitializeStats(other.max, other.min, other.first, other.last, other.sum)
+		d.isEmpty = true
+	} else {
+		d.UpdateValue(other.max, other.min, other.first, other.last, other.sum)
+	}
+}
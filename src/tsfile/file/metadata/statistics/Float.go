@@ -20,6 +20,10 @@ func (s *Float) Deserialize(reader *utils.FileReader) {
 	s.first = reader.ReadFloat()
 	s.last = reader.ReadFloat()
 	s.sum = reader.ReadDouble()
+	// written max first, see Serialize
+	if s.min > s.max {
+		s.min, s.max = s.max, s.min
+	}
 }
 
 func (f *Float) SizeOfDaum() int {
@@ -78,3 +82,36 @@ func (f *Float) InitializeStats(max float32, min float32, first float32, last fl
 func (s *Float) GetSerializedSize() int {
 	return 4*constant.FLOAT_LEN + constant.DOUBLE_LEN
 }
+
+func (f *Float) GetMax() interface{} {
+	return f.max
+}
+
+func (f *Float) GetMin() interface{} {
+	return f.min
+}
+
+func (f *Float) GetFirst() interface{} {
+	return f.first
+}
+
+func (f *Float) GetLast() interface{} {
+	return f.last
+}
+
+func (f *Float) GetSum() float64 {
+	return f.sum
+}
+
+func (f *Float) Merge(stats Statistics) {
+	other := stats.(*Float)
+	if !other.isEmpty {
+		return
+	}
+	if !f.isEmpty {
+		f.InitializeStats(other.max, other.min, other.first, other.last, other.sum)
+		f.isEmpty = true
+	} else {
+		f.UpdateValue(other.max, other.min, other.first, other.last, other.sum)
+	}
+}
@@ -20,6 +20,10 @@ func (s *Integer) Deserialize(reader *utils.FileReader) {
 	s.first = reader.ReadInt()
 	s.last = reader.ReadInt()
 	s.sum = reader.ReadDouble()
+	// written max first, see Serialize
+	if s.min > s.max {
+		s.min, s.max = s.max, s.min
+	}
 }
 
 func (i *Integer) SizeOfDaum() int {
@@ -78,3 +82,36 @@ func (i *Integer) InitializeStats(max int32, min int32, first int32, last int32,
 func (s *Integer) GetSerializedSize() int {
 	return 4*constant.INT_LEN + constant.DOUBLE_LEN
 }
+
+func (i *Integer) GetMax() interface{} {
+	return i.max
+}
+
+func (i *Integer) GetMin() interface{} {
+	return i.min
+}
+
+func (i *Integer) GetFirst() interface{} {
+	return i.first
+}
+
+func (i *Integer) GetLast() interface{} {
+	return i.last
+}
+
+func (i *Integer) GetSum() float64 {
+	return i.sum
+}
+
+func (i *Integer) Merge(stats Statistics) {
+	other := stats.(*Integer)
+	if !other.isEmpty {
+		return
+	}
+	if !i.isEmpty {
+		i.InitializeStats(other.max, other.min, other.first, other.last, other.sum)
+		i.isEmpty = true
+	} else {
+		i.UpdateValue(other.max, other.min, other.first, other.last, other.sum)
+	}
+}
@@ -20,6 +20,10 @@ func (s *Long) Deserialize(reader *utils.FileReader) {
 	s.first = reader.ReadLong()
 	s.last = reader.ReadLong()
 	s.sum = reader.ReadDouble()
+	// written max first, see Serialize
+	if s.min > s.max {
+		s.min, s.max = s.max, s.min
+	}
 }
 
 func (l *Long) SizeOfDaum() int {
@@ -78,3 +82,36 @@ func (l *Long) InitializeStats(max int64, min int64, first int64, last int64, su
 func (s *Long) GetSerializedSize() int {
 	return 4*constant.LONG_LEN + constant.DOUBLE_LEN
 }
+
+func (l *Long) GetMax() interface{} {
+	return l.max
+}
+
+func (l *Long) GetMin() interface{} {
+	return l.min
+}
+
+func (l *Long) GetFirst() interface{} {
+	return l.first
+}
+
+func (l *Long) GetLast() interface{} {
+	return l.last
+}
+
+func (l *Long) GetSum() float64 {
+	return l.sum
+}
+
+func (l *Long) Merge(stats Statistics) {
+	other := stats.(*Long)
+	if !other.isEmpty {
+		return
+	}
+	if !l.isEmpty {
+		l.InitializeStats(other.max, other.min, other.first, other.last, other.sum)
+		l.isEmpty = true
+	} else {
+		l.UpdateValue(other.max, other.min, other.first, other.last, other.sum)
+	}
+}
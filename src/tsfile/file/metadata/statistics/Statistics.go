@@ -2,6 +2,7 @@ package statistics
 
 import (
 	"bytes"
+	"fmt"
 	"strconv"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
@@ -17,9 +18,19 @@ type Statistics interface {
 	GetSumByte(tdt int16) []byte
 	SizeOfDaum() int
 	UpdateStats(value interface{})
+	// typed accessors, values are of the Go type matching the series data type (string for TEXT)
+	GetMax() interface{}
+	GetMin() interface{}
+	GetFirst() interface{}
+	GetLast() interface{}
+	GetSum() float64
+	// Merge folds the statistics of a later run of values of the same data type into this one
+	Merge(stats Statistics)
 }
 
-func Deserialize(reader *utils.FileReader, dataType constant.TSDataType) Statistics {
+// Deserialize reads the statistics of a series of the given data type, an error if the data type is unknown. Whether
+// reading them failed is told by reader.Err.
+func Deserialize(reader *utils.FileReader, dataType constant.TSDataType) (Statistics, error) {
 	var statistics Statistics
 
 	switch dataType {
@@ -36,12 +47,12 @@ func Deserialize(reader *utils.FileReader, dataType constant.TSDataType) Statist
 	case constant.TEXT:
 		statistics = new(Binary)
 	default:
-		panic("Statistics unknown dataType: " + strconv.Itoa(int(dataType)))
+		return nil, fmt.Errorf("unknown data type %d", dataType)
 	}
 
 	statistics.Deserialize(reader)
 
-	return statistics
+	return statistics, nil
 }
 
 func GetStatsByType(tsDataType int16) Statistics {
@@ -65,26 +76,29 @@ func GetStatsByType(tsDataType int16) Statistics {
 	return statistics
 }
 
+// Serialize writes min before max, the order of the TsFile format. Files written when max went first are read back by
+// swapping the two values whenever min is greater than max, which cannot be otherwise.
 func Serialize(s Statistics, buffer *bytes.Buffer, tsDataType int16) int {
 	var length int
 	if s.SizeOfDaum() == 0 {
 		return 0
 	} else if s.SizeOfDaum() != -1 {
-		buffer.Write(s.GetMaxByte(tsDataType))
+		// min goes before max, which is the order Deserialize reads them in
 		buffer.Write(s.GetMinByte(tsDataType))
+		buffer.Write(s.GetMaxByte(tsDataType))
 		buffer.Write(s.GetFirstByte(tsDataType))
 		buffer.Write(s.GetLastByte(tsDataType))
 		buffer.Write(s.GetSumByte(tsDataType))
 		length = s.SizeOfDaum()*4 + 8
 	} else {
-		maxData := s.GetMaxByte(tsDataType)
-		buffer.Write(utils.Int32ToByte(int32(len(maxData)), 0))
-		maxLen, _ := buffer.Write(maxData)
-		length += maxLen
 		minData := s.GetMinByte(tsDataType)
 		buffer.Write(utils.Int32ToByte(int32(len(minData)), 0))
 		minLen, _ := buffer.Write(minData)
 		length += minLen
+		maxData := s.GetMaxByte(tsDataType)
+		buffer.Write(utils.Int32ToByte(int32(len(maxData)), 0))
+		maxLen, _ := buffer.Write(maxData)
+		length += maxLen
 		firstData := s.GetFirstByte(tsDataType)
 		buffer.Write(utils.Int32ToByte(int32(len(firstData)), 0))
 		firstLen, _ := buffer.Write(firstData)
@@ -0,0 +1,75 @@
+package statistics
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+)
+
+func TestStatistics(t *testing.T) {
+	cases := []struct {
+		dataType constant.TSDataType
+		values   []interface{}
+		// min, max, first and last
+		expected []interface{}
+	}{
+		{constant.BOOLEAN, []interface{}{true, false, true}, []interface{}{false, true, true, true}},
+		{constant.INT32, []interface{}{int32(5), int32(-3), int32(9), int32(1)},
+			[]interface{}{int32(-3), int32(9), int32(5), int32(1)}},
+		{constant.INT64, []interface{}{int64(5), int64(-3), int64(9)}, []interface{}{int64(-3), int64(9), int64(5),
+			int64(9)}},
+		{constant.FLOAT, []interface{}{float32(1.5), float32(-2.5)}, []interface{}{float32(-2.5), float32(1.5),
+			float32(1.5), float32(-2.5)}},
+		{constant.DOUBLE, []interface{}{0.5, 4.0, 2.0}, []interface{}{0.5, 4.0, 0.5, 2.0}},
+		{constant.TEXT, []interface{}{"b", "c", "a"}, []interface{}{"a", "c", "b", "a"}},
+	}
+	for _, c := range cases {
+		stats := GetStatsByType(int16(c.dataType))
+		for _, v := range c.values {
+			stats.UpdateStats(v)
+		}
+		got := []interface{}{stats.GetMin(), stats.GetMax(), stats.GetFirst(), stats.GetLast()}
+		if fmt.Sprint(got) != fmt.Sprint(c.expected) {
+			t.Fatal(fmt.Sprintf("%v: expected %v got %v", c.dataType, c.expected, got))
+		}
+
+		// the statistics read back as written
+		buffer := new(bytes.Buffer)
+		Serialize(stats, buffer, int16(c.dataType))
+		reader := utils.NewReaderAtFileReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		read, err := Deserialize(reader, c.dataType)
+		if err != nil || reader.Err() != nil {
+			t.Fatal(fmt.Sprintf("%v: %v, %v", c.dataType, err, reader.Err()))
+		}
+		got = []interface{}{read.GetMin(), read.GetMax(), read.GetFirst(), read.GetLast()}
+		if fmt.Sprint(got) != fmt.Sprint(c.expected) || read.GetSum() != stats.GetSum() {
+			t.Fatal(fmt.Sprintf("%v: expected %v summing to %v got %v, %v", c.dataType, c.expected, stats.GetSum(),
+				got, read.GetSum()))
+		}
+
+		// truncated statistics are reported by the reader
+		reader = utils.NewReaderAtFileReader(bytes.NewReader(buffer.Bytes()[:buffer.Len()-1]),
+			int64(buffer.Len()-1))
+		if _, err := Deserialize(reader, c.dataType); err != nil || reader.Err() == nil {
+			t.Fatal(fmt.Sprintf("%v: expected truncated statistics to be reported got %v", c.dataType, err))
+		}
+	}
+
+	// statistics written max first read back min first
+	buffer := new(bytes.Buffer)
+	for _, v := range []int32{9, -3, 5, 1} {
+		buffer.Write(utils.Int32ToByte(v, 0))
+	}
+	buffer.Write(utils.Float64ToByte(12, 0))
+	read, err := Deserialize(utils.NewReaderAtFileReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len())),
+		constant.INT32)
+	if err != nil || read.GetMin() != int32(-3) || read.GetMax() != int32(9) {
+		t.Fatal(fmt.Sprintf("Expected min -3 and max 9 got %v, %v", read, err))
+	}
+
+	if _, err := Deserialize(utils.NewReaderAtFileReader(bytes.NewReader(nil), 0), constant.INVALID); err == nil {
+		t.Fatal("Expected an unknown data type to be rejected")
+	}
+}
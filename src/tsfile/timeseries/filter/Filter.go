@@ -5,3 +5,90 @@ package filter
 type Filter interface {
 	Satisfy(val interface{}) bool
 }
+
+// RangeFilter is a Filter that can also be checked against all the values within [min, max] at once, so that a chunk
+// or a page can be accepted or skipped by its statistics without decoding it.
+type RangeFilter interface {
+	Filter
+	// SatisfyAny returns false only if no value within [min, max] can satisfy the filter.
+	SatisfyAny(min interface{}, max interface{}) bool
+	// SatisfyAll returns true only if every value within [min, max] satisfies the filter.
+	SatisfyAll(min interface{}, max interface{}) bool
+}
+
+// SatisfyAny is RangeFilter.SatisfyAny for any filter, a filter without range support may be satisfied by any range.
+func SatisfyAny(f Filter, min interface{}, max interface{}) bool {
+	if rf, ok := f.(RangeFilter); ok {
+		return rf.SatisfyAny(min, max)
+	}
+	return true
+}
+
+// SatisfyAll is RangeFilter.SatisfyAll for any filter, a filter without range support is never known to be satisfied
+// by a whole range.
+func SatisfyAll(f Filter, min interface{}, max interface{}) bool {
+	if rf, ok := f.(RangeFilter); ok {
+		return rf.SatisfyAll(min, max)
+	}
+	return false
+}
+
+// PageFilter is a Filter of RowRecords that can be checked against the time range and the value range of a page (or a
+// chunk) of a series, so that the page can be skipped without decoding it.
+type PageFilter interface {
+	Filter
+	// MayMatch returns false only if no row at a timestamp within [minTime, maxTime] can satisfy the filter, be it holding
+	// a value of the series at path within [minValue, maxValue] or no value of that series at all. The value range is
+	// nil if unknown.
+	MayMatch(path string, minTime int64, maxTime int64, minValue interface{}, maxValue interface{}) bool
+}
+
+// MayMatch is PageFilter.MayMatch for any filter, a filter without page support may match any page.
+func MayMatch(f Filter, path string, minTime int64, maxTime int64, minValue interface{}, maxValue interface{}) bool {
+	if pf, ok := f.(PageFilter); ok {
+		return pf.MayMatch(path, minTime, maxTime, minValue, maxValue)
+	}
+	return true
+}
+
+// PathFilter is a Filter of RowRecords that tells the series whose values it reads, so that the rows it is checked on
+// can be made to hold them.
+type PathFilter interface {
+	Filter
+	// Paths returns the series read by the filter.
+	Paths() []string
+}
+
+// Paths returns the series read by the filters, each once, see PathFilter. A filter without path support reads none.
+func Paths(filters ...Filter) []string {
+	var paths []string
+	added := make(map[string]bool)
+	for _, f := range filters {
+		if pf, ok := f.(PathFilter); ok {
+			for _, path := range pf.Paths() {
+				if !added[path] {
+					added[path] = true
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// MissingFilter is a Filter of RowRecords that tells whether a row holding none of the series it reads may satisfy it,
+// so that the rows on which only the selected series have values are not left out.
+type MissingFilter interface {
+	Filter
+	// SatisfyMissing returns false only if no row without a value of the series read by the filter can satisfy it.
+	SatisfyMissing() bool
+}
+
+// SatisfyMissing is MissingFilter.SatisfyMissing for any filter, a filter without missing support may be satisfied by
+// such a row.
+func SatisfyMissing(f Filter) bool {
+	if mf, ok := f.(MissingFilter); ok {
+		return mf.SatisfyMissing()
+	}
+	return true
+}
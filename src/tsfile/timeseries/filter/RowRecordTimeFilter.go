@@ -19,3 +19,19 @@ func (s *RowRecordTimeFilter) Satisfy(val interface{}) bool {
 	}
 	return false
 }
+
+func (s *RowRecordTimeFilter) MayMatch(path string, minTime int64, maxTime int64, minValue interface{},
+	maxValue interface{}) bool {
+	return SatisfyAny(s.Filter, minTime, maxTime)
+}
+
+// SatisfyAny tells whether a row at a timestamp within [min, max] may satisfy the filter, so that a RowRecordTimeFilter
+// and its combinations can prune by time range where the timestamps are filtered on their own (e.g. aggregations).
+func (s *RowRecordTimeFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	return SatisfyAny(s.Filter, min, max)
+}
+
+// SatisfyAll tells whether the rows at every timestamp within [min, max] satisfy the filter.
+func (s *RowRecordTimeFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	return SatisfyAll(s.Filter, min, max)
+}
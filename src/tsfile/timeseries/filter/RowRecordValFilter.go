@@ -31,9 +31,29 @@ func (s *RowRecordValFilter) Satisfy(val interface{}) bool {
 					return s.filter.Satisfy(m.Values()[i])
 				}
 			}
+			// the row holds no value of the series
+			return s.filter.Satisfy(nil)
 		}
 
 		return s.filter.Satisfy(m.Values()[s.seriesIndex])
 	}
 	return false
 }
+
+func (s *RowRecordValFilter) Paths() []string {
+	return []string{s.seriesName}
+}
+
+// MayMatch checks the value range if the filter is on the series at path and the range is known (not nil), a row
+// without a value of the series satisfying the filter if the inner filter accepts nil (e.g. a NotFilter).
+func (s *RowRecordValFilter) MayMatch(path string, minTime int64, maxTime int64, minValue interface{},
+	maxValue interface{}) bool {
+	if path != s.seriesName || minValue == nil || maxValue == nil {
+		return true
+	}
+	return SatisfyAny(s.filter, minValue, maxValue) || s.filter.Satisfy(nil)
+}
+
+func (s *RowRecordValFilter) SatisfyMissing() bool {
+	return s.filter.Satisfy(nil)
+}
@@ -19,3 +19,43 @@ func (f *AndFilter) Satisfy(val interface{}) bool {
 	}
 	return true
 }
+
+func (f *AndFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	for _, filt := range f.Filters {
+		if !filter.SatisfyAny(filt, min, max) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *AndFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	for _, filt := range f.Filters {
+		if !filter.SatisfyAll(filt, min, max) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *AndFilter) MayMatch(path string, minTime int64, maxTime int64, minValue interface{}, maxValue interface{}) bool {
+	for _, filt := range f.Filters {
+		if !filter.MayMatch(filt, path, minTime, maxTime, minValue, maxValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *AndFilter) Paths() []string {
+	return filter.Paths(f.Filters...)
+}
+
+func (f *AndFilter) SatisfyMissing() bool {
+	for _, filt := range f.Filters {
+		if !filter.SatisfyMissing(filt) {
+			return false
+		}
+	}
+	return true
+}
@@ -7,3 +7,11 @@ type EmptyFilter struct {
 func (EmptyFilter) Satisfy(val interface{}) bool {
 	return true
 }
+
+func (EmptyFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	return true
+}
+
+func (EmptyFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	return true
+}
@@ -4,7 +4,7 @@ import "strings"
 
 // EqFilters compare the input value to the Reference value, and return true iff they are equal.
 // Type mismatch will set the return value to false.
-// Supported types: int32(int) int64(long) float32(float) float64(double) string.
+// Supported types: int32(int) int64(long) float32(float) float64(double) string bool.
 type IntEqFilter struct {
 	Ref int32
 }
@@ -16,6 +16,20 @@ func (f *IntEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *IntEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if min, max, ok := intRange(min, max); ok {
+		return min <= f.Ref && f.Ref <= max
+	}
+	return false
+}
+
+func (f *IntEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if min, max, ok := intRange(min, max); ok {
+		return min == f.Ref && max == f.Ref
+	}
+	return false
+}
+
 type LongEqFilter struct {
 	Ref int64
 }
@@ -27,6 +41,20 @@ func (f *LongEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *LongEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if min, max, ok := longRange(min, max); ok {
+		return min <= f.Ref && f.Ref <= max
+	}
+	return false
+}
+
+func (f *LongEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if min, max, ok := longRange(min, max); ok {
+		return min == f.Ref && max == f.Ref
+	}
+	return false
+}
+
 type StrEqFilter struct {
 	Ref string
 }
@@ -38,6 +66,20 @@ func (f *StrEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *StrEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if min, max, ok := strRange(min, max); ok {
+		return strings.Compare(min, f.Ref) <= 0 && strings.Compare(f.Ref, max) <= 0
+	}
+	return false
+}
+
+func (f *StrEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if min, max, ok := strRange(min, max); ok {
+		return strings.Compare(min, f.Ref) == 0 && strings.Compare(max, f.Ref) == 0
+	}
+	return false
+}
+
 type FloatEqFilter struct {
 	Ref float32
 }
@@ -49,6 +91,20 @@ func (f *FloatEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *FloatEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if min, max, ok := floatRange(min, max); ok {
+		return min <= f.Ref && f.Ref <= max
+	}
+	return false
+}
+
+func (f *FloatEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if min, max, ok := floatRange(min, max); ok {
+		return min == f.Ref && max == f.Ref
+	}
+	return false
+}
+
 type DoubleEqFilter struct {
 	Ref float64
 }
@@ -59,3 +115,28 @@ func (f *DoubleEqFilter) Satisfy(val interface{}) bool {
 	}
 	return false
 }
+
+func (f *DoubleEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if min, max, ok := doubleRange(min, max); ok {
+		return min <= f.Ref && f.Ref <= max
+	}
+	return false
+}
+
+func (f *DoubleEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if min, max, ok := doubleRange(min, max); ok {
+		return min == f.Ref && max == f.Ref
+	}
+	return false
+}
+
+type BoolEqFilter struct {
+	Ref bool
+}
+
+func (f *BoolEqFilter) Satisfy(val interface{}) bool {
+	if v, ok := val.(bool); ok {
+		return f.Ref == v
+	}
+	return false
+}
@@ -16,6 +16,20 @@ func (f *IntGtEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *IntGtEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := max.(int32); ok {
+		return v >= f.Ref
+	}
+	return false
+}
+
+func (f *IntGtEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := min.(int32); ok {
+		return v >= f.Ref
+	}
+	return false
+}
+
 type LongGtEqFilter struct {
 	Ref int64
 }
@@ -27,6 +41,20 @@ func (f *LongGtEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *LongGtEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := max.(int64); ok {
+		return v >= f.Ref
+	}
+	return false
+}
+
+func (f *LongGtEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := min.(int64); ok {
+		return v >= f.Ref
+	}
+	return false
+}
+
 type StrGtEqFilter struct {
 	Ref string
 }
@@ -38,6 +66,20 @@ func (f *StrGtEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *StrGtEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := max.(string); ok {
+		return strings.Compare(v, f.Ref) >= 0
+	}
+	return false
+}
+
+func (f *StrGtEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := min.(string); ok {
+		return strings.Compare(v, f.Ref) >= 0
+	}
+	return false
+}
+
 type FloatGtEqFilter struct {
 	Ref float32
 }
@@ -49,6 +91,20 @@ func (f *FloatGtEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *FloatGtEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := max.(float32); ok {
+		return v >= f.Ref
+	}
+	return false
+}
+
+func (f *FloatGtEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := min.(float32); ok {
+		return v >= f.Ref
+	}
+	return false
+}
+
 type DoubleGtEqFilter struct {
 	Ref float64
 }
@@ -59,3 +115,17 @@ func (f *DoubleGtEqFilter) Satisfy(val interface{}) bool {
 	}
 	return false
 }
+
+func (f *DoubleGtEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := max.(float64); ok {
+		return v >= f.Ref
+	}
+	return false
+}
+
+func (f *DoubleGtEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := min.(float64); ok {
+		return v >= f.Ref
+	}
+	return false
+}
@@ -16,6 +16,20 @@ func (f *IntGtFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *IntGtFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := max.(int32); ok {
+		return v > f.Ref
+	}
+	return false
+}
+
+func (f *IntGtFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := min.(int32); ok {
+		return v > f.Ref
+	}
+	return false
+}
+
 type LongGtFilter struct {
 	Ref int64
 }
@@ -27,6 +41,20 @@ func (f *LongGtFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *LongGtFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := max.(int64); ok {
+		return v > f.Ref
+	}
+	return false
+}
+
+func (f *LongGtFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := min.(int64); ok {
+		return v > f.Ref
+	}
+	return false
+}
+
 type StrGtFilter struct {
 	Ref string
 }
@@ -38,6 +66,20 @@ func (f *StrGtFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *StrGtFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := max.(string); ok {
+		return strings.Compare(v, f.Ref) > 0
+	}
+	return false
+}
+
+func (f *StrGtFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := min.(string); ok {
+		return strings.Compare(v, f.Ref) > 0
+	}
+	return false
+}
+
 type FloatGtFilter struct {
 	Ref float32
 }
@@ -49,6 +91,20 @@ func (f *FloatGtFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *FloatGtFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := max.(float32); ok {
+		return v > f.Ref
+	}
+	return false
+}
+
+func (f *FloatGtFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := min.(float32); ok {
+		return v > f.Ref
+	}
+	return false
+}
+
 type DoubleGtFilter struct {
 	Ref float64
 }
@@ -59,3 +115,17 @@ func (f *DoubleGtFilter) Satisfy(val interface{}) bool {
 	}
 	return false
 }
+
+func (f *DoubleGtFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := max.(float64); ok {
+		return v > f.Ref
+	}
+	return false
+}
+
+func (f *DoubleGtFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := min.(float64); ok {
+		return v > f.Ref
+	}
+	return false
+}
@@ -16,6 +16,20 @@ func (f *IntLtEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *IntLtEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := min.(int32); ok {
+		return v <= f.Ref
+	}
+	return false
+}
+
+func (f *IntLtEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := max.(int32); ok {
+		return v <= f.Ref
+	}
+	return false
+}
+
 type LongLtEqFilter struct {
 	Ref int64
 }
@@ -27,6 +41,20 @@ func (f *LongLtEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *LongLtEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := min.(int64); ok {
+		return v <= f.Ref
+	}
+	return false
+}
+
+func (f *LongLtEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := max.(int64); ok {
+		return v <= f.Ref
+	}
+	return false
+}
+
 type StrLtEqFilter struct {
 	Ref string
 }
@@ -38,6 +66,20 @@ func (f *StrLtEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *StrLtEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := min.(string); ok {
+		return strings.Compare(v, f.Ref) <= 0
+	}
+	return false
+}
+
+func (f *StrLtEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := max.(string); ok {
+		return strings.Compare(v, f.Ref) <= 0
+	}
+	return false
+}
+
 type FloatLtEqFilter struct {
 	Ref float32
 }
@@ -49,6 +91,20 @@ func (f *FloatLtEqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *FloatLtEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := min.(float32); ok {
+		return v <= f.Ref
+	}
+	return false
+}
+
+func (f *FloatLtEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := max.(float32); ok {
+		return v <= f.Ref
+	}
+	return false
+}
+
 type DoubleLtEqFilter struct {
 	Ref float64
 }
@@ -59,3 +115,17 @@ func (f *DoubleLtEqFilter) Satisfy(val interface{}) bool {
 	}
 	return false
 }
+
+func (f *DoubleLtEqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := min.(float64); ok {
+		return v <= f.Ref
+	}
+	return false
+}
+
+func (f *DoubleLtEqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := max.(float64); ok {
+		return v <= f.Ref
+	}
+	return false
+}
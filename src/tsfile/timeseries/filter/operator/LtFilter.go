@@ -16,6 +16,20 @@ func (f *IntLtFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *IntLtFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := min.(int32); ok {
+		return v < f.Ref
+	}
+	return false
+}
+
+func (f *IntLtFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := max.(int32); ok {
+		return v < f.Ref
+	}
+	return false
+}
+
 type LongLtFilter struct {
 	Ref int64
 }
@@ -27,6 +41,20 @@ func (f *LongLtFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *LongLtFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := min.(int64); ok {
+		return v < f.Ref
+	}
+	return false
+}
+
+func (f *LongLtFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := max.(int64); ok {
+		return v < f.Ref
+	}
+	return false
+}
+
 type StrLtFilter struct {
 	Ref string
 }
@@ -38,6 +66,20 @@ func (f *StrLtFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *StrLtFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := min.(string); ok {
+		return strings.Compare(v, f.Ref) < 0
+	}
+	return false
+}
+
+func (f *StrLtFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := max.(string); ok {
+		return strings.Compare(v, f.Ref) < 0
+	}
+	return false
+}
+
 type FloatLtFilter struct {
 	Ref float32
 }
@@ -49,6 +91,20 @@ func (f *FloatLtFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *FloatLtFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := min.(float32); ok {
+		return v < f.Ref
+	}
+	return false
+}
+
+func (f *FloatLtFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := max.(float32); ok {
+		return v < f.Ref
+	}
+	return false
+}
+
 type DoubleLtFilter struct {
 	Ref float64
 }
@@ -59,3 +115,17 @@ func (f *DoubleLtFilter) Satisfy(val interface{}) bool {
 	}
 	return false
 }
+
+func (f *DoubleLtFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if v, ok := min.(float64); ok {
+		return v < f.Ref
+	}
+	return false
+}
+
+func (f *DoubleLtFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if v, ok := max.(float64); ok {
+		return v < f.Ref
+	}
+	return false
+}
@@ -4,7 +4,7 @@ import "strings"
 
 // NeqFilters compare the input value to the Reference value, and return true iff the input != the Reference.
 // Type mismatch will set the return value to false. Use lexicographical order for strings.
-// Supported types: int32(int) int64(long) float32(float) float64(double) string.
+// Supported types: int32(int) int64(long) float32(float) float64(double) string bool.
 type IntNeqFilter struct {
 	Ref int32
 }
@@ -16,6 +16,20 @@ func (f *IntNeqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *IntNeqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if min, max, ok := intRange(min, max); ok {
+		return min != f.Ref || max != f.Ref
+	}
+	return false
+}
+
+func (f *IntNeqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if min, max, ok := intRange(min, max); ok {
+		return f.Ref < min || f.Ref > max
+	}
+	return false
+}
+
 type LongNeqFilter struct {
 	Ref int64
 }
@@ -27,6 +41,20 @@ func (f *LongNeqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *LongNeqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if min, max, ok := longRange(min, max); ok {
+		return min != f.Ref || max != f.Ref
+	}
+	return false
+}
+
+func (f *LongNeqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if min, max, ok := longRange(min, max); ok {
+		return f.Ref < min || f.Ref > max
+	}
+	return false
+}
+
 type StrNeqFilter struct {
 	Ref string
 }
@@ -38,6 +66,20 @@ func (f *StrNeqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *StrNeqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if min, max, ok := strRange(min, max); ok {
+		return strings.Compare(min, f.Ref) != 0 || strings.Compare(max, f.Ref) != 0
+	}
+	return false
+}
+
+func (f *StrNeqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if min, max, ok := strRange(min, max); ok {
+		return strings.Compare(f.Ref, min) < 0 || strings.Compare(f.Ref, max) > 0
+	}
+	return false
+}
+
 type FloatNeqFilter struct {
 	Ref float32
 }
@@ -49,6 +91,20 @@ func (f *FloatNeqFilter) Satisfy(val interface{}) bool {
 	return false
 }
 
+func (f *FloatNeqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if min, max, ok := floatRange(min, max); ok {
+		return min != f.Ref || max != f.Ref
+	}
+	return false
+}
+
+func (f *FloatNeqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if min, max, ok := floatRange(min, max); ok {
+		return f.Ref < min || f.Ref > max
+	}
+	return false
+}
+
 type DoubleNeqFilter struct {
 	Ref float64
 }
@@ -59,3 +115,28 @@ func (f *DoubleNeqFilter) Satisfy(val interface{}) bool {
 	}
 	return false
 }
+
+func (f *DoubleNeqFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if min, max, ok := doubleRange(min, max); ok {
+		return min != f.Ref || max != f.Ref
+	}
+	return false
+}
+
+func (f *DoubleNeqFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if min, max, ok := doubleRange(min, max); ok {
+		return f.Ref < min || f.Ref > max
+	}
+	return false
+}
+
+type BoolNeqFilter struct {
+	Ref bool
+}
+
+func (f *BoolNeqFilter) Satisfy(val interface{}) bool {
+	if v, ok := val.(bool); ok {
+		return f.Ref != v
+	}
+	return false
+}
@@ -5,9 +5,21 @@ import "tsfile/timeseries/filter"
 // NotFilter returns true iff the value DOES NOT satisfy the inner filter.
 // NOTICE: !(3.0 < "dd") will return true because (3.0 < "dd") returns false due to type mismatch.
 type NotFilter struct {
-	inner filter.Filter
+	Filter filter.Filter
 }
 
 func (f *NotFilter) Satisfy(val interface{}) bool {
-	return !f.inner.Satisfy(val)
+	return !f.Filter.Satisfy(val)
+}
+
+func (f *NotFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	return !filter.SatisfyAll(f.Filter, min, max)
+}
+
+func (f *NotFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	return !filter.SatisfyAny(f.Filter, min, max)
+}
+
+func (f *NotFilter) Paths() []string {
+	return filter.Paths(f.Filter)
 }
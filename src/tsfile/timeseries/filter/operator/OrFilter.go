@@ -4,18 +4,74 @@ import "tsfile/timeseries/filter"
 
 // OrFilter returns true iff the value satisfies at least one of its children or it has no children.
 type OrFilter struct {
-	filters []filter.Filter
+	Filters []filter.Filter
 }
 
 func (f *OrFilter) Satisfy(val interface{}) bool {
-	if f.filters == nil {
+	if f.Filters == nil {
 		return true
 	}
 
-	for _, filt := range f.filters {
+	for _, filt := range f.Filters {
 		if filt.Satisfy(val) {
 			return true
 		}
 	}
 	return false
 }
+
+func (f *OrFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	if f.Filters == nil {
+		return true
+	}
+
+	for _, filt := range f.Filters {
+		if filter.SatisfyAny(filt, min, max) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *OrFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	if f.Filters == nil {
+		return true
+	}
+
+	for _, filt := range f.Filters {
+		if filter.SatisfyAll(filt, min, max) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *OrFilter) MayMatch(path string, minTime int64, maxTime int64, minValue interface{}, maxValue interface{}) bool {
+	if f.Filters == nil {
+		return true
+	}
+
+	for _, filt := range f.Filters {
+		if filter.MayMatch(filt, path, minTime, maxTime, minValue, maxValue) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *OrFilter) Paths() []string {
+	return filter.Paths(f.Filters...)
+}
+
+func (f *OrFilter) SatisfyMissing() bool {
+	if f.Filters == nil {
+		return true
+	}
+
+	for _, filt := range f.Filters {
+		if filter.SatisfyMissing(filt) {
+			return true
+		}
+	}
+	return false
+}
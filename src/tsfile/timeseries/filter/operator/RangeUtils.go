@@ -0,0 +1,48 @@
+package operator
+
+// longRange, intRange, floatRange, doubleRange and strRange assert both bounds of a range to their type, ok is false
+// on type mismatch.
+func longRange(min interface{}, max interface{}) (int64, int64, bool) {
+	lMin, ok := min.(int64)
+	if !ok {
+		return 0, 0, false
+	}
+	lMax, ok := max.(int64)
+	return lMin, lMax, ok
+}
+
+func intRange(min interface{}, max interface{}) (int32, int32, bool) {
+	vMin, ok := min.(int32)
+	if !ok {
+		return 0, 0, false
+	}
+	vMax, ok := max.(int32)
+	return vMin, vMax, ok
+}
+
+func floatRange(min interface{}, max interface{}) (float32, float32, bool) {
+	vMin, ok := min.(float32)
+	if !ok {
+		return 0, 0, false
+	}
+	vMax, ok := max.(float32)
+	return vMin, vMax, ok
+}
+
+func doubleRange(min interface{}, max interface{}) (float64, float64, bool) {
+	vMin, ok := min.(float64)
+	if !ok {
+		return 0, 0, false
+	}
+	vMax, ok := max.(float64)
+	return vMin, vMax, ok
+}
+
+func strRange(min interface{}, max interface{}) (string, string, bool) {
+	vMin, ok := min.(string)
+	if !ok {
+		return "", "", false
+	}
+	vMax, ok := max.(string)
+	return vMin, vMax, ok
+}
@@ -0,0 +1,30 @@
+package query
+
+import "tsfile/common/constant"
+
+// Fill describes how the missing (nil) values of a column in query results are filled.
+// PREVIOUS_FILL uses the last value before the missing one, LINEAR_FILL interpolates between the values before and
+// after it (numeric types only) and CONSTANT_FILL uses Value. For PREVIOUS_FILL and LINEAR_FILL, LookBack limits how
+// far (in time) the neighbours may be from the missing value, 0 means no limit. Values that cannot be filled stay nil.
+type Fill struct {
+	Type     constant.FillType
+	Value    interface{}
+	LookBack int64
+}
+
+func NewPreviousFill(lookBack int64) *Fill {
+	return &Fill{Type: constant.PREVIOUS_FILL, LookBack: lookBack}
+}
+
+func NewLinearFill(lookBack int64) *Fill {
+	return &Fill{Type: constant.LINEAR_FILL, LookBack: lookBack}
+}
+
+func NewConstantFill(value interface{}) *Fill {
+	return &Fill{Type: constant.CONSTANT_FILL, Value: value}
+}
+
+// Within reports whether a neighbour at the given distance (in time) from a missing value may be used to fill it.
+func (f *Fill) Within(distance int64) bool {
+	return f.LookBack <= 0 || distance <= f.LookBack
+}
@@ -0,0 +1,40 @@
+package query
+
+// GroupBy splits [StartTime, EndTime) into the windows [StartTime + i*SlidingStep, StartTime + i*SlidingStep + Interval),
+// the last one cut at EndTime. A SlidingStep of 0 means Interval, i.e. windows that neither overlap nor leave gaps.
+type GroupBy struct {
+	StartTime   int64
+	EndTime     int64
+	Interval    int64
+	SlidingStep int64
+}
+
+func NewGroupBy(startTime int64, endTime int64, interval int64) *GroupBy {
+	return &GroupBy{StartTime: startTime, EndTime: endTime, Interval: interval, SlidingStep: interval}
+}
+
+// Step returns the distance between the starts of two adjacent windows.
+func (g *GroupBy) Step() int64 {
+	if g.SlidingStep <= 0 {
+		return g.Interval
+	}
+	return g.SlidingStep
+}
+
+// WindowCount returns the number of windows, 0 if the group by is not valid.
+func (g *GroupBy) WindowCount() int {
+	if g.Interval <= 0 || g.EndTime <= g.StartTime {
+		return 0
+	}
+	return int((g.EndTime-g.StartTime-1)/g.Step()) + 1
+}
+
+// Window returns the range [start, end) of the i-th window.
+func (g *GroupBy) Window(i int) (start int64, end int64) {
+	start = g.StartTime + int64(i)*g.Step()
+	end = start + g.Interval
+	if end > g.EndTime {
+		end = g.EndTime
+	}
+	return start, end
+}
@@ -1,11 +1,40 @@
 package query
 
-import "tsfile/timeseries/filter"
+import (
+	"context"
+	"tsfile/common/constant"
+	"tsfile/common/memory"
+	"tsfile/timeseries/filter"
+	"tsfile/timeseries/query/expression"
+)
 
 type QueryExpression struct {
-	selectPaths    []string
+	selectPaths []string
+	// expressions computed over the select paths, one result column each instead of one per select path
+	expressions    []expression.Expression
 	conditionPaths []string
 	filter         filter.Filter
+	// aggregations are applied to every select path, making this an aggregation query over the points whose
+	// timestamps satisfy the filter, which may not read the values of series
+	aggregations []constant.AggregationType
+	// functions are the names of user-defined aggregate functions applied to every select path after the aggregations
+	functions []string
+	groupBy   *GroupBy
+	// fills of the result columns, keyed by the column names in RowRecord.Paths()
+	fills map[string]*Fill
+	// alignByDevice lays the results out as (time, device, sensor1, sensor2, ...), one device after another
+	alignByDevice bool
+	// rows and select paths (after expanding path patterns) to skip and to return at most, 0 means no limit
+	rowOffset    int64
+	rowLimit     int64
+	seriesOffset int
+	seriesLimit  int
+	// descending returns the rows (or group by windows) from the latest to the earliest, ORDER BY time DESC
+	descending bool
+	// ctx stops the readers of the query once done, see Engine.QueryContext
+	ctx context.Context
+	// budget accounts the memory held by the query, see Engine.SetMemoryBudget
+	budget *memory.Budget
 }
 
 func (q *QueryExpression) ConditionPaths() []string {
@@ -31,3 +60,130 @@ func (q *QueryExpression) Filter() filter.Filter {
 func (q *QueryExpression) SelectPaths() []string {
 	return q.selectPaths
 }
+
+func (q *QueryExpression) SelectExpressions() []expression.Expression {
+	return q.expressions
+}
+
+// SetSelectExpressions makes a raw data query return a column per expression, named by its String, instead of a column
+// per select path. The select paths are replaced by the series the expressions read, and the fills are keyed by these
+// series.
+func (q *QueryExpression) SetSelectExpressions(expressions []expression.Expression) {
+	q.expressions = expressions
+	q.selectPaths = expression.PathsOf(expressions)
+}
+
+func (q *QueryExpression) Aggregations() []constant.AggregationType {
+	return q.aggregations
+}
+
+func (q *QueryExpression) SetAggregations(aggregations []constant.AggregationType) {
+	q.aggregations = aggregations
+}
+
+func (q *QueryExpression) AggregateFunctions() []string {
+	return q.functions
+}
+
+// SetAggregateFunctions applies the user-defined aggregate functions registered under the given names to every select
+// path, making this an aggregation query as SetAggregations does. Their columns come after those of the aggregations,
+// see aggregation.FunctionColumnName.
+func (q *QueryExpression) SetAggregateFunctions(names []string) {
+	q.functions = names
+}
+
+// IsAggregation tells whether the query has aggregations or aggregate functions.
+func (q *QueryExpression) IsAggregation() bool {
+	return len(q.aggregations) > 0 || len(q.functions) > 0
+}
+
+func (q *QueryExpression) GroupBy() *GroupBy {
+	return q.groupBy
+}
+
+// SetGroupBy makes an aggregation query return one row per window instead of a single row.
+func (q *QueryExpression) SetGroupBy(groupBy *GroupBy) {
+	q.groupBy = groupBy
+}
+
+func (q *QueryExpression) Fills() map[string]*Fill {
+	return q.fills
+}
+
+// SetFill fills the missing values of the given result column, which is a select path or, for aggregation queries, a
+// column named as in aggregation.ColumnName.
+func (q *QueryExpression) SetFill(column string, fill *Fill) {
+	if q.fills == nil {
+		q.fills = make(map[string]*Fill)
+	}
+	q.fills[column] = fill
+}
+
+func (q *QueryExpression) AlignByDevice() bool {
+	return q.alignByDevice
+}
+
+// SetAlignByDevice makes the query return a column per sensor (or per aggregation of a sensor) instead of per path,
+// with the rows of each device following those of the previous one. The fills are then keyed by these columns and
+// applied within each device.
+func (q *QueryExpression) SetAlignByDevice(alignByDevice bool) {
+	q.alignByDevice = alignByDevice
+}
+
+func (q *QueryExpression) RowOffset() int64 {
+	return q.rowOffset
+}
+
+func (q *QueryExpression) RowLimit() int64 {
+	return q.rowLimit
+}
+
+// SetRowLimit makes the query skip the first offset rows and return at most limit rows after them, 0 meaning no limit.
+func (q *QueryExpression) SetRowLimit(limit int64, offset int64) {
+	q.rowLimit = limit
+	q.rowOffset = offset
+}
+
+func (q *QueryExpression) SeriesOffset() int {
+	return q.seriesOffset
+}
+
+func (q *QueryExpression) SeriesLimit() int {
+	return q.seriesLimit
+}
+
+// SetSeriesLimit makes the query skip the first offset select paths and keep at most limit paths after them, 0 meaning
+// no limit. Path patterns are expanded before.
+func (q *QueryExpression) SetSeriesLimit(limit int, offset int) {
+	q.seriesLimit = limit
+	q.seriesOffset = offset
+}
+
+func (q *QueryExpression) Descending() bool {
+	return q.descending
+}
+
+func (q *QueryExpression) SetDescending(descending bool) {
+	q.descending = descending
+}
+
+// Context returns the context the query runs in, context.Background() if none was set.
+func (q *QueryExpression) Context() context.Context {
+	if q.ctx == nil {
+		return context.Background()
+	}
+	return q.ctx
+}
+
+func (q *QueryExpression) SetContext(ctx context.Context) {
+	q.ctx = ctx
+}
+
+// Budget returns the memory budget the query is accounted to, nil if none.
+func (q *QueryExpression) Budget() *memory.Budget {
+	return q.budget
+}
+
+func (q *QueryExpression) SetBudget(budget *memory.Budget) {
+	q.budget = budget
+}
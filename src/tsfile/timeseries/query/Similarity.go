@@ -0,0 +1,34 @@
+package query
+
+import (
+	"tsfile/common/constant"
+	"tsfile/timeseries/filter"
+)
+
+// SimilarityQuery searches a numeric series for the K windows of as many consecutive points as Pattern that are the
+// closest to it by Measure. Windows overlapping a closer match are left out, so that the matches are K distinct
+// occurrences rather than one occurrence shifted by a few points.
+// Normalize z-normalizes the pattern and every window, so that they match whatever their offset and amplitude. Band
+// is the number of points DTW may shift a point by. If TimeFilter is not nil, only the windows whose timestamps all
+// satisfy it are searched.
+type SimilarityQuery struct {
+	Path       string
+	Pattern    []float64
+	Measure    constant.DistanceMeasure
+	Normalize  bool
+	Band       int
+	K          int
+	TimeFilter filter.Filter
+}
+
+func NewSimilarityQuery(path string, pattern []float64, measure constant.DistanceMeasure, k int) *SimilarityQuery {
+	return &SimilarityQuery{Path: path, Pattern: pattern, Measure: measure, Normalize: true, Band: len(pattern) / 10,
+		K: k}
+}
+
+// Match is a window of a series found by a similarity search, from StartTime to EndTime inclusive.
+type Match struct {
+	StartTime int64
+	EndTime   int64
+	Distance  float64
+}
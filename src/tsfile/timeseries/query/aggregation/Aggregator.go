@@ -0,0 +1,311 @@
+package aggregation
+
+import (
+	"math"
+	"strings"
+	"tsfile/common/constant"
+	"tsfile/common/sketch"
+	"tsfile/file/metadata"
+)
+
+// AggregatorSize is an estimate of the memory held by an Aggregator, for memory budgets.
+const AggregatorSize = 160
+
+// Aggregator accumulates the points of one series, either one by one or a whole chunk or page at a time from its
+// statistics, and gives the result of any AggregationType over everything it has seen. The approximate aggregations
+// (the percentiles and APPROX_COUNT_DISTINCT) are computed from sketches of the points, and VARIANCE and STDDEV from
+// their sum of squares, which only the extended statistics of chunks can update: see CanUseStatistics.
+type Aggregator struct {
+	dataType constant.TSDataType
+	count    int64
+	sum      float64
+	min      interface{}
+	max      interface{}
+	first    interface{}
+	last     interface{}
+	minTime  int64
+	maxTime  int64
+
+	quantiles    *sketch.Quantile
+	distinct     *sketch.HyperLogLog
+	variance     bool
+	sumOfSquares float64
+}
+
+// NewAggregator returns an aggregator without sketches, whose approximate aggregations, VARIANCE and STDDEV are nil.
+func NewAggregator(dataType constant.TSDataType) *Aggregator {
+	return &Aggregator{dataType: dataType, minTime: constant.INVALID_TIMESTAMP, maxTime: constant.INVALID_TIMESTAMP}
+}
+
+// NewAggregatorFor returns an aggregator keeping the sketches and sums needed by the given aggregations.
+func NewAggregatorFor(dataType constant.TSDataType, aggregations []constant.AggregationType) *Aggregator {
+	a := NewAggregator(dataType)
+	for _, aggregation := range aggregations {
+		switch aggregation {
+		case constant.P50, constant.P95, constant.P99:
+			if a.quantiles == nil && isNumeric(dataType) {
+				a.quantiles = sketch.NewQuantile()
+			}
+		case constant.APPROX_COUNT_DISTINCT:
+			if a.distinct == nil {
+				a.distinct = new(sketch.HyperLogLog)
+			}
+		case constant.VARIANCE, constant.STDDEV:
+			a.variance = isNumeric(dataType)
+		}
+	}
+	return a
+}
+
+// Size is an estimate of the memory held by an aggregator for the given aggregations, for memory budgets.
+func Size(aggregations []constant.AggregationType) int64 {
+	size := int64(AggregatorSize)
+	quantiles, distinct := false, false
+	for _, aggregation := range aggregations {
+		switch aggregation {
+		case constant.P50, constant.P95, constant.P99:
+			quantiles = true
+		case constant.APPROX_COUNT_DISTINCT:
+			distinct = true
+		}
+	}
+	if quantiles {
+		size += sketch.QuantileSize
+	}
+	if distinct {
+		size += sketch.HyperLogLogSize
+	}
+	return size
+}
+
+// CanUseStatistics tells whether the aggregator can be updated from statistics rather than points, given whether they
+// include extended statistics. The distinct count always needs the points.
+func (a *Aggregator) CanUseStatistics(extended bool) bool {
+	if a.distinct != nil {
+		return false
+	}
+	return extended || (a.quantiles == nil && !a.variance)
+}
+
+func (a *Aggregator) DataType() constant.TSDataType {
+	return a.dataType
+}
+
+func (a *Aggregator) Count() int64 {
+	return a.count
+}
+
+// Update adds a single point.
+func (a *Aggregator) Update(timestamp int64, value interface{}) {
+	a.UpdateFromStatistics(1, timestamp, timestamp, value, value, value, value, toDouble(value))
+	if a.quantiles != nil {
+		a.quantiles.Add(toDouble(value))
+	}
+	if a.distinct != nil {
+		a.distinct.Add(value)
+	}
+	if a.variance {
+		a.sumOfSquares += toDouble(value) * toDouble(value)
+	}
+}
+
+// Merge adds the points seen by other, an aggregator of a series of the same data type, e.g. to combine the results
+// of files queried separately. The sketches and sums kept by both are merged, the others are dropped.
+func (a *Aggregator) Merge(other *Aggregator) {
+	a.UpdateFromStatistics(other.count, other.minTime, other.maxTime, other.min, other.max, other.first, other.last,
+		other.sum)
+	if a.quantiles != nil && other.quantiles != nil {
+		a.quantiles.Merge(other.quantiles)
+	} else {
+		a.quantiles = nil
+	}
+	if a.distinct != nil && other.distinct != nil {
+		a.distinct.Merge(other.distinct)
+	} else {
+		a.distinct = nil
+	}
+	a.variance = a.variance && other.variance
+	a.sumOfSquares += other.sumOfSquares
+}
+
+// UpdateFromExtendedStatistics adds the extended statistics of points already given to UpdateFromStatistics.
+func (a *Aggregator) UpdateFromExtendedStatistics(extended *metadata.ExtendedStatistics) {
+	if a.quantiles != nil {
+		a.quantiles.Merge(extended.Quantiles)
+	}
+	if a.variance {
+		a.sumOfSquares += extended.SumOfSquares
+	}
+}
+
+// UpdateFromStatistics adds count points within [minTime, maxTime] given only their statistics, first and last being
+// the values at minTime and maxTime.
+func (a *Aggregator) UpdateFromStatistics(count int64, minTime int64, maxTime int64, min interface{}, max interface{},
+	first interface{}, last interface{}, sum float64) {
+	if count <= 0 {
+		return
+	}
+	if a.count == 0 {
+		a.min, a.max, a.first, a.last = min, max, first, last
+		a.minTime, a.maxTime = minTime, maxTime
+	} else {
+		if compare(min, a.min) < 0 {
+			a.min = min
+		}
+		if compare(max, a.max) > 0 {
+			a.max = max
+		}
+		if minTime < a.minTime {
+			a.minTime = minTime
+			a.first = first
+		}
+		if maxTime > a.maxTime {
+			a.maxTime = maxTime
+			a.last = last
+		}
+	}
+	a.count += count
+	a.sum += sum
+}
+
+// Result returns the value of the given aggregation: int64 for COUNT, APPROX_COUNT_DISTINCT, MIN_TIME and MAX_TIME,
+// float64 for SUM, AVG, VARIANCE, STDDEV and the percentiles and the Go type of the series for the others. It is nil if no point has
+// been seen (except for the counts), if the aggregation does not apply to the data type, e.g. SUM of a TEXT series, or
+// if the aggregator does not keep the sketch or sum it needs.
+func (a *Aggregator) Result(aggregation constant.AggregationType) interface{} {
+	if aggregation == constant.COUNT {
+		return a.count
+	}
+	if aggregation == constant.APPROX_COUNT_DISTINCT {
+		if a.distinct == nil {
+			return nil
+		}
+		return a.distinct.Estimate()
+	}
+	if a.count == 0 {
+		return nil
+	}
+	switch aggregation {
+	case constant.SUM:
+		if isNumeric(a.dataType) {
+			return a.sum
+		}
+	case constant.AVG:
+		if isNumeric(a.dataType) {
+			return a.sum / float64(a.count)
+		}
+	case constant.MIN_VALUE:
+		return a.min
+	case constant.MAX_VALUE:
+		return a.max
+	case constant.FIRST_VALUE:
+		return a.first
+	case constant.LAST_VALUE:
+		return a.last
+	case constant.MIN_TIME:
+		return a.minTime
+	case constant.MAX_TIME:
+		return a.maxTime
+	case constant.P50:
+		return a.quantile(0.5)
+	case constant.P95:
+		return a.quantile(0.95)
+	case constant.P99:
+		return a.quantile(0.99)
+	case constant.VARIANCE:
+		if a.variance {
+			return a.populationVariance()
+		}
+	case constant.STDDEV:
+		if a.variance {
+			return math.Sqrt(a.populationVariance())
+		}
+	}
+	return nil
+}
+
+func (a *Aggregator) populationVariance() float64 {
+	mean := a.sum / float64(a.count)
+	// rounding may make it slightly negative for constant series
+	return math.Max(a.sumOfSquares/float64(a.count)-mean*mean, 0)
+}
+
+func (a *Aggregator) quantile(q float64) interface{} {
+	if a.quantiles == nil {
+		return nil
+	}
+	return a.quantiles.Quantile(q)
+}
+
+func isNumeric(dataType constant.TSDataType) bool {
+	switch dataType {
+	case constant.INT32, constant.INT64, constant.FLOAT, constant.DOUBLE:
+		return true
+	}
+	return false
+}
+
+func toDouble(value interface{}) float64 {
+	switch v := value.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// compare returns a negative number, 0 or a positive number if a is less than, equal to or greater than b, which are
+// of the same type. false is less than true and strings are compared lexicographically.
+func compare(a interface{}, b interface{}) int {
+	switch va := a.(type) {
+	case int32:
+		return compareDouble(float64(va), float64(b.(int32)))
+	case int64:
+		vb := b.(int64)
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+		return 0
+	case float32:
+		return compareDouble(float64(va), float64(b.(float32)))
+	case float64:
+		return compareDouble(va, b.(float64))
+	case string:
+		return strings.Compare(va, b.(string))
+	case bool:
+		vb := b.(bool)
+		if va == vb {
+			return 0
+		} else if vb {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func compareDouble(a float64, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// ColumnName names the column of an aggregation of a path in query results, e.g. "avg(root.d0.s0)".
+func ColumnName(aggregation constant.AggregationType, path string) string {
+	return strings.ToLower(aggregation.String()) + "(" + path + ")"
+}
+
+// FunctionColumnName names the column of a user-defined aggregate function of a path, e.g. "energy(root.d0.s0)".
+func FunctionColumnName(name string, path string) string {
+	return strings.ToLower(name) + "(" + path + ")"
+}
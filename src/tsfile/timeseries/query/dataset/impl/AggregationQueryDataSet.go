@@ -0,0 +1,76 @@
+package impl
+
+import (
+	"errors"
+	"tsfile/common/constant"
+	"tsfile/timeseries/query/aggregation"
+	"tsfile/timeseries/read/datatype"
+)
+
+// AggregationQueryDataSet returns the results of an aggregation query, one row per group by window (or a single row
+// without group by) whose timestamp is the start of the window. There is a column for every aggregation of every
+// select path, named as in aggregation.ColumnName, followed by a column for every user-defined aggregate function,
+// named as in aggregation.FunctionColumnName.
+type AggregationQueryDataSet struct {
+	aggregations []constant.AggregationType
+	// aggregators[i][j] holds the points of the i-th select path in the j-th window
+	aggregators [][]*aggregation.Aggregator
+	// functionResults[i][j][k] is the result of the k-th function of the i-th select path in the j-th window
+	functionResults [][][]interface{}
+	timestamps      []int64
+
+	row   *datatype.RowRecord
+	index int
+}
+
+func NewAggregationQueryDataSet(selectPaths []string, aggregations []constant.AggregationType, timestamps []int64,
+	aggregators [][]*aggregation.Aggregator) *AggregationQueryDataSet {
+	return NewFunctionAggregationQueryDataSet(selectPaths, aggregations, nil, timestamps, aggregators, nil)
+}
+
+// NewFunctionAggregationQueryDataSet returns the results of the user-defined aggregate functions as well, computed
+// beforehand into functionResults.
+func NewFunctionAggregationQueryDataSet(selectPaths []string, aggregations []constant.AggregationType,
+	functions []string, timestamps []int64, aggregators [][]*aggregation.Aggregator,
+	functionResults [][][]interface{}) *AggregationQueryDataSet {
+	columns := make([]string, 0, len(selectPaths)*(len(aggregations)+len(functions)))
+	for _, path := range selectPaths {
+		for _, aggr := range aggregations {
+			columns = append(columns, aggregation.ColumnName(aggr, path))
+		}
+		for _, name := range functions {
+			columns = append(columns, aggregation.FunctionColumnName(name, path))
+		}
+	}
+	return &AggregationQueryDataSet{aggregations: aggregations, aggregators: aggregators,
+		functionResults: functionResults, timestamps: timestamps, row: datatype.NewRowRecordWithPaths(columns)}
+}
+
+func (set *AggregationQueryDataSet) HasNext() bool {
+	return set.index < len(set.timestamps)
+}
+
+func (set *AggregationQueryDataSet) Next() (*datatype.RowRecord, error) {
+	if !set.HasNext() {
+		return nil, errors.New("Dataset exhausted!")
+	}
+	column := 0
+	for i, windows := range set.aggregators {
+		for _, aggr := range set.aggregations {
+			set.row.Values()[column] = windows[set.index].Result(aggr)
+			column++
+		}
+		if set.functionResults != nil {
+			column += copy(set.row.Values()[column:], set.functionResults[i][set.index])
+		}
+	}
+	set.row.SetTimestamp(set.timestamps[set.index])
+	set.index++
+	return set.row, nil
+}
+
+func (set *AggregationQueryDataSet) Close() {
+	set.aggregators = nil
+	set.functionResults = nil
+	set.index = len(set.timestamps)
+}
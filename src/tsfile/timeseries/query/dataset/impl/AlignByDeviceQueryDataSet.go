@@ -0,0 +1,77 @@
+package impl
+
+import (
+	"errors"
+	"tsfile/timeseries/query/dataset"
+	"tsfile/timeseries/read/datatype"
+)
+
+// DEVICE_COLUMN is the name of the first column of the rows aligned by device, which holds the device id.
+const DEVICE_COLUMN = "Device"
+
+// DeviceDataSetFactory creates the data set of one device, mapping every column of its rows to the index of a column
+// of the aligned rows (not counting the device column).
+type DeviceDataSetFactory func(deviceId string) (dataSet dataset.IQueryDataSet, columnMapping []int)
+
+// AlignByDeviceQueryDataSet returns the rows of each device one device after another, the columns of all devices being
+// the same. The data set of a device is only created once the rows of the previous device have been read.
+type AlignByDeviceQueryDataSet struct {
+	devices    []string
+	newDataSet DeviceDataSetFactory
+
+	deviceIndex int
+	current     dataset.IQueryDataSet
+	mapping     []int
+
+	row *datatype.RowRecord
+}
+
+func NewAlignByDeviceQueryDataSet(devices []string, columns []string, newDataSet DeviceDataSetFactory) *AlignByDeviceQueryDataSet {
+	paths := append([]string{DEVICE_COLUMN}, columns...)
+	return &AlignByDeviceQueryDataSet{devices: devices, newDataSet: newDataSet, row: datatype.NewRowRecordWithPaths(paths)}
+}
+
+func (set *AlignByDeviceQueryDataSet) HasNext() bool {
+	for {
+		if set.current != nil {
+			if set.current.HasNext() {
+				return true
+			}
+			set.current.Close()
+			set.current = nil
+		}
+		if set.deviceIndex >= len(set.devices) {
+			return false
+		}
+		set.current, set.mapping = set.newDataSet(set.devices[set.deviceIndex])
+		set.deviceIndex++
+	}
+}
+
+func (set *AlignByDeviceQueryDataSet) Next() (*datatype.RowRecord, error) {
+	if !set.HasNext() {
+		return nil, errors.New("Dataset exhausted!")
+	}
+	record, err := set.current.Next()
+	if err != nil {
+		return nil, err
+	}
+	values := set.row.Values()
+	values[0] = set.devices[set.deviceIndex-1]
+	for i := 1; i < len(values); i++ {
+		values[i] = nil
+	}
+	for i, value := range record.Values() {
+		values[set.mapping[i]+1] = value
+	}
+	set.row.SetTimestamp(record.Timestamp())
+	return set.row, nil
+}
+
+func (set *AlignByDeviceQueryDataSet) Close() {
+	if set.current != nil {
+		set.current.Close()
+		set.current = nil
+	}
+	set.deviceIndex = len(set.devices)
+}
@@ -0,0 +1,59 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"tsfile/timeseries/query/dataset"
+	"tsfile/timeseries/read/datatype"
+)
+
+// ContextQueryDataSet ends another data set once its context is done. The readers behind the inner data set are
+// expected to stop on the same context, so that a data set left without rows by the cancellation is told apart from
+// an exhausted one: HasNext then reports one more row, for which Next closes the inner data set and returns the cause
+// of the cancellation (ctx.Err() unless the context was cancelled with a cause).
+type ContextQueryDataSet struct {
+	inner dataset.IQueryDataSet
+	ctx   context.Context
+	// closed is set once the inner data set is closed, either by Close or on cancellation
+	closed bool
+}
+
+func NewContextQueryDataSet(ctx context.Context, inner dataset.IQueryDataSet) *ContextQueryDataSet {
+	return &ContextQueryDataSet{inner: inner, ctx: ctx}
+}
+
+func (set *ContextQueryDataSet) HasNext() bool {
+	if set.closed {
+		return false
+	}
+	return set.ctx.Err() != nil || set.inner.HasNext() || set.ctx.Err() != nil
+}
+
+func (set *ContextQueryDataSet) Next() (*datatype.RowRecord, error) {
+	if set.closed {
+		return nil, errors.New("Dataset exhausted!")
+	}
+	if set.ctx.Err() != nil {
+		set.Close()
+		return nil, context.Cause(set.ctx)
+	}
+	row, err := set.inner.Next()
+	// a row read while the context was cancelled may lack the values of the readers that stopped
+	if set.ctx.Err() != nil {
+		set.Close()
+		return nil, context.Cause(set.ctx)
+	}
+	return row, err
+}
+
+func (set *ContextQueryDataSet) Close() {
+	if !set.closed {
+		set.closed = true
+		set.inner.Close()
+	}
+}
+
+// Inner returns the data set whose rows this one passes on.
+func (set *ContextQueryDataSet) Inner() dataset.IQueryDataSet {
+	return set.inner
+}
@@ -0,0 +1,57 @@
+package impl
+
+import (
+	"tsfile/timeseries/query/dataset"
+	"tsfile/timeseries/query/expression"
+	"tsfile/timeseries/read/datatype"
+)
+
+// ExpressionQueryDataSet computes expressions over the rows of another data set, which holds the series they read. There
+// is a column per expression named by its String, and a row per row of the inner data set.
+type ExpressionQueryDataSet struct {
+	inner      dataset.IQueryDataSet
+	evaluators []expression.Evaluator
+
+	row *datatype.RowRecord
+}
+
+// NewExpressionQueryDataSet computes expressions over inner, whose rows hold the values of paths.
+func NewExpressionQueryDataSet(inner dataset.IQueryDataSet, paths []string,
+	expressions []expression.Expression) *ExpressionQueryDataSet {
+	index := make(map[string]int, len(paths))
+	for i, path := range paths {
+		index[path] = i
+	}
+	columns := make([]string, len(expressions))
+	evaluators := make([]expression.Evaluator, len(expressions))
+	for i, e := range expressions {
+		columns[i] = e.String()
+		evaluators[i] = e.NewEvaluator(index)
+	}
+	return &ExpressionQueryDataSet{inner: inner, evaluators: evaluators, row: datatype.NewRowRecordWithPaths(columns)}
+}
+
+func (set *ExpressionQueryDataSet) HasNext() bool {
+	return set.inner.HasNext()
+}
+
+func (set *ExpressionQueryDataSet) Next() (*datatype.RowRecord, error) {
+	row, err := set.inner.Next()
+	if err != nil {
+		return nil, err
+	}
+	for i, evaluate := range set.evaluators {
+		set.row.Values()[i] = evaluate(row.Timestamp(), row.Values())
+	}
+	set.row.SetTimestamp(row.Timestamp())
+	return set.row, nil
+}
+
+func (set *ExpressionQueryDataSet) Close() {
+	set.inner.Close()
+}
+
+// Inner returns the data set whose rows this one passes on.
+func (set *ExpressionQueryDataSet) Inner() dataset.IQueryDataSet {
+	return set.inner
+}
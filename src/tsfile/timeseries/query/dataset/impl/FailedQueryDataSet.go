@@ -0,0 +1,33 @@
+package impl
+
+import (
+	"errors"
+	"tsfile/timeseries/read/datatype"
+)
+
+// FailedQueryDataSet stands for a data set whose construction failed: HasNext reports a single row, for which Next
+// returns the error.
+type FailedQueryDataSet struct {
+	err error
+}
+
+func NewFailedQueryDataSet(err error) *FailedQueryDataSet {
+	return &FailedQueryDataSet{err: err}
+}
+
+func (set *FailedQueryDataSet) HasNext() bool {
+	return set.err != nil
+}
+
+func (set *FailedQueryDataSet) Next() (*datatype.RowRecord, error) {
+	if set.err == nil {
+		return nil, errors.New("Dataset exhausted!")
+	}
+	err := set.err
+	set.err = nil
+	return nil, err
+}
+
+func (set *FailedQueryDataSet) Close() {
+	set.err = nil
+}
@@ -0,0 +1,269 @@
+package impl
+
+import (
+	"errors"
+	"math"
+	"tsfile/common/constant"
+	"tsfile/common/memory"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/dataset"
+	"tsfile/timeseries/read/datatype"
+)
+
+// FillQueryDataSet fills the missing values of the rows of another data set. Rows are read ahead only as far as
+// needed to find the next value of a column with a linear fill, so only the rows within a gap are buffered. If the rows
+// come in descending time order, the previous values are the ones read ahead.
+type FillQueryDataSet struct {
+	inner      dataset.IQueryDataSet
+	fills      map[string]*query.Fill
+	descending bool
+
+	// the fill of every column, resolved from the paths of the first row
+	columnFills []*query.Fill
+	// the last non-missing value of every column returned so far and its timestamp
+	lastValues []interface{}
+	lastTimes  []int64
+
+	// rows read from inner but not returned yet, copied since inner may reuse its row
+	buffer []*datatype.RowRecord
+	// popped is the number of rows returned so far, and ahead for every column the number of rows read so far up to
+	// which findAhead found no value of the column, so that the rows of a gap are looked through once
+	popped int64
+	ahead  []int64
+	// err is the error reading ahead failed with, returned by the next call to Next
+	err    error
+	failed bool
+
+	// budget accounts the buffered rows and the last values, reserved holding the bytes accounted so far
+	budget   *memory.Budget
+	reserved int64
+}
+
+// NewFillQueryDataSet creates a data set filling the rows of inner. budget may be nil, a failed reservation ending the
+// data set and being reported through the context of the budget.
+func NewFillQueryDataSet(inner dataset.IQueryDataSet, fills map[string]*query.Fill, descending bool,
+	budget *memory.Budget) *FillQueryDataSet {
+	return &FillQueryDataSet{inner: inner, fills: fills, descending: descending, budget: budget}
+}
+
+func (set *FillQueryDataSet) HasNext() bool {
+	if set.err != nil {
+		return true
+	}
+	if set.failed {
+		return false
+	}
+	return len(set.buffer) > 0 || set.inner.HasNext()
+}
+
+func (set *FillQueryDataSet) Next() (*datatype.RowRecord, error) {
+	if set.err == nil && len(set.buffer) == 0 {
+		if ok, err := set.readAhead(); err != nil {
+			set.err = err
+		} else if !ok {
+			return nil, errors.New("Dataset exhausted!")
+		}
+	}
+	if set.err != nil {
+		return nil, set.fail()
+	}
+	row := set.buffer[0]
+	if set.columnFills == nil {
+		set.resolveFills(row.Paths())
+	}
+
+	values := row.Values()
+	for i, fill := range set.columnFills {
+		if values[i] != nil {
+			set.lastValues[i] = values[i]
+			set.lastTimes[i] = row.Timestamp()
+			continue
+		}
+		if fill != nil {
+			values[i] = set.fillValue(i, fill, row.Timestamp())
+		}
+	}
+	if set.err != nil {
+		// the row cannot be filled
+		return nil, set.fail()
+	}
+	set.buffer[0] = nil
+	set.buffer = set.buffer[1:]
+	set.popped++
+	set.release(rowSize(row))
+	return row, nil
+}
+
+// fail returns the error reading ahead failed with, ending the data set.
+func (set *FillQueryDataSet) fail() error {
+	err := set.err
+	set.err = nil
+	set.failed = true
+	return err
+}
+
+func (set *FillQueryDataSet) Close() {
+	set.inner.Close()
+	set.buffer = nil
+	set.release(set.reserved)
+}
+
+func (set *FillQueryDataSet) reserve(size int64) error {
+	if err := set.budget.Reserve(size); err != nil {
+		return err
+	}
+	set.reserved += size
+	return nil
+}
+
+func (set *FillQueryDataSet) release(size int64) {
+	set.budget.Release(size)
+	set.reserved -= size
+}
+
+// rowSize is the size of a row as accounted to the budget.
+func rowSize(row *datatype.RowRecord) int64 {
+	return 8 + int64(len(row.Values()))*memory.ValueSize
+}
+
+func (set *FillQueryDataSet) resolveFills(paths []string) {
+	set.columnFills = make([]*query.Fill, len(paths))
+	for i, path := range paths {
+		set.columnFills[i] = set.fills[path]
+	}
+	set.lastValues = make([]interface{}, len(paths))
+	set.lastTimes = make([]int64, len(paths))
+	set.ahead = make([]int64, len(paths))
+	set.reserve(int64(len(paths)) * (memory.ValueSize + 8))
+}
+
+func (set *FillQueryDataSet) fillValue(column int, fill *query.Fill, timestamp int64) interface{} {
+	switch fill.Type {
+	case constant.CONSTANT_FILL:
+		return fill.Value
+	case constant.PREVIOUS_FILL:
+		_, prevValue := set.findPrevious(column, fill, timestamp)
+		return prevValue
+	case constant.LINEAR_FILL:
+		prevTime, prevValue := set.findPrevious(column, fill, timestamp)
+		if prevValue == nil {
+			return nil
+		}
+		nextTime, nextValue := set.findNext(column, fill, timestamp)
+		if nextValue != nil {
+			return interpolate(prevTime, prevValue, nextTime, nextValue, timestamp)
+		}
+	}
+	return nil
+}
+
+// findPrevious and findNext give the closest value of the column before and after the timestamp of the head row,
+// within the look-back of the fill.
+func (set *FillQueryDataSet) findPrevious(column int, fill *query.Fill, timestamp int64) (int64, interface{}) {
+	if set.descending {
+		return set.findAhead(column, fill, timestamp)
+	}
+	return set.findLast(column, fill, timestamp)
+}
+
+func (set *FillQueryDataSet) findNext(column int, fill *query.Fill, timestamp int64) (int64, interface{}) {
+	if set.descending {
+		return set.findLast(column, fill, timestamp)
+	}
+	return set.findAhead(column, fill, timestamp)
+}
+
+func (set *FillQueryDataSet) findLast(column int, fill *query.Fill, timestamp int64) (int64, interface{}) {
+	if set.lastValues[column] == nil || !fill.Within(distance(timestamp, set.lastTimes[column])) {
+		return 0, nil
+	}
+	return set.lastTimes[column], set.lastValues[column]
+}
+
+// findAhead looks for the first value of the column after the head row in the order of the rows, reading ahead while
+// the rows are within the look-back of the fill. It finds none if reading ahead fails, the error being kept for Next.
+func (set *FillQueryDataSet) findAhead(column int, fill *query.Fill, timestamp int64) (int64, interface{}) {
+	i := 1
+	if ahead := int(set.ahead[column] - set.popped); ahead > i {
+		i = ahead
+	}
+	for ; ; i++ {
+		set.ahead[column] = set.popped + int64(i)
+		if i == len(set.buffer) {
+			if set.err != nil {
+				return 0, nil
+			}
+			ok, err := set.readAhead()
+			if err != nil {
+				set.err = err
+			}
+			if !ok {
+				return 0, nil
+			}
+		}
+		row := set.buffer[i]
+		if !fill.Within(distance(row.Timestamp(), timestamp)) {
+			return 0, nil
+		}
+		if value := row.Values()[column]; value != nil {
+			return row.Timestamp(), value
+		}
+	}
+}
+
+func distance(a int64, b int64) int64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// readAhead appends a copy of the next row of inner to the buffer, returning false if there is none or it cannot be
+// read.
+func (set *FillQueryDataSet) readAhead() (bool, error) {
+	if !set.inner.HasNext() {
+		return false, nil
+	}
+	row, err := set.inner.Next()
+	if err != nil {
+		return false, err
+	}
+	if err := set.reserve(rowSize(row)); err != nil {
+		return false, err
+	}
+	copied := datatype.NewRowRecordWithPaths(row.Paths())
+	copy(copied.Values(), row.Values())
+	copied.SetTimestamp(row.Timestamp())
+	set.buffer = append(set.buffer, copied)
+	return true, nil
+}
+
+// interpolate returns the value at t on the line between (t0, v0) and (t1, v1) in the type of v0, integers being
+// rounded. It returns nil for non-numeric or mismatching types.
+func interpolate(t0 int64, v0 interface{}, t1 int64, v1 interface{}, t int64) interface{} {
+	ratio := float64(t-t0) / float64(t1-t0)
+	switch a := v0.(type) {
+	case int32:
+		if b, ok := v1.(int32); ok {
+			return int32(math.Round(float64(a) + (float64(b)-float64(a))*ratio))
+		}
+	case int64:
+		if b, ok := v1.(int64); ok {
+			return int64(math.Round(float64(a) + (float64(b)-float64(a))*ratio))
+		}
+	case float32:
+		if b, ok := v1.(float32); ok {
+			return float32(float64(a) + (float64(b)-float64(a))*ratio)
+		}
+	case float64:
+		if b, ok := v1.(float64); ok {
+			return a + (b-a)*ratio
+		}
+	}
+	return nil
+}
+
+// Inner returns the data set whose rows this one passes on.
+func (set *FillQueryDataSet) Inner() dataset.IQueryDataSet {
+	return set.inner
+}
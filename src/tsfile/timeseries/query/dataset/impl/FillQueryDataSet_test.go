@@ -0,0 +1,104 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"tsfile/common/memory"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/read/datatype"
+)
+
+// rowsDataSet returns rows of the given values at timestamps 1, 2, ..., then fails with err if it is not nil.
+type rowsDataSet struct {
+	paths  []string
+	values [][]interface{}
+	err    error
+	next   int
+}
+
+func (set *rowsDataSet) HasNext() bool {
+	return set.next < len(set.values) || (set.next == len(set.values) && set.err != nil)
+}
+
+func (set *rowsDataSet) Next() (*datatype.RowRecord, error) {
+	set.next++
+	if set.next > len(set.values) {
+		return nil, set.err
+	}
+	row := datatype.NewRowRecordWithPaths(set.paths)
+	copy(row.Values(), set.values[set.next-1])
+	row.SetTimestamp(int64(set.next))
+	return row, nil
+}
+
+func (set *rowsDataSet) Close() {
+}
+
+func TestFillQueryDataSet(t *testing.T) {
+	// a long gap in s0 filled linearly while s1 has a value in every other row
+	var values [][]interface{}
+	for i := 0; i < 1000; i++ {
+		var s0, s1 interface{}
+		if i == 0 || i == 999 {
+			s0 = float64(i)
+		}
+		if i%2 == 0 {
+			s1 = int32(i)
+		}
+		values = append(values, []interface{}{s0, s1})
+	}
+	fills := map[string]*query.Fill{"root.d0.s0": query.NewLinearFill(0), "root.d0.s1": query.NewLinearFill(0)}
+	set := NewFillQueryDataSet(&rowsDataSet{paths: []string{"root.d0.s0", "root.d0.s1"}, values: values}, fills,
+		false, nil)
+	for i := 0; set.HasNext(); i++ {
+		row, err := set.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected interface{} = int32(i)
+		if i == 999 {
+			// no value of s1 after the last row
+			expected = nil
+		}
+		if s0, ok := row.Values()[0].(float64); !ok || math.Abs(s0-float64(i)) > 1e-9 || row.Values()[1] != expected {
+			t.Fatal(fmt.Sprintf("Expected %d, %v at %d got %v", i, expected, row.Timestamp(), row.Values()))
+		}
+	}
+
+	// a row that cannot be read ahead fails the data set instead of leaving the gap unfilled
+	failure := errors.New("cannot read the next row")
+	set = NewFillQueryDataSet(&rowsDataSet{paths: []string{"root.d0.s0"}, values: [][]interface{}{{1.0}, {nil}},
+		err: failure}, map[string]*query.Fill{"root.d0.s0": query.NewLinearFill(0)}, false, nil)
+	if row, err := set.Next(); err != nil || row.Values()[0] != 1.0 {
+		t.Fatal(fmt.Sprintf("Expected 1 got %v, %v", row, err))
+	}
+	if !set.HasNext() {
+		t.Fatal("Expected the failure to be reported")
+	}
+	if _, err := set.Next(); err != failure {
+		t.Fatal(fmt.Sprintf("Expected the read failure got %v", err))
+	}
+	if set.HasNext() {
+		t.Fatal("Expected no row after the failure")
+	}
+
+	// as does a budget refusing the rows of the gap
+	_, budget := memory.WithBudget(context.Background(), 200)
+	values = [][]interface{}{{1.0}}
+	for i := 0; i < 10; i++ {
+		values = append(values, []interface{}{nil})
+	}
+	set = NewFillQueryDataSet(&rowsDataSet{paths: []string{"root.d0.s0"}, values: append(values, []interface{}{2.0})},
+		map[string]*query.Fill{"root.d0.s0": query.NewLinearFill(0)}, false, budget)
+	set.Next()
+	var exceeded *memory.BudgetExceededError
+	if _, err := set.Next(); !errors.As(err, &exceeded) {
+		t.Fatal(fmt.Sprintf("Expected the budget to be exceeded got %v", err))
+	}
+	if set.HasNext() {
+		t.Fatal("Expected no row after the failure")
+	}
+}
@@ -0,0 +1,66 @@
+package impl
+
+import (
+	"errors"
+	"tsfile/timeseries/query/dataset"
+	"tsfile/timeseries/read/datatype"
+)
+
+// LimitQueryDataSet skips the first offset rows of another data set and stops after limit rows (no limit if limit is
+// 0), so that the readers behind it are not read further than needed.
+type LimitQueryDataSet struct {
+	inner    dataset.IQueryDataSet
+	offset   int64
+	limit    int64
+	returned int64
+	// err is the error skipping the offset rows failed with, returned by the next call to Next
+	err    error
+	failed bool
+}
+
+func NewLimitQueryDataSet(inner dataset.IQueryDataSet, limit int64, offset int64) *LimitQueryDataSet {
+	return &LimitQueryDataSet{inner: inner, limit: limit, offset: offset}
+}
+
+func (set *LimitQueryDataSet) HasNext() bool {
+	if set.err != nil {
+		return true
+	}
+	if set.failed {
+		return false
+	}
+	for set.offset > 0 && set.inner.HasNext() {
+		if _, err := set.inner.Next(); err != nil {
+			set.err = err
+			return true
+		}
+		set.offset--
+	}
+	if set.limit > 0 && set.returned >= set.limit {
+		return false
+	}
+	return set.inner.HasNext()
+}
+
+func (set *LimitQueryDataSet) Next() (*datatype.RowRecord, error) {
+	if !set.HasNext() {
+		return nil, errors.New("Dataset exhausted!")
+	}
+	if set.err != nil {
+		err := set.err
+		set.err = nil
+		set.failed = true
+		return nil, err
+	}
+	set.returned++
+	return set.inner.Next()
+}
+
+func (set *LimitQueryDataSet) Close() {
+	set.inner.Close()
+}
+
+// Inner returns the data set whose rows this one passes on.
+func (set *LimitQueryDataSet) Inner() dataset.IQueryDataSet {
+	return set.inner
+}
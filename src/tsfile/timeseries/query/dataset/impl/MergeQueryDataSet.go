@@ -19,9 +19,9 @@ type MergeQueryDataSet struct {
 }
 
 func NewMergeQueryDataSet(selectPaths []string, conditionPaths []string, readerMap map[string]reader.TimeValuePairReader,
-	filter filter.Filter) *MergeQueryDataSet {
+	filter filter.Filter, descending bool) *MergeQueryDataSet {
 	allPaths := utils.MergeStrings(selectPaths, conditionPaths)
-	rowReader := basic.NewFilteredRowReader(allPaths, readerMap, filter)
+	rowReader := basic.NewFilteredRowReader(allPaths, readerMap, filter, descending)
 	dataSet := &MergeQueryDataSet{reader: rowReader}
 	dataSet.row = datatype.NewRowRecordWithPaths(selectPaths)
 	dataSet.selectPaths = selectPaths
@@ -2,6 +2,7 @@ package impl
 
 import (
 	"tsfile/common/constant"
+	"tsfile/common/memory"
 	"tsfile/timeseries/filter"
 	"tsfile/timeseries/query/timegen"
 	"tsfile/timeseries/query/timegen/impl"
@@ -10,7 +11,6 @@ import (
 	"tsfile/timeseries/read/reader/impl/basic"
 	"tsfile/timeseries/read/reader/impl/seek"
 	"errors"
-	"tsfile/common/log"
 )
 
 type TimestampQueryDataSet struct {
@@ -21,52 +21,44 @@ type TimestampQueryDataSet struct {
 	currTime int64
 	current  *datatype.RowRecord
 	exhausted bool
+	// err is the error reading the rows failed with, returned by the next call to Next
+	err error
 }
 
 func NewTimestampQueryDataSet(selectPaths []string, conditionPaths []string,
-	selectReaderMap map[string]reader.ISeekableTimeValuePairReader, conditionReaderMap map[string]reader.TimeValuePairReader, filter filter.Filter) *TimestampQueryDataSet {
+	selectReaderMap map[string]reader.ISeekableTimeValuePairReader, conditionReaderMap map[string]reader.TimeValuePairReader, filter filter.Filter,
+	descending bool, budget *memory.Budget) *TimestampQueryDataSet {
 	tGen := impl.NewRowRecordTimestampGenerator(conditionPaths, conditionReaderMap, filter)
-	rGen := basic.NewFilteredRowReader(conditionPaths, conditionReaderMap, filter)
-	r := seek.NewSeekableRowReader(selectPaths, selectReaderMap)
+	rGen := basic.NewFilteredRowReader(conditionPaths, conditionReaderMap, filter, descending)
+	r := seek.NewSeekableRowReader(selectPaths, selectReaderMap, budget)
 	return &TimestampQueryDataSet{tGen: tGen, rGen: rGen, r: r, currTime: constant.INVALID_TIMESTAMP, exhausted:false}
 }
 
 func (set *TimestampQueryDataSet) fetch() {
-	//if set.tGen.HasNext() {
-	//	currTime, err := set.tGen.Next()
-	//	if err != nil {
-	//		log.Error("cannot generate next timestamp", err)
-	//		set.exhausted = true
-	//		return
-	//	}
-	//	if set.r.Seek(currTime) {
-	//		set.current = set.r.Current()
-	//	}
-	//}
-	if set.rGen.HasNext() {
+	// skip the timestamps on which none of the select paths has a value
+	for set.rGen.HasNext() {
 		currRecord, err := set.rGen.Next()
 		if err != nil {
-			log.Error("cannot generate next timestamp", err)
-			set.exhausted = true
+			set.err = err
 			return
 		}
 		if set.r.Seek(currRecord.Timestamp()) {
 			set.current = set.r.Current()
+			return
 		}
-	} else {
-		set.exhausted = true
 	}
+	set.exhausted = true
 }
 
 func (set *TimestampQueryDataSet) HasNext() bool {
 	if set.exhausted {
 		return false
 	}
-	if set.current != nil {
+	if set.current != nil || set.err != nil {
 		return true
 	}
 	set.fetch()
-	if  set.current != nil {
+	if set.current != nil || set.err != nil {
 		return true
 	} else {
 		set.exhausted = true
@@ -78,16 +70,20 @@ func (set *TimestampQueryDataSet) Next() (*datatype.RowRecord, error) {
 	if set.exhausted {
 		return nil, errors.New("Dataset exhausted!");
 	}
-	if set.current == nil {
+	if set.current == nil && set.err == nil {
 		set.fetch()
 	}
+	if set.err != nil {
+		set.exhausted = true
+		return nil, set.err
+	}
 	ret := set.current
 	if ret == nil {
 		set.exhausted = true
 		return nil, errors.New("Dataset exhausted!");
 	}
+	// the next row is fetched lazily because the seekable reader reuses the returned RowRecord
 	set.current = nil
-	set.fetch()
 	return ret, nil
 }
 
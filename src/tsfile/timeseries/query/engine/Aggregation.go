@@ -0,0 +1,247 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"tsfile/common/constant"
+	"tsfile/file/metadata"
+	"tsfile/timeseries/filter"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/aggregation"
+	"tsfile/timeseries/read/datatype"
+	"tsfile/timeseries/read/reader/impl/basic"
+)
+
+// Aggregate computes the given aggregations over the points of the series at path whose timestamps satisfy timeFilter,
+// or over all of its points if timeFilter is nil. The results are in the order of aggregations, see
+// aggregation.Aggregator.Result for their types.
+// Chunks and pages entirely within the time range are aggregated from their statistics, only the pages on its
+// boundaries are decoded. To be pruned this way, timeFilter must implement filter.RangeFilter, otherwise every page is
+// decoded and checked point by point. The percentiles, VARIANCE and STDDEV can only be taken from the statistics of
+// chunks written with extended digests (see conf.ExtendedDigest), APPROX_COUNT_DISTINCT needs every page in the time
+// range decoded.
+// Aggregate fails with the error a chunk or a page of the series cannot be read with.
+func (e *Engine) Aggregate(path string, aggregations []constant.AggregationType,
+	timeFilter filter.Filter) ([]interface{}, error) {
+	aggregator, err := e.aggregate(path, aggregations, timeFilter)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, len(aggregations))
+	for i, aggr := range aggregations {
+		results[i] = aggregator.Result(aggr)
+	}
+	return results, nil
+}
+
+// aggregationTarget receives the chunks, pages and points of a series that fall into the time range it is interested
+// in, see Engine.aggregateSeries.
+type aggregationTarget interface {
+	// accepts tells whether any point within [minTime, maxTime] may be aggregated and whether all of them are, in which
+	// case they are given to updateFromStatistics instead of being decoded, given whether their statistics include
+	// extended ones.
+	accepts(minTime int64, maxTime int64, extended bool) (some bool, all bool)
+	// updateFromStatistics is given extended statistics only if accepts was told they are available, nil otherwise.
+	updateFromStatistics(count int64, minTime int64, maxTime int64, min interface{}, max interface{},
+		first interface{}, last interface{}, sum float64, extended *metadata.ExtendedStatistics)
+	update(timestamp int64, value interface{})
+}
+
+// filterTarget aggregates the points whose timestamps satisfy a filter, or all points if the filter is nil.
+type filterTarget struct {
+	aggregator *aggregation.Aggregator
+	timeFilter filter.Filter
+}
+
+func (t *filterTarget) accepts(minTime int64, maxTime int64, extended bool) (bool, bool) {
+	some, all := acceptsTimes(t.timeFilter, minTime, maxTime)
+	return some, all && t.aggregator.CanUseStatistics(extended)
+}
+
+func (t *filterTarget) updateFromStatistics(count int64, minTime int64, maxTime int64, min interface{},
+	max interface{}, first interface{}, last interface{}, sum float64, extended *metadata.ExtendedStatistics) {
+	t.aggregator.UpdateFromStatistics(count, minTime, maxTime, min, max, first, last, sum)
+	if extended != nil {
+		t.aggregator.UpdateFromExtendedStatistics(extended)
+	}
+}
+
+func (t *filterTarget) update(timestamp int64, value interface{}) {
+	if t.timeFilter == nil || t.timeFilter.Satisfy(timestamp) {
+		t.aggregator.Update(timestamp, value)
+	}
+}
+
+// acceptsTimes tells whether any and whether all of the timestamps within [minTime, maxTime] satisfy timeFilter, all
+// of them if it is nil.
+func acceptsTimes(timeFilter filter.Filter, minTime int64, maxTime int64) (some bool, all bool) {
+	if timeFilter == nil {
+		return true, true
+	}
+	return filter.SatisfyAny(timeFilter, minTime, maxTime), filter.SatisfyAll(timeFilter, minTime, maxTime)
+}
+
+// rowTimeFilter checks timestamps against a filter of RowRecords reading no series, e.g. the WHERE clause of an
+// aggregation query combining RowRecordTimeFilters, so that the targets can take it as their time filter.
+type rowTimeFilter struct {
+	filter.Filter
+	record datatype.RowRecord
+}
+
+func (f *rowTimeFilter) Satisfy(val interface{}) bool {
+	f.record.SetTimestamp(val.(int64))
+	return f.Filter.Satisfy(&f.record)
+}
+
+func (f *rowTimeFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	return filter.SatisfyAny(f.Filter, min, max)
+}
+
+func (f *rowTimeFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	return filter.SatisfyAll(f.Filter, min, max)
+}
+
+// contextTarget stops passing chunks and pages to its target once ctx is done, so that no further page is read.
+type contextTarget struct {
+	aggregationTarget
+	ctx context.Context
+}
+
+func (t *contextTarget) accepts(minTime int64, maxTime int64, extended bool) (bool, bool) {
+	if t.ctx.Err() != nil {
+		return false, false
+	}
+	return t.aggregationTarget.accepts(minTime, maxTime, extended)
+}
+
+// targetWithContext makes target stop once the context of exp is done, if it can be.
+func targetWithContext(target aggregationTarget, exp *query.QueryExpression) aggregationTarget {
+	if exp.Context().Done() == nil {
+		return target
+	}
+	return &contextTarget{aggregationTarget: target, ctx: exp.Context()}
+}
+
+func (e *Engine) aggregate(path string, aggregations []constant.AggregationType,
+	timeFilter filter.Filter) (*aggregation.Aggregator, error) {
+	aggregator := aggregation.NewAggregatorFor(e.getSeriesDataType(path), aggregations)
+	target := &filterTarget{aggregator: aggregator, timeFilter: timeFilter}
+	if err := e.aggregateSeries(path, target, new(query.QueryExpression)); err != nil {
+		return nil, err
+	}
+	return aggregator, nil
+}
+
+// getSeriesDataType returns constant.INVALID for paths not in this file.
+func (e *Engine) getSeriesDataType(path string) constant.TSDataType {
+	if _, sensorId, ok := splitPath(path); ok {
+		return e.getDataType(sensorId)
+	}
+	return constant.INVALID
+}
+
+// aggregateSeries feeds the series at path to target, whole chunks or pages at a time where target accepts all their
+// points, decoding only the pages it accepts partially. The points of files with overlapping time ranges are read with
+// the context and the budget of exp. The error a chunk, a page or the points fail to be read with is returned.
+func (e *Engine) aggregateSeries(path string, target aggregationTarget, exp *query.QueryExpression) error {
+	deviceId, sensorId, ok := splitPath(path)
+	if !ok {
+		log.Println(fmt.Sprintf("Invalid path : %s", path))
+		return nil
+	}
+	if len(e.files) > 0 {
+		return e.aggregateFiles(path, target, exp)
+	}
+	dataType := e.getDataType(sensorId)
+	deviceMeta, ok := e.fileMeta.DeviceMap()[deviceId]
+	if dataType == constant.INVALID || !ok {
+		log.Println(fmt.Sprintf("No such timeseries in this file : %s", path))
+		return nil
+	}
+
+	for _, rowGroupMeta := range deviceMeta.GetRowGroups() {
+		for _, chunkMeta := range rowGroupMeta.GetChunkMetaDataSli() {
+			if chunkMeta.Sensor() != sensorId {
+				continue
+			}
+			if err := e.aggregateChunk(target, chunkMeta, dataType); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Engine) aggregateChunk(target aggregationTarget, chunkMeta *metadata.ChunkMetaData,
+	dataType constant.TSDataType) error {
+	extended, hasExtended := chunkMeta.GetDigest().GetExtendedStatistics()
+	some, all := target.accepts(chunkMeta.GetStartTime(), chunkMeta.GetEndTime(), hasExtended)
+	if !some {
+		return nil
+	}
+	if all && updateFromDigest(target, chunkMeta, dataType, extended) {
+		return nil
+	}
+
+	chunkHeader, err := e.reader.ReadChunkHeaderAt(chunkMeta.FileOffsetOfCorrespondingData())
+	if err != nil {
+		return fmt.Errorf("cannot read chunk of %s : %w", chunkMeta.Sensor(), err)
+	}
+	pos := e.reader.Pos()
+	for i := 0; i < chunkHeader.GetNumberOfPages(); i++ {
+		pageHeader, err := e.reader.ReadPageHeaderAt(dataType, pos)
+		if err != nil {
+			return fmt.Errorf("cannot read page of %s : %w", chunkMeta.Sensor(), err)
+		}
+		dataPos := e.reader.Pos()
+		pos = dataPos + int64(pageHeader.GetCompressedSize())
+
+		minTime, maxTime := pageHeader.Min_timestamp(), pageHeader.Max_timestamp()
+		some, all := target.accepts(minTime, maxTime, false)
+		if !some {
+			continue
+		}
+		if all {
+			stats := *pageHeader.GetStatistics()
+			target.updateFromStatistics(int64(pageHeader.GetNumberOfValues()), minTime, maxTime, stats.GetMin(),
+				stats.GetMax(), stats.GetFirst(), stats.GetLast(), stats.GetSum(), nil)
+			continue
+		}
+
+		// a boundary page, decode it and check every point
+		pageReader := basic.NewSeriesReader([]int64{dataPos}, []int{int(pageHeader.GetCompressedSize())},
+			[]constant.CompressionType{chunkHeader.GetCompressionType()},
+			[]constant.TSEncoding{chunkHeader.GetEncodingType()}, e.reader, dataType, false)
+		for pageReader.HasNext() {
+			pair, err := pageReader.Next()
+			if err != nil {
+				return fmt.Errorf("cannot read page of %s : %w", chunkMeta.Sensor(), err)
+			}
+			target.update(pair.Timestamp, pair.Value)
+		}
+	}
+	return nil
+}
+
+// updateFromDigest aggregates a whole chunk from the statistics in its metadata, including its extended statistics if
+// not nil, and reports false if they are incomplete, in which case the chunk is left for its pages to be read.
+func updateFromDigest(target aggregationTarget, chunkMeta *metadata.ChunkMetaData, dataType constant.TSDataType,
+	extended *metadata.ExtendedStatistics) bool {
+	digest := chunkMeta.GetDigest()
+	if digest == nil || chunkMeta.GetNumOfPoints() <= 0 || chunkMeta.GetStartTime() > chunkMeta.GetEndTime() {
+		return false
+	}
+	keys := []string{metadata.MIN_VALUE, metadata.MAX_VALUE, metadata.FIRST, metadata.LAST, metadata.SUM}
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value, ok := digest.GetValue(key, dataType)
+		if !ok {
+			return false
+		}
+		values[i] = value
+	}
+	target.updateFromStatistics(chunkMeta.GetNumOfPoints(), chunkMeta.GetStartTime(), chunkMeta.GetEndTime(),
+		values[0], values[1], values[2], values[3], values[4].(float64), extended)
+	return true
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"tsfile/common/constant"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/aggregation"
+	"tsfile/timeseries/query/dataset"
+	impl2 "tsfile/timeseries/query/dataset/impl"
+)
+
+// alignByDeviceSet runs the query once per device of the select paths, in the order the devices first appear, and
+// lays the results of all devices out under the same sensor columns.
+func (e *Engine) alignByDeviceSet(exp *query.QueryExpression) dataset.IQueryDataSet {
+	var devices, sensors []string
+	deviceSensors := make(map[string][]string)
+	sensorIndex := make(map[string]int)
+	for _, path := range exp.SelectPaths() {
+		deviceId, sensorId, ok := splitPath(path)
+		if !ok {
+			log.Println(fmt.Sprintf("Invalid path : %s", path))
+			continue
+		}
+		if _, ok := deviceSensors[deviceId]; !ok {
+			devices = append(devices, deviceId)
+		}
+		deviceSensors[deviceId] = append(deviceSensors[deviceId], sensorId)
+		if _, ok := sensorIndex[sensorId]; !ok {
+			sensorIndex[sensorId] = len(sensors)
+			sensors = append(sensors, sensorId)
+		}
+	}
+
+	// a column per sensor, or per aggregation and aggregate function of a sensor
+	aggregations, functions := exp.Aggregations(), exp.AggregateFunctions()
+	perSensor := len(aggregations) + len(functions)
+	columns := sensors
+	if exp.IsAggregation() {
+		columns = make([]string, 0, len(sensors)*perSensor)
+		for _, sensorId := range sensors {
+			for _, aggr := range aggregations {
+				columns = append(columns, aggregation.ColumnName(aggr, sensorId))
+			}
+			for _, name := range functions {
+				columns = append(columns, aggregation.FunctionColumnName(name, sensorId))
+			}
+		}
+	}
+
+	// the sub queries are run while iterating, after Query has given exp its context and budget back
+	ctx, budget := exp.Context(), exp.Budget()
+	newDataSet := func(deviceId string) (dataset.IQueryDataSet, []int) {
+		var paths, subColumns []string
+		var mapping []int
+		for _, sensorId := range deviceSensors[deviceId] {
+			path := deviceId + constant.PATH_SEPARATOR + sensorId
+			paths = append(paths, path)
+			if !exp.IsAggregation() {
+				subColumns = append(subColumns, path)
+				mapping = append(mapping, sensorIndex[sensorId])
+				continue
+			}
+			for i, aggr := range aggregations {
+				subColumns = append(subColumns, aggregation.ColumnName(aggr, path))
+				mapping = append(mapping, sensorIndex[sensorId]*perSensor+i)
+			}
+			for i, name := range functions {
+				subColumns = append(subColumns, aggregation.FunctionColumnName(name, path))
+				mapping = append(mapping, sensorIndex[sensorId]*perSensor+len(aggregations)+i)
+			}
+		}
+
+		sub := new(query.QueryExpression)
+		sub.SetSelectPaths(paths)
+		sub.SetConditionPaths(exp.ConditionPaths())
+		sub.SetFilter(exp.Filter())
+		sub.SetAggregations(aggregations)
+		sub.SetAggregateFunctions(functions)
+		sub.SetGroupBy(exp.GroupBy())
+		sub.SetDescending(exp.Descending())
+		sub.SetContext(ctx)
+		sub.SetBudget(budget)
+		for i, subColumn := range subColumns {
+			if fill, ok := exp.Fills()[columns[mapping[i]]]; ok {
+				sub.SetFill(subColumn, fill)
+			}
+		}
+		return e.query(sub), mapping
+	}
+	return impl2.NewAlignByDeviceQueryDataSet(devices, columns, newDataSet)
+}
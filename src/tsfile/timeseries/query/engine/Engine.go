@@ -1,15 +1,22 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"tsfile/common/constant"
+	"tsfile/common/memory"
+	"tsfile/common/utils"
 	"tsfile/file/header"
 	"tsfile/file/metadata"
+	"tsfile/timeseries/filter"
 	"tsfile/timeseries/query"
 	"tsfile/timeseries/query/dataset"
 	impl2 "tsfile/timeseries/query/dataset/impl"
+	"tsfile/timeseries/query/parser"
+	"tsfile/timeseries/query/udf"
 	"tsfile/timeseries/read"
 	"tsfile/timeseries/read/reader"
 	"tsfile/timeseries/read/reader/impl/basic"
@@ -19,37 +26,170 @@ import (
 type Engine struct {
 	reader   *read.TsFileSequenceReader
 	fileMeta *metadata.FileMetaData
+
+	// files holds an engine per file, from the oldest, when several files are queried as one, see OpenFiles
+	files           []*Engine
+	duplicatePolicy constant.DuplicatePolicy
+	// memoryBudget is the number of bytes each query may hold, 0 for no limit
+	memoryBudget int64
+	// functions are the user-defined functions queries may call, see RegisterScalarFunction
+	functions udf.Registry
 }
 
-func (e *Engine) Open(reader *read.TsFileSequenceReader) {
+// Open makes the engine query the file of reader, a *utils.CorruptedError if its metadata is corrupted. The chunks and
+// pages found corrupted later are logged and skipped by queries.
+func (e *Engine) Open(reader *read.TsFileSequenceReader) error {
+	fileMeta, err := reader.ReadFileMetadata()
+	if err != nil {
+		return err
+	}
 	e.reader = reader
-	e.fileMeta = reader.ReadFileMetadata()
+	e.fileMeta = fileMeta
+	return nil
 }
 
 func (e *Engine) Close() {
+	if len(e.files) > 0 {
+		for _, file := range e.files {
+			file.Close()
+		}
+		e.files = nil
+		return
+	}
 	e.reader.Close()
 	e.reader = nil
 	e.fileMeta = nil
 }
 
+// Query runs exp, which is left unchanged so that it can be run again: its select paths are expanded on a copy. If a
+// memory budget is set, the data set of a query exceeding it returns a *memory.BudgetExceededError from Next.
 func (e *Engine) Query(exp *query.QueryExpression) dataset.IQueryDataSet {
-	dataSet := e.decideQuerySet(exp)
+	resolved := *exp
+	return e.runQuery(&resolved)
+}
+
+// runQuery runs exp as Query does, changing it on the way (e.g. its select paths are expanded).
+func (e *Engine) runQuery(exp *query.QueryExpression) dataset.IQueryDataSet {
+	if e.memoryBudget <= 0 {
+		return e.query(exp)
+	}
+	ctx, dataSet := e.queryWithBudget(exp)
+	return impl2.NewContextQueryDataSet(ctx, dataSet)
+}
+
+// QueryContext is Query bound to ctx: once ctx is done, the readers of the query stop before their next page and the
+// data set returns ctx.Err() from Next, closing its readers. ctx.Err() is returned right away if ctx is done before
+// the data set is constructed, e.g. while an aggregation query reads the chunks it aggregates. An exceeded memory
+// budget is reported the same way, as a *memory.BudgetExceededError. As with Query, exp is left unchanged.
+func (e *Engine) QueryContext(ctx context.Context, exp *query.QueryExpression) (dataset.IQueryDataSet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	resolved := *exp
+	exp = &resolved
+	exp.SetContext(ctx)
+	var dataSet dataset.IQueryDataSet
+	if e.memoryBudget > 0 {
+		ctx, dataSet = e.queryWithBudget(exp)
+	} else {
+		dataSet = e.query(exp)
+	}
+	if ctx.Err() != nil {
+		dataSet.Close()
+		return nil, context.Cause(ctx)
+	}
+	return impl2.NewContextQueryDataSet(ctx, dataSet), nil
+}
+
+// SetMemoryBudget limits the memory each query may hold to the given number of bytes, 0 meaning no limit. The buffers
+// of the pages being read, the decoded points cached to align rows, the rows buffered by fills and the aggregators of
+// aggregation queries are accounted, see memory.Budget.
+func (e *Engine) SetMemoryBudget(bytes int64) {
+	e.memoryBudget = bytes
+}
+
+// queryWithBudget runs exp with a budget of its own and returns the context the budget cancels once exceeded. The
+// context and the budget are set on exp only while the data set is constructed, its readers keeping them.
+func (e *Engine) queryWithBudget(exp *query.QueryExpression) (context.Context, dataset.IQueryDataSet) {
+	parent := exp.Context()
+	ctx, budget := memory.WithBudget(parent, e.memoryBudget)
+	exp.SetContext(ctx)
+	exp.SetBudget(budget)
+	defer func() {
+		exp.SetContext(parent)
+		exp.SetBudget(nil)
+	}()
+	return ctx, e.query(exp)
+}
+
+func (e *Engine) query(exp *query.QueryExpression) dataset.IQueryDataSet {
+	if len(exp.SelectExpressions()) == 0 {
+		exp.SetSelectPaths(limitSeries(e.expandPaths(exp.SelectPaths()), exp.SeriesLimit(), exp.SeriesOffset()))
+	}
+	var dataSet dataset.IQueryDataSet
+	offset := exp.RowOffset()
+	if exp.AlignByDevice() {
+		dataSet = e.alignByDeviceSet(exp)
+	} else if len(e.files) == 0 && canSkipPages(exp) {
+		dataSet, offset = e.skippingQuerySet(exp)
+	} else {
+		dataSet = e.decideQuerySet(exp)
+		if len(exp.Fills()) > 0 {
+			dataSet = impl2.NewFillQueryDataSet(dataSet, exp.Fills(), exp.Descending(), exp.Budget())
+		}
+		if len(exp.SelectExpressions()) > 0 && !exp.IsAggregation() {
+			dataSet = impl2.NewExpressionQueryDataSet(dataSet, exp.SelectPaths(), exp.SelectExpressions())
+		}
+	}
+	// rows are skipped and limited after filling so that the filled values do not depend on the page asked for
+	if offset > 0 || exp.RowLimit() > 0 {
+		dataSet = impl2.NewLimitQueryDataSet(dataSet, exp.RowLimit(), offset)
+	}
 	return dataSet
 }
 
+// Parse turns a textual query on the series of this file into a QueryExpression, see parser.Parse for the syntax. The
+// functions registered on the engine may be called in it.
+func (e *Engine) Parse(sql string) (*query.QueryExpression, error) {
+	return parser.ParseWithFunctions(sql, e.getSeriesDataType, &e.functions)
+}
+
 func (e *Engine) decideQuerySet(exp *query.QueryExpression) dataset.IQueryDataSet {
-	if len(exp.ConditionPaths()) == 0 {
-		exp.SetConditionPaths(exp.SelectPaths())
+	if exp.IsAggregation() {
+		return e.aggregationQuerySet(exp)
 	}
+	// the rows the filter is checked on hold every series it reads
+	conditionPaths := appendPaths(exp.ConditionPaths(), filter.Paths(exp.Filter()))
+	if len(conditionPaths) == 0 {
+		conditionPaths = exp.SelectPaths()
+	} else if f := exp.Filter(); f != nil && len(filter.Paths(f)) > 0 && filter.SatisfyMissing(f) {
+		// e.g. "time > 5 OR s0 > 3" or "NOT s0 > 3", the rows without a value of s0 are checked too
+		conditionPaths = appendPaths(conditionPaths, exp.SelectPaths())
+	}
+	exp.SetConditionPaths(conditionPaths)
 	selectReaderMap := e.constructSeekableReaderMap(exp)
-	conditionReaderMap := e.consturctReaderMapFromPaths(exp.ConditionPaths())
-	return impl2.NewTimestampQueryDataSet(exp.SelectPaths(), exp.ConditionPaths(), selectReaderMap, conditionReaderMap, exp.Filter())
+	conditionReaderMap := e.consturctReaderMapFromPaths(exp.ConditionPaths(), exp)
+	return impl2.NewTimestampQueryDataSet(exp.SelectPaths(), exp.ConditionPaths(), selectReaderMap, conditionReaderMap,
+		exp.Filter(), exp.Descending(), exp.Budget())
+}
+
+// appendPaths returns the paths followed by those of more that are not among them.
+func appendPaths(paths []string, more []string) []string {
+	added := make(map[string]bool)
+	var merged []string
+	for _, path := range append(append([]string{}, paths...), more...) {
+		if !added[path] {
+			added[path] = true
+			merged = append(merged, path)
+		}
+	}
+	return merged
 }
 
-func (e *Engine) consturctReaderMapFromPaths(paths []string) map[string]reader.TimeValuePairReader {
+func (e *Engine) consturctReaderMapFromPaths(paths []string, exp *query.QueryExpression) map[string]reader.TimeValuePairReader {
 	readerMap := make(map[string]reader.TimeValuePairReader)
 	for _, path := range paths {
-		readerMap[path] = e.constructReader(path)
+		readerMap[path] = e.constructReader(path, exp)
 	}
 	return readerMap
 }
@@ -57,11 +197,11 @@ func (e *Engine) consturctReaderMapFromPaths(paths []string) map[string]reader.T
 func (e *Engine) constructReaderMap(exp *query.QueryExpression) map[string]reader.TimeValuePairReader {
 	readerMap := make(map[string]reader.TimeValuePairReader)
 	for _, path := range exp.SelectPaths() {
-		readerMap[path] = e.constructReader(path)
+		readerMap[path] = e.constructReader(path, exp)
 	}
 	for _, path := range exp.ConditionPaths() {
 		if _, ok := readerMap[path]; !ok {
-			readerMap[path] = e.constructReader(path)
+			readerMap[path] = e.constructReader(path, exp)
 		}
 	}
 	return readerMap
@@ -70,47 +210,80 @@ func (e *Engine) constructReaderMap(exp *query.QueryExpression) map[string]reade
 func (e *Engine) constructSeekableReaderMap(exp *query.QueryExpression) map[string]reader.ISeekableTimeValuePairReader {
 	readerMap := make(map[string]reader.ISeekableTimeValuePairReader)
 	for _, path := range exp.SelectPaths() {
-		readerMap[path] = e.constructSeekableReader(path)
+		readerMap[path] = e.constructSeekableReader(path, exp)
 	}
 	for _, path := range exp.ConditionPaths() {
 		if _, ok := readerMap[path]; !ok {
-			readerMap[path] = e.constructSeekableReader(path)
+			readerMap[path] = e.constructSeekableReader(path, exp)
 		}
 	}
 	return readerMap
 }
 
-func (e *Engine) constructReader(path string) reader.TimeValuePairReader {
-	dataType, encoding, offsets, sizes, _ := e.getPageInfo(path, false)
-	return basic.NewSeriesReader(offsets, sizes, e.reader, dataType, encoding)
+// constructReader and constructSeekableReader create readers of the pages of path that may match the filter of exp,
+// in the time order of exp.
+func (e *Engine) constructReader(path string, exp *query.QueryExpression) reader.TimeValuePairReader {
+	if len(e.files) > 0 {
+		return e.constructMergeReader(path, exp)
+	}
+	dataType, encodings, offsets, sizes, compressions, _ := e.getPageInfo(path, false, exp.Filter())
+	seriesReader := basic.NewSeriesReader(offsets, sizes, compressions, encodings, e.reader, dataType, exp.Descending())
+	seriesReader.Budget = exp.Budget()
+	return withContext(seriesReader, exp)
 }
 
-func (e *Engine) constructSeekableReader(path string) reader.ISeekableTimeValuePairReader {
-	dataType, encoding, offsets, sizes, headers := e.getPageInfo(path, true)
-	return seek.NewSeekableSeriesReader(offsets, sizes, e.reader, headers, dataType, encoding)
+func (e *Engine) constructSeekableReader(path string, exp *query.QueryExpression) reader.ISeekableTimeValuePairReader {
+	if len(e.files) > 0 {
+		return e.constructMergeSeekableReader(path, exp)
+	}
+	dataType, encodings, offsets, sizes, compressions, headers := e.getPageInfo(path, true, exp.Filter())
+	seriesReader := seek.NewSeekableSeriesReader(offsets, sizes, compressions, encodings, e.reader, headers, dataType,
+		exp.Descending())
+	seriesReader.Budget = exp.Budget()
+	if exp.Context().Done() == nil {
+		return seriesReader
+	}
+	return seek.NewContextSeekableSeriesReader(exp.Context(), seriesReader)
 }
 
-func (e *Engine) getPageInfo(path string, needHeader bool) (dataType constant.TSDataType, encoding constant.TSEncoding,
-	offsets []int64, sizes []int, pageHeaders []*header.PageHeader) {
-	pathSplits := strings.Split(path, constant.PATH_SEPARATOR)
-	pathLevelLen := len(pathSplits)
-	if pathLevelLen < 2 {
-		log.Println(fmt.Println("Invalid path : %s", path))
-		return 0, 0, nil, nil, nil
+// withContext makes seriesReader stop once the context of exp is done, if it can be.
+func withContext(seriesReader reader.TimeValuePairReader, exp *query.QueryExpression) reader.TimeValuePairReader {
+	if exp.Context().Done() == nil {
+		return seriesReader
+	}
+	return basic.NewContextSeriesReader(exp.Context(), seriesReader)
+}
+
+// getPageInfo collects the location of every page of the given path. The compression and encoding are recorded per
+// page since chunks of the same series in different row groups may be compressed and encoded differently. Chunks and pages whose time and
+// value ranges prove that no row holding their values matches pageFilter (if not nil) are left out, chunks being
+// checked against their digest before their headers are read.
+func (e *Engine) getPageInfo(path string, needHeader bool, pageFilter filter.Filter) (dataType constant.TSDataType,
+	encodings []constant.TSEncoding, offsets []int64, sizes []int, compressions []constant.CompressionType,
+	pageHeaders []*header.PageHeader) {
+	return e.collectPages(path, needHeader, pageFilter, nil)
+}
+
+// collectPages is getPageInfo counting the chunks and pages read and left out into plan, if not nil.
+func (e *Engine) collectPages(path string, needHeader bool, pageFilter filter.Filter, plan *PathPlan) (
+	dataType constant.TSDataType, encodings []constant.TSEncoding, offsets []int64, sizes []int,
+	compressions []constant.CompressionType, pageHeaders []*header.PageHeader) {
+	deviceId, sensorId, ok := splitPath(path)
+	if !ok {
+		log.Println(fmt.Sprintf("Invalid path : %s", path))
+		return 0, nil, nil, nil, nil, nil
 	}
-	deviceId := strings.Join(pathSplits[0:pathLevelLen-1], constant.PATH_SEPARATOR)
-	sensorId := pathSplits[pathLevelLen-1]
 
 	dataType = e.getDataType(sensorId)
 	if dataType == constant.INVALID {
 		log.Println(fmt.Sprintf("No such timeseries in this file : %s", path))
-		return 0, 0, nil, nil, nil
+		return 0, nil, nil, nil, nil, nil
 	}
 
 	deviceMeta, ok := e.fileMeta.DeviceMap()[deviceId]
 	if !ok {
 		log.Println(fmt.Sprintf("No such timeseries in this file : %s", path))
-		return 0, 0, nil, nil, nil
+		return 0, nil, nil, nil, nil, nil
 	}
 
 	var headers []*header.PageHeader
@@ -122,26 +295,141 @@ func (e *Engine) getPageInfo(path string, needHeader bool) (dataType constant.TS
 			if chunkMeta.Sensor() != sensorId {
 				continue
 			}
-			chunkHeader := e.reader.ReadChunkHeaderAt(chunkMeta.FileOffsetOfCorrespondingData())
-			encoding = chunkHeader.GetEncodingType()
+			if !chunkMayMatch(pageFilter, path, chunkMeta, dataType) {
+				plan.addChunk(false)
+				continue
+			}
+			plan.addChunk(true)
+			chunkHeader, err := e.reader.ReadChunkHeaderAt(chunkMeta.FileOffsetOfCorrespondingData())
+			if err != nil {
+				log.Println(fmt.Sprintf("Cannot read chunk of %s : %v", path, err))
+				continue
+			}
+			encoding := chunkHeader.GetEncodingType()
+			compression := chunkHeader.GetCompressionType()
 			pos := e.reader.Pos()
 			for i := 0; i < chunkHeader.GetNumberOfPages(); i++ {
-				pageHeader := e.reader.ReadPageHeaderAt(dataType, pos)
-				offsets = append(offsets, e.reader.Pos())
+				pageHeader, err := e.reader.ReadPageHeaderAt(dataType, pos)
+				if err != nil {
+					log.Println(fmt.Sprintf("Cannot read page of %s : %v", path, err))
+					break
+				}
+				dataPos := e.reader.Pos()
+				pos = dataPos + int64(pageHeader.GetCompressedSize())
+				if pageFilter != nil {
+					stats := *pageHeader.GetStatistics()
+					if !filter.MayMatch(pageFilter, path, pageHeader.Min_timestamp(), pageHeader.Max_timestamp(),
+						stats.GetMin(), stats.GetMax()) {
+						plan.addPage(false, 0)
+						continue
+					}
+				}
+				plan.addPage(true, int64(pageHeader.GetCompressedSize()))
+				offsets = append(offsets, dataPos)
 				sizes = append(sizes, int(pageHeader.GetCompressedSize()))
-				pos = e.reader.Pos() + int64(pageHeader.GetCompressedSize())
+				compressions = append(compressions, compression)
+				encodings = append(encodings, encoding)
 				if needHeader {
 					headers = append(headers, pageHeader)
 				}
 			}
 		}
 	}
-	return dataType, encoding, offsets, sizes, headers
+	return dataType, encodings, offsets, sizes, compressions, headers
+}
+
+// expandPaths replaces the path patterns among paths with the series in this file that match them, in lexicographical
+// order. Other paths are kept as they are, series already selected are not added again by patterns.
+func (e *Engine) expandPaths(paths []string) []string {
+	var allPaths []string
+	var expanded []string
+	added := make(map[string]bool)
+	for _, path := range paths {
+		if !utils.IsPathPattern(path) {
+			added[path] = true
+			expanded = append(expanded, path)
+			continue
+		}
+		if allPaths == nil {
+			allPaths = e.allPaths()
+		}
+		for _, p := range allPaths {
+			if !added[p] && utils.MatchPath(path, p) {
+				added[p] = true
+				expanded = append(expanded, p)
+			}
+		}
+	}
+	return expanded
+}
+
+// allPaths lists the full paths of all series in this file (or these files) in lexicographical order.
+func (e *Engine) allPaths() []string {
+	var paths []string
+	if len(e.files) > 0 {
+		seen := make(map[string]bool)
+		for _, file := range e.files {
+			for _, path := range file.allPaths() {
+				if !seen[path] {
+					seen[path] = true
+					paths = append(paths, path)
+				}
+			}
+		}
+		sort.Strings(paths)
+		return paths
+	}
+	for deviceId, deviceMeta := range e.fileMeta.DeviceMap() {
+		sensors := make(map[string]bool)
+		for _, rowGroupMeta := range deviceMeta.GetRowGroups() {
+			for _, chunkMeta := range rowGroupMeta.GetChunkMetaDataSli() {
+				if !sensors[chunkMeta.Sensor()] {
+					sensors[chunkMeta.Sensor()] = true
+					paths = append(paths, deviceId+constant.PATH_SEPARATOR+chunkMeta.Sensor())
+				}
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// splitPath splits a full series path into the device id and the sensor id, ok is false if it has less than 2 levels.
+func splitPath(path string) (deviceId string, sensorId string, ok bool) {
+	pathSplits := strings.Split(path, constant.PATH_SEPARATOR)
+	pathLevelLen := len(pathSplits)
+	if pathLevelLen < 2 {
+		return "", "", false
+	}
+	return strings.Join(pathSplits[0:pathLevelLen-1], constant.PATH_SEPARATOR), pathSplits[pathLevelLen-1], true
 }
 
 func (e *Engine) getDataType(path string) constant.TSDataType {
+	for _, file := range e.files {
+		if dataType := file.getDataType(path); dataType != constant.INVALID {
+			return dataType
+		}
+	}
+	if e.fileMeta == nil {
+		return constant.INVALID
+	}
 	if tsMeta, ok := e.fileMeta.TimeSeriesMetadataMap()[path]; ok {
 		return tsMeta.DataType()
 	}
 	return constant.INVALID
 }
+
+// chunkMayMatch checks the time range and the value range in the digest of a chunk against pageFilter, a missing value
+// range being left unknown.
+func chunkMayMatch(pageFilter filter.Filter, path string, chunkMeta *metadata.ChunkMetaData,
+	dataType constant.TSDataType) bool {
+	if pageFilter == nil {
+		return true
+	}
+	var minValue, maxValue interface{}
+	if digest := chunkMeta.GetDigest(); digest != nil {
+		minValue, _ = digest.GetValue(metadata.MIN_VALUE, dataType)
+		maxValue, _ = digest.GetValue(metadata.MAX_VALUE, dataType)
+	}
+	return filter.MayMatch(pageFilter, path, chunkMeta.GetStartTime(), chunkMeta.GetEndTime(), minValue, maxValue)
+}
@@ -1,12 +1,35 @@
 package engine
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
 	"testing"
+	"time"
+	"tsfile/common/conf"
+	"tsfile/common/memory"
+	"tsfile/common/utils"
+	"tsfile/file/header"
+	"tsfile/file/metadata"
 	"tsfile/timeseries/filter"
 	"tsfile/timeseries/filter/operator"
 	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/aggregation"
+	"tsfile/timeseries/query/dataset"
+	impl2 "tsfile/timeseries/query/dataset/impl"
+	"tsfile/timeseries/query/expression"
+	"tsfile/timeseries/query/parser"
+	"tsfile/timeseries/query/udf"
 	"tsfile/timeseries/read"
+	"tsfile/timeseries/read/datatype"
+	"tsfile/timeseries/read/reader/impl/basic"
 	"tsfile/timeseries/write/tsFileWriter"
 	"tsfile/timeseries/write/sensorDescriptor"
 	"tsfile/common/constant"
@@ -30,6 +53,7 @@ func prepareTsFile() (err error) {
 	d1s0_time := []int64{3,4,5}
 	d1s0_val := []int32{3,4,5}
 
+	os.Remove(tempFilePath)
 	writer, err := tsFileWriter.NewTsFileWriter(tempFilePath)
 	if err != nil {
 		return err
@@ -44,23 +68,23 @@ func prepareTsFile() (err error) {
 		record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
 		pt, _ := tsFileWriter.NewInt("s0", constant.INT32, d0s0_val[i])
 		record.AddTuple(pt)
-		writer.Write(*record)
+		writer.Write(record)
 	}
 	for i, t := range d0s1_time {
 		record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
 		pt, _ := tsFileWriter.NewInt("s1", constant.INT32, d0s1_val[i])
 		record.AddTuple(pt)
-		writer.Write(*record)
+		writer.Write(record)
 	}
 	for i, t := range d1s0_time {
 		record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d1")
 		pt, _ := tsFileWriter.NewInt("s0", constant.INT32, d1s0_val[i])
 		record.AddTuple(pt)
-		writer.Write(*record)
+		writer.Write(record)
 	}
 
-	if !writer.Close() {
-		return errors.New("Cannot close the the TsFile")
+	if err := writer.Close(); err != nil {
+		return err
 	}
 	return nil
 }
@@ -79,6 +103,7 @@ func TestEngine(t *testing.T) {
 	defer func() {
 		engine.Close()
 		f.Close()
+		os.Remove(tempFilePath)
 	}()
 
 	// test a non-existing series
@@ -145,9 +170,9 @@ func TestEngine(t *testing.T) {
 	dataSet = engine.Query(exp)
 	cnt = int32(0)
 	var s0Vals []interface{}
-	s0Vals = append(s0Vals, 1, 2, 3, 4, 5, nil)
+	s0Vals = append(s0Vals, int32(1), int32(2), int32(3), int32(4), int32(5), nil)
 	var s1Vals []interface{}
-	s1Vals = append(s1Vals,5, 4, nil, 3, 2, 1 )
+	s1Vals = append(s1Vals, int32(5), int32(4), nil, int32(3), int32(2), int32(1))
 	for dataSet.HasNext() {
 		record, err := dataSet.Next()
 		if err != nil {
@@ -155,8 +180,8 @@ func TestEngine(t *testing.T) {
 		}
 		checkPath(paths, record.Paths(), t)
 		if record.Timestamp() != int64(cnt+1) ||
-			record.Values()[0].(int32) != s0Vals[cnt] ||
-			record.Values()[1].(int32) != s1Vals[cnt] {
+			record.Values()[0] != s0Vals[cnt] ||
+			record.Values()[1] != s1Vals[cnt] {
 			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", cnt+1, s0Vals[cnt], s1Vals[cnt], record))
 		}
 		cnt++
@@ -182,9 +207,9 @@ func TestEngine(t *testing.T) {
 	dataSet = engine.Query(exp)
 	cnt = int32(0)
 	s0Vals = nil
-	s0Vals = append(s0Vals, 4, 5)
+	s0Vals = append(s0Vals, int32(4), int32(5))
 	s1Vals = nil
-	s1Vals = append(s1Vals, 3, 2)
+	s1Vals = append(s1Vals, int32(3), int32(2))
 	for dataSet.HasNext() {
 		record, err := dataSet.Next()
 		if err != nil {
@@ -192,8 +217,8 @@ func TestEngine(t *testing.T) {
 		}
 		checkPath(paths, record.Paths(), t)
 		if record.Timestamp() != int64(cnt+4) ||
-			record.Values()[0].(int32) != s0Vals[cnt] ||
-			record.Values()[1].(int32) != s1Vals[cnt] {
+			record.Values()[0] != s0Vals[cnt] ||
+			record.Values()[1] != s1Vals[cnt] {
 			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", cnt+4, s0Vals[cnt], s1Vals[cnt], record))
 		}
 		cnt++
@@ -209,9 +234,9 @@ func TestEngine(t *testing.T) {
 	dataSet = engine.Query(exp)
 	cnt = int32(0)
 	s0Vals = nil
-	s0Vals = append(s0Vals, 4, 5)
+	s0Vals = append(s0Vals, int32(4), int32(5))
 	s1Vals = nil
-	s1Vals = append(s1Vals, 3, 2)
+	s1Vals = append(s1Vals, int32(3), int32(2))
 	for dataSet.HasNext() {
 		record, err := dataSet.Next()
 		if err != nil {
@@ -219,8 +244,8 @@ func TestEngine(t *testing.T) {
 		}
 		checkPath(paths, record.Paths(), t)
 		if record.Timestamp() != int64(cnt+4) ||
-			record.Values()[0].(int32) != s0Vals[cnt] ||
-			record.Values()[1].(int32) != s1Vals[cnt] {
+			record.Values()[0] != s0Vals[cnt] ||
+			record.Values()[1] != s1Vals[cnt] {
 			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", cnt+4, s0Vals[cnt], s1Vals[cnt], record))
 		}
 		cnt++
@@ -238,9 +263,9 @@ func TestEngine(t *testing.T) {
 	dataSet = engine.Query(exp)
 	cnt = int32(0)
 	s0Vals = nil
-	s0Vals = append(s0Vals, 4)
+	s0Vals = append(s0Vals, int32(4))
 	s1Vals = nil
-	s1Vals = append(s1Vals, 3)
+	s1Vals = append(s1Vals, int32(3))
 	for dataSet.HasNext() {
 		record, err := dataSet.Next()
 		if err != nil {
@@ -248,21 +273,2453 @@ func TestEngine(t *testing.T) {
 		}
 		checkPath(paths, record.Paths(), t)
 		if record.Timestamp() != int64(cnt+4) ||
-			record.Values()[0].(int32) != s0Vals[cnt] ||
-			record.Values()[1].(int32) != s1Vals[cnt] {
+			record.Values()[0] != s0Vals[cnt] ||
+			record.Values()[1] != s1Vals[cnt] {
 			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", cnt+4, s0Vals[cnt], s1Vals[cnt], record))
 		}
 		cnt++
 	}
 }
 
-func checkPath(pathA []string, pathB []string, t *testing.T) {
-	if len(pathA) != len(pathB) {
-		t.Fatal("SelectPaths not consistent")
+func TestEngineFilteredRows(t *testing.T) {
+	err := prepareTsFile()
+	if err != nil {
+		t.Fatal(err)
 	}
-	for i, _ := range pathA {
-		if pathA[i] != pathB[i] {
-			t.Fatal("SelectPaths not consistent")
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// the condition series has values at 1, 2, 4, 5, 6 and the selected one at 3, 4, 5 only, the timestamps without a
+	// selected value are skipped rather than ending the query
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{series[2]})
+	exp.SetConditionPaths([]string{series[1]})
+	exp.SetFilter(filter.NewRowRecordValFilter(series[1], &operator.IntGtEqFilter{1}))
+	dataSet := engine.Query(exp)
+
+	// rows are fetched on demand, Next returning each before the reader moves on to the next one
+	for _, expected := range []int64{4, 5} {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Timestamp() != expected || record.Values()[0] != int32(expected) {
+			t.Fatal(fmt.Sprintf("Expected [%d, %d] got %v", expected, expected, record))
+		}
+	}
+	if dataSet.HasNext() {
+		t.Fatal("Expected 2 rows")
+	}
+	if _, err := dataSet.Next(); err == nil {
+		t.Fatal("Expected the data set to be exhausted")
+	}
+}
+
+func prepareMixedCompressionTsFile() (err error) {
+	/*
+		Assumed data layout, every record is flushed into its own row group:
+		root.d0.s0 : [1,1], [2,2], [3,3] (UNCOMPRESSED), [4,4], [5,5], [6,6] (SNAPPY)
+		root.d0.s1 : [1,10], [2,20], [3,30] (TS_2DIFF), [4,40], [5,50], [6,60] (PLAIN), all SNAPPY
+	*/
+	groupSize := conf.GroupSizeInByte
+	conf.GroupSizeInByte = 1
+	defer func() {
+		conf.GroupSizeInByte = groupSize
+	}()
+
+	os.Remove(tempFilePath)
+	writer, err := tsFileWriter.NewTsFileWriter(tempFilePath)
+	if err != nil {
+		return err
+	}
+
+	des, _ := sensorDescriptor.New("s0", constant.INT32, constant.RLE)
+	writer.AddSensor(des)
+	des, _ = sensorDescriptor.NewWithCompress("s1", constant.INT64, constant.TS_2DIFF, constant.SNAPPY)
+	writer.AddSensor(des)
+
+	for t := int64(1); t <= 6; t++ {
+		if t == 4 {
+			des, _ = sensorDescriptor.NewWithCompress("s0", constant.INT32, constant.RLE, constant.SNAPPY)
+			writer.AddSensor(des)
+			des, _ = sensorDescriptor.NewWithCompress("s1", constant.INT64, constant.PLAIN, constant.SNAPPY)
+			writer.AddSensor(des)
+		}
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
+		pt, _ := tsFileWriter.NewInt("s0", constant.INT32, int32(t))
+		record.AddTuple(pt)
+		pt, _ = tsFileWriter.NewLong("s1", constant.INT64, t*10)
+		record.AddTuple(pt)
+		writer.Write(record)
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func TestEngineMixedCompression(t *testing.T) {
+	err := prepareMixedCompressionTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// make sure the series really span row groups with different compressions and encodings
+	compressions := make(map[constant.CompressionType]bool)
+	encodings := make(map[constant.TSEncoding]bool)
+	for _, rowGroup := range engine.fileMeta.DeviceMap()["root.d0"].GetRowGroups() {
+		for _, chunk := range rowGroup.GetChunkMetaDataSli() {
+			chunkHeader, err := f.ReadChunkHeaderAt(chunk.FileOffsetOfCorrespondingData())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if chunk.Sensor() == "s0" {
+				compressions[chunkHeader.GetCompressionType()] = true
+			} else {
+				encodings[chunkHeader.GetEncodingType()] = true
+			}
+		}
+	}
+	if !compressions[constant.UNCOMPRESSED] || !compressions[constant.SNAPPY] {
+		t.Fatal(fmt.Sprintf("Expected both compressed and uncompressed chunks, got %v", compressions))
+	}
+	if !encodings[constant.TS_2DIFF] || !encodings[constant.PLAIN] {
+		t.Fatal(fmt.Sprintf("Expected both TS_2DIFF and PLAIN chunks, got %v", encodings))
+	}
+
+	// test selecting series with mixed compressions and encodings without conditions
+	paths := []string{"root.d0.s0", "root.d0.s1"}
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths(paths)
+	dataSet := engine.Query(exp)
+	cnt := int64(0)
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cnt++
+		checkPath(paths, record.Paths(), t)
+		if record.Timestamp() != cnt || record.Values()[0] != int32(cnt) || record.Values()[1] != cnt*10 {
+			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", cnt, cnt, cnt*10, record))
+		}
+	}
+	if cnt != 6 {
+		t.Fatal(fmt.Sprintf("Expected 6 rows got %d", cnt))
+	}
+
+	// test a condition that crosses the boundary between uncompressed and compressed row groups
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths(paths)
+	exp.SetConditionPaths([]string{"root.d0.s0"})
+	exp.SetFilter(filter.NewRowRecordValFilter("root.d0.s0", &operator.IntGtEqFilter{3}))
+	dataSet = engine.Query(exp)
+	cnt = int64(2)
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cnt++
+		if record.Timestamp() != cnt || record.Values()[0] != int32(cnt) || record.Values()[1] != cnt*10 {
+			t.Fatal(fmt.Sprintf("Expected [%d, %d, %d] got %v", cnt, cnt, cnt*10, record))
+		}
+	}
+	if cnt != 6 {
+		t.Fatal(fmt.Sprintf("Expected 4 rows got %d", cnt-2))
+	}
+}
+
+func prepareAggregationTsFile() (err error) {
+	/*
+		Assumed data layout, 10 points per page:
+		root.d0.s0 : [1,1], [2,2], ..., [100,100]
+		root.d0.s1 : [1,0.5], [2,1.0], ..., [100,50.0]
+	*/
+	pagePoints := conf.MaxNumberOfPointsInPage
+	conf.MaxNumberOfPointsInPage = 10
+	defer func() {
+		conf.MaxNumberOfPointsInPage = pagePoints
+	}()
+
+	os.Remove(tempFilePath)
+	writer, err := tsFileWriter.NewTsFileWriter(tempFilePath)
+	if err != nil {
+		return err
+	}
+
+	des, _ := sensorDescriptor.New("s0", constant.INT32, constant.RLE)
+	writer.AddSensor(des)
+	des, _ = sensorDescriptor.NewWithCompress("s1", constant.DOUBLE, constant.PLAIN, constant.SNAPPY)
+	writer.AddSensor(des)
+
+	for t := int64(1); t <= 100; t++ {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
+		pt, _ := tsFileWriter.NewInt("s0", constant.INT32, int32(t))
+		record.AddTuple(pt)
+		pt, _ = tsFileWriter.NewDouble("s1", constant.DOUBLE, float64(t)*0.5)
+		record.AddTuple(pt)
+		writer.Write(record)
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// pointFilter hides the range checks of its inner filter, so every page has to be decoded.
+type pointFilter struct {
+	inner filter.Filter
+}
+
+func (f *pointFilter) Satisfy(val interface{}) bool {
+	return f.inner.Satisfy(val)
+}
+
+var allAggregations = []constant.AggregationType{constant.COUNT, constant.SUM, constant.MIN_VALUE,
+	constant.MAX_VALUE, constant.FIRST_VALUE, constant.LAST_VALUE, constant.AVG, constant.MIN_TIME, constant.MAX_TIME}
+
+func checkAggregation(expected []interface{}, results []interface{}, t *testing.T) {
+	if len(expected) != len(results) {
+		t.Fatal(fmt.Sprintf("Expected %v got %v", expected, results))
+	}
+	for i := range expected {
+		if expected[i] != results[i] {
+			t.Fatal(fmt.Sprintf("%v: expected %v got %v", allAggregations[i], expected, results))
+		}
+	}
+}
+
+// aggregate returns the results of Engine.Aggregate, failing the test if it fails.
+func aggregate(e *Engine, path string, aggregations []constant.AggregationType, timeFilter filter.Filter,
+	t *testing.T) []interface{} {
+	results, err := e.Aggregate(path, aggregations, timeFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestEngineAggregate(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// the chunk metadata must describe the chunk for it to be aggregated without reading its pages
+	for _, chunk := range engine.fileMeta.DeviceMap()["root.d0"].GetRowGroups()[0].GetChunkMetaDataSli() {
+		if chunk.GetStartTime() != 1 || chunk.GetEndTime() != 100 || chunk.GetNumOfPoints() != 100 {
+			t.Fatal(fmt.Sprintf("Expected chunk of 100 points in [1, 100] got %d in [%d, %d]", chunk.GetNumOfPoints(),
+				chunk.GetStartTime(), chunk.GetEndTime()))
+		}
+	}
+
+	// whole series from the chunk statistics
+	checkAggregation([]interface{}{int64(100), float64(5050), int32(1), int32(100), int32(1), int32(100), 50.5,
+		int64(1), int64(100)}, aggregate(engine, "root.d0.s0", allAggregations, nil, t), t)
+	checkAggregation([]interface{}{int64(100), 2525.0, 0.5, 50.0, 0.5, 50.0, 25.25, int64(1), int64(100)},
+		aggregate(engine, "root.d0.s1", allAggregations, nil, t), t)
+
+	// [15, 77) covers pages 21~70 entirely and pages 11~20 and 71~80 partially
+	timeFilter := &operator.AndFilter{[]filter.Filter{&operator.LongGtEqFilter{15}, &operator.LongLtFilter{77}}}
+	expected := []interface{}{int64(62), float64(2821), int32(15), int32(76), int32(15), int32(76), 45.5,
+		int64(15), int64(76)}
+	checkAggregation(expected, aggregate(engine, "root.d0.s0", allAggregations, timeFilter, t), t)
+	checkAggregation(expected, aggregate(engine, "root.d0.s0", allAggregations, &pointFilter{timeFilter}, t), t)
+
+	// a range outside of the data
+	checkAggregation([]interface{}{int64(0), nil, nil, nil, nil, nil, nil, nil, nil},
+		aggregate(engine, "root.d0.s0", allAggregations, &operator.LongGtFilter{100}, t), t)
+
+	// a missing series
+	checkAggregation([]interface{}{int64(0), nil, nil, nil, nil, nil, nil, nil, nil},
+		aggregate(engine, "root.d0.s9", allAggregations, nil, t), t)
+}
+
+func TestEngineAggregateRowGroups(t *testing.T) {
+	err := prepareMixedCompressionTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	aggregations := []constant.AggregationType{constant.COUNT, constant.SUM, constant.FIRST_VALUE, constant.LAST_VALUE}
+	checkAggregation([]interface{}{int64(6), float64(210), int64(10), int64(60)},
+		aggregate(engine, "root.d0.s1", aggregations, nil, t), t)
+	checkAggregation([]interface{}{int64(3), float64(12), int32(3), int32(5)},
+		aggregate(engine, "root.d0.s0", aggregations,
+			&operator.AndFilter{[]filter.Filter{&operator.LongGtFilter{2}, &operator.LongNeqFilter{6}}}, t), t)
+}
+
+func TestEnginePushdown(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	s0Gt := filter.NewRowRecordValFilter("root.d0.s0", &operator.IntGtFilter{92})
+	s1Lt := filter.NewRowRecordValFilter("root.d0.s1", &operator.DoubleLtFilter{8.0})
+	timeLt := &filter.RowRecordTimeFilter{&operator.LongLtFilter{15}}
+	cases := []struct {
+		filter filter.Filter
+		// the number of pages of root.d0.s0 left to read out of 10
+		pages int
+	}{
+		{s0Gt, 1},
+		{timeLt, 2},
+		{&operator.AndFilter{[]filter.Filter{s0Gt, s1Lt}}, 1},
+		{&operator.OrFilter{[]filter.Filter{s0Gt, timeLt}}, 3},
+		// s1 may match on any page of s0
+		{&operator.OrFilter{[]filter.Filter{s0Gt, s1Lt}}, 10},
+		// rows without s0 satisfy the filter
+		{filter.NewRowRecordValFilter("root.d0.s0", &operator.NotFilter{&operator.IntGtFilter{20}}), 10},
+		{&operator.NotFilter{s0Gt}, 10},
+	}
+	for i, c := range cases {
+		_, _, offsets, _, _, _ := engine.getPageInfo("root.d0.s0", false, c.filter)
+		if len(offsets) != c.pages {
+			t.Fatal(fmt.Sprintf("Filter %d: expected %d pages got %d", i, c.pages, len(offsets)))
+		}
+
+		// the same filter without range support reads every page and must give the same rows
+		exp := new(query.QueryExpression)
+		exp.SetSelectPaths([]string{"root.d0.s0", "root.d0.s1"})
+		exp.SetConditionPaths([]string{"root.d0.s0", "root.d0.s1"})
+		exp.SetFilter(c.filter)
+		rows := collectRows(engine.Query(exp), t)
+		exp = new(query.QueryExpression)
+		exp.SetSelectPaths([]string{"root.d0.s0", "root.d0.s1"})
+		exp.SetConditionPaths([]string{"root.d0.s0", "root.d0.s1"})
+		exp.SetFilter(&pointFilter{c.filter})
+		expected := collectRows(engine.Query(exp), t)
+		if len(rows) != len(expected) {
+			t.Fatal(fmt.Sprintf("Filter %d: expected %d rows got %d", i, len(expected), len(rows)))
+		}
+		for j := range expected {
+			for k := range expected[j] {
+				if rows[j][k] != expected[j][k] {
+					t.Fatal(fmt.Sprintf("Filter %d: expected %v got %v", i, expected[j], rows[j]))
+				}
+			}
+		}
+	}
+}
+
+func TestEnginePushdownRowGroups(t *testing.T) {
+	err := prepareMixedCompressionTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// every row group holds one chunk of root.d0.s1, only the last 2 may match
+	s1Gt := filter.NewRowRecordValFilter("root.d0.s1", &operator.LongGtFilter{45})
+	chunks := 0
+	for _, rowGroupMeta := range engine.fileMeta.DeviceMap()["root.d0"].GetRowGroups() {
+		for _, chunkMeta := range rowGroupMeta.GetChunkMetaDataSli() {
+			if chunkMeta.Sensor() == "s1" && chunkMayMatch(s1Gt, "root.d0.s1", chunkMeta, constant.INT64) {
+				chunks++
+			}
+		}
+	}
+	if chunks != 2 {
+		t.Fatal(fmt.Sprintf("Expected 2 chunks got %d", chunks))
+	}
+
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetConditionPaths([]string{"root.d0.s1"})
+	exp.SetFilter(s1Gt)
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s0"}, [][]interface{}{{int64(5), int32(5)},
+		{int64(6), int32(6)}}, t)
+}
+
+func TestEngineGroupBy(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	paths := []string{"root.d0.s0", "root.d0.s1"}
+	aggregations := []constant.AggregationType{constant.COUNT, constant.SUM, constant.MAX_VALUE}
+	expectedPaths := []string{"count(root.d0.s0)", "sum(root.d0.s0)", "max_value(root.d0.s0)", "count(root.d0.s1)",
+		"sum(root.d0.s1)", "max_value(root.d0.s1)"}
+	groupBys := []*query.GroupBy{
+		// tumbling windows that do not align with the pages, starting before the data
+		query.NewGroupBy(-4, 110, 25),
+		// windows with gaps between them
+		{StartTime: 1, EndTime: 101, Interval: 20, SlidingStep: 30},
+		// overlapping windows
+		{StartTime: 0, EndTime: 100, Interval: 30, SlidingStep: 10},
+	}
+	for _, groupBy := range groupBys {
+		exp := new(query.QueryExpression)
+		exp.SetSelectPaths(paths)
+		exp.SetAggregations(aggregations)
+		exp.SetGroupBy(groupBy)
+		dataSet := engine.Query(exp)
+		cnt := 0
+		for dataSet.HasNext() {
+			record, err := dataSet.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkPath(expectedPaths, record.Paths(), t)
+			start, end := groupBy.Window(cnt)
+			if record.Timestamp() != start {
+				t.Fatal(fmt.Sprintf("Expected window starting at %d got %d", start, record.Timestamp()))
+			}
+			// the points of the window, s0 = t and s1 = t * 0.5
+			count, sum, max := int64(0), int64(0), int64(0)
+			for ts := start; ts < end; ts++ {
+				if ts >= 1 && ts <= 100 {
+					count++
+					sum += ts
+					max = ts
+				}
+			}
+			var expected []interface{}
+			if count == 0 {
+				expected = []interface{}{int64(0), nil, nil, int64(0), nil, nil}
+			} else {
+				expected = []interface{}{count, float64(sum), int32(max), count, float64(sum) * 0.5, float64(max) * 0.5}
+			}
+			for i := range expected {
+				if expected[i] != record.Values()[i] {
+					t.Fatal(fmt.Sprintf("Window [%d, %d): expected %v got %v", start, end, expected, record.Values()))
+				}
+			}
+			cnt++
+		}
+		if cnt != groupBy.WindowCount() {
+			t.Fatal(fmt.Sprintf("Expected %d windows got %d", groupBy.WindowCount(), cnt))
+		}
+	}
+
+	// without group by there is a single row for the whole series
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths(paths)
+	exp.SetAggregations(aggregations)
+	dataSet := engine.Query(exp)
+	record, err := dataSet.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Values()[0] != int64(100) || record.Values()[4] != 2525.0 || dataSet.HasNext() {
+		t.Fatal(fmt.Sprintf("Expected a single row of 100 points got %v", record))
+	}
+}
+
+func TestEngineFilteredAggregation(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// only the points whose timestamps satisfy the WHERE clause are aggregated, s0 = t and s1 = t * 0.5
+	cases := []struct {
+		sql      string
+		paths    []string
+		expected [][]interface{}
+	}{
+		{"SELECT count(s1), sum(s1) FROM root.d0 WHERE time >= 4", []string{"count(root.d0.s1)", "sum(root.d0.s1)"},
+			[][]interface{}{{int64(0), int64(97), 2522.0}}},
+		{"SELECT count(s0), max_value(s0) FROM root.d0 WHERE time > 15 AND time <= 35 OR time = 90",
+			[]string{"count(root.d0.s0)", "max_value(root.d0.s0)"}, [][]interface{}{{int64(0), int64(21), int32(90)}}},
+		{"SELECT count(s0), sum(s0) FROM root.d0 WHERE NOT time < 30 GROUP BY ([0, 100), 25)",
+			[]string{"count(root.d0.s0)", "sum(root.d0.s0)"},
+			[][]interface{}{{int64(0), int64(0), nil}, {int64(25), int64(20), 790.0}, {int64(50), int64(25), 1550.0},
+				{int64(75), int64(25), 2175.0}}},
+	}
+	for _, c := range cases {
+		exp, err := engine.Parse(c.sql)
+		if err != nil {
+			t.Fatal(fmt.Sprintf("%s: %v", c.sql, err))
 		}
+		checkAlignedRows(engine.Query(exp), c.paths, c.expected, t)
+	}
+	// the pages within the time range are aggregated from their statistics, only the first one is decoded
+	exp, err := engine.Parse("SELECT count(s0) FROM root.d0 WHERE time >= 4")
+	if err != nil {
+		t.Fatal(err)
 	}
+	if plan := engine.Explain(exp); plan.Err != nil || plan.Stats.PagesDecoded != 1 {
+		t.Fatal(plan.String())
+	}
+
+	// the values of a series cannot filter an aggregation
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetAggregations([]constant.AggregationType{constant.COUNT})
+	exp.SetFilter(filter.NewRowRecordValFilter("root.d0.s0", &operator.IntGtEqFilter{Ref: 4}))
+	if _, err := countRows(engine.Query(exp)); err == nil || !strings.Contains(err.Error(), "root.d0.s0") {
+		t.Fatal(fmt.Sprintf("Expected the value filter to be rejected got %v", err))
+	}
+}
+
+func TestEngineFill(t *testing.T) {
+	err := prepareTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// root.d0.s0 misses t=6, root.d0.s1 misses t=3 and root.d1.s0 misses t=1, 2 and 6
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths(series)
+	exp.SetFill(series[0], query.NewPreviousFill(0))
+	exp.SetFill(series[1], query.NewLinearFill(0))
+	exp.SetFill(series[2], query.NewConstantFill(int32(-1)))
+	expected := [][]interface{}{
+		{int32(1), int32(5), int32(-1)},
+		{int32(2), int32(4), int32(-1)},
+		{int32(3), int32(4), int32(3)}, // 3.5 rounded
+		{int32(4), int32(3), int32(4)},
+		{int32(5), int32(2), int32(5)},
+		{int32(5), int32(1), int32(-1)},
+	}
+	dataSet := engine.Query(exp)
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPath(series, record.Paths(), t)
+		for i := range expected[cnt] {
+			if record.Timestamp() != int64(cnt+1) || record.Values()[i] != expected[cnt][i] {
+				t.Fatal(fmt.Sprintf("Expected %d %v got %v", cnt+1, expected[cnt], record))
+			}
+		}
+		cnt++
+	}
+	if cnt != len(expected) {
+		t.Fatal(fmt.Sprintf("Expected %d rows got %d", len(expected), cnt))
+	}
+}
+
+func TestEngineFillGroupBy(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// windows of 10 from -29 to 150, only those starting at 1 ~ 91 have points
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetAggregations([]constant.AggregationType{constant.MAX_VALUE, constant.LAST_VALUE})
+	exp.SetGroupBy(query.NewGroupBy(-29, 150, 10))
+	exp.SetFill("max_value(root.d0.s0)", query.NewPreviousFill(20))
+	exp.SetFill("last_value(root.d0.s0)", query.NewLinearFill(0))
+	dataSet := engine.Query(exp)
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected []interface{}
+		switch ts := record.Timestamp(); {
+		case ts < 1 || ts > 111:
+			expected = []interface{}{nil, nil}
+		case ts > 91:
+			// filled from the window starting at 91, there is no later value to interpolate with
+			expected = []interface{}{int32(100), nil}
+		default:
+			expected = []interface{}{int32(ts + 9), int32(ts + 9)}
+		}
+		if record.Values()[0] != expected[0] || record.Values()[1] != expected[1] {
+			t.Fatal(fmt.Sprintf("Window %d: expected %v got %v", record.Timestamp(), expected, record.Values()))
+		}
+	}
+}
+
+func TestEngineLast(t *testing.T) {
+	err := prepareMixedCompressionTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	last := engine.Last([]string{"root.d0.s0", "root.d0.s1", "root.d9.s0", "not a series"})
+	if last[0] == nil || last[0].Timestamp != 6 || last[0].Value != int32(6) {
+		t.Fatal(fmt.Sprintf("Expected [6, 6] got %v", last[0]))
+	}
+	if last[1] == nil || last[1].Timestamp != 6 || last[1].Value != int64(60) {
+		t.Fatal(fmt.Sprintf("Expected [6, 60] got %v", last[1]))
+	}
+	if last[2] != nil || last[3] != nil {
+		t.Fatal(fmt.Sprintf("Expected no value for missing series got %v %v", last[2], last[3]))
+	}
+
+	device := engine.LastOfDevice("root.d0")
+	if len(device) != 2 || device["root.d0.s0"].Value != int32(6) || device["root.d0.s1"].Value != int64(60) {
+		t.Fatal(fmt.Sprintf("Expected the last values of s0 and s1 got %v", device))
+	}
+}
+
+func TestEngineWildcardPaths(t *testing.T) {
+	err := prepareTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	cases := []struct {
+		patterns []string
+		expected []string
+	}{
+		{[]string{"root.*.s0"}, []string{"root.d0.s0", "root.d1.s0"}},
+		{[]string{"root.d0.*"}, []string{"root.d0.s0", "root.d0.s1"}},
+		{[]string{"root.**.s0"}, []string{"root.d0.s0", "root.d1.s0"}},
+		{[]string{"root.**"}, []string{"root.d0.s0", "root.d0.s1", "root.d1.s0"}},
+		{[]string{"root.d0.s1", "root.d0.*"}, []string{"root.d0.s1", "root.d0.s0"}},
+		{[]string{"root.*"}, nil},
+		{[]string{"root.*.*.s0"}, nil},
+	}
+	for _, c := range cases {
+		expanded := engine.expandPaths(c.patterns)
+		if len(expanded) != len(c.expected) {
+			t.Fatal(fmt.Sprintf("%v: expected %v got %v", c.patterns, c.expected, expanded))
+		}
+		checkPath(c.expected, expanded, t)
+	}
+
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.*.s0"})
+	dataSet := engine.Query(exp)
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPath([]string{"root.d0.s0", "root.d1.s0"}, record.Paths(), t)
+		cnt++
+	}
+	if cnt != 5 {
+		t.Fatal(fmt.Sprintf("Expected 5 rows got %d", cnt))
+	}
+}
+
+func TestEngineAlignByDevice(t *testing.T) {
+	err := prepareTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.*.s0", "root.*.s1"})
+	exp.SetAlignByDevice(true)
+	expected := [][]interface{}{
+		{int64(1), "root.d0", int32(1), int32(5)},
+		{int64(2), "root.d0", int32(2), int32(4)},
+		{int64(3), "root.d0", int32(3), nil},
+		{int64(4), "root.d0", int32(4), int32(3)},
+		{int64(5), "root.d0", int32(5), int32(2)},
+		{int64(6), "root.d0", nil, int32(1)},
+		{int64(3), "root.d1", int32(3), nil},
+		{int64(4), "root.d1", int32(4), nil},
+		{int64(5), "root.d1", int32(5), nil},
+	}
+	checkAlignedRows(engine.Query(exp), []string{"Device", "s0", "s1"}, expected, t)
+
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.*.s0", "root.*.s1"})
+	exp.SetAggregations([]constant.AggregationType{constant.COUNT})
+	exp.SetAlignByDevice(true)
+	expected = [][]interface{}{
+		{int64(0), "root.d0", int64(5), int64(5)},
+		{int64(0), "root.d1", int64(3), nil},
+	}
+	checkAlignedRows(engine.Query(exp), []string{"Device", "count(s0)", "count(s1)"}, expected, t)
+}
+
+func TestEngineLimit(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// the first 3 pages are jumped over, 5 rows of the 4th page are skipped by the data set
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetRowLimit(10, 35)
+	if !canSkipPages(exp) {
+		t.Fatal("Expected pages to be skipped")
+	}
+	if _, offset := engine.skippingQuerySet(exp); offset != 5 {
+		t.Fatal(fmt.Sprintf("Expected 5 rows left to skip got %d", offset))
+	}
+	var expected [][]interface{}
+	for i := int64(36); i <= 45; i++ {
+		expected = append(expected, []interface{}{i, int32(i)})
+	}
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s0"}, expected, t)
+
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0", "root.d0.s1"})
+	exp.SetRowLimit(3, 98)
+	expected = [][]interface{}{{int64(99), int32(99), 49.5}, {int64(100), int32(100), 50.0}}
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s0", "root.d0.s1"}, expected, t)
+
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetRowLimit(0, 200)
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s0"}, nil, t)
+
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.*"})
+	exp.SetSeriesLimit(1, 1)
+	exp.SetRowLimit(2, 0)
+	expected = [][]interface{}{{int64(1), 0.5}, {int64(2), 1.0}}
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s1"}, expected, t)
+	// the expression keeps its pattern and is limited the same way when run again
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s1"}, expected, t)
+	if paths := exp.SelectPaths(); len(paths) != 1 || paths[0] != "root.d0.*" {
+		t.Fatal(fmt.Sprintf("Expected the select paths to be left unchanged got %v", paths))
+	}
+}
+
+var tempDirPath = "temp_TsFiles"
+
+// prepareSeriesTsFile writes a file holding root.d0 with the sensors in values, all of them at the given timestamps.
+func prepareSeriesTsFile(path string, times []int64, values map[string][]int32) (err error) {
+	writer, err := tsFileWriter.NewTsFileWriter(path)
+	if err != nil {
+		return err
+	}
+	var sensors []string
+	for sensor := range values {
+		sensors = append(sensors, sensor)
+		des, _ := sensorDescriptor.New(sensor, constant.INT32, constant.RLE)
+		writer.AddSensor(des)
+	}
+	for i, t := range times {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
+		for _, sensor := range sensors {
+			pt, _ := tsFileWriter.NewInt(sensor, constant.INT32, values[sensor][i])
+			record.AddTuple(pt)
+		}
+		writer.Write(record)
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func prepareMultiFileDir() (err error) {
+	/*
+		Assumed data layout, from the oldest file:
+		a.tsfile root.d0.s0 : [1,1], [2,2], [3,3], [4,4], [5,5]
+		         root.d0.s1 : [1,1], [2,2], [3,3], [4,4], [5,5]
+		b.tsfile root.d0.s0 :                      [4,40], [5,50], [6,60], [7,70], [8,80]
+		c.tsfile root.d0.s0 : [20,2000], [21,2100], [22,2200]
+		notes.txt is not a TsFile
+	*/
+	os.RemoveAll(tempDirPath)
+	if err = os.Mkdir(tempDirPath, 0755); err != nil {
+		return err
+	}
+	if err = prepareSeriesTsFile(tempDirPath+"/a.tsfile", []int64{1, 2, 3, 4, 5},
+		map[string][]int32{"s0": {1, 2, 3, 4, 5}, "s1": {1, 2, 3, 4, 5}}); err != nil {
+		return err
+	}
+	if err = prepareSeriesTsFile(tempDirPath+"/b.tsfile", []int64{4, 5, 6, 7, 8},
+		map[string][]int32{"s0": {40, 50, 60, 70, 80}}); err != nil {
+		return err
+	}
+	if err = prepareSeriesTsFile(tempDirPath+"/c.tsfile", []int64{20, 21, 22},
+		map[string][]int32{"s0": {2000, 2100, 2200}}); err != nil {
+		return err
+	}
+	file, err := os.Create(tempDirPath + "/notes.txt")
+	if err != nil {
+		return err
+	}
+	file.WriteString("not a TsFile")
+	return file.Close()
+}
+
+func TestEngineMultiFile(t *testing.T) {
+	err := prepareMultiFileDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := new(Engine)
+	if err = engine.OpenDir(tempDirPath); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		engine.Close()
+		os.RemoveAll(tempDirPath)
+	}()
+	if len(engine.files) != 3 {
+		t.Fatal(fmt.Sprintf("Expected 3 TsFiles got %d", len(engine.files)))
+	}
+	checkPath([]string{"root.d0.s0", "root.d0.s1"}, engine.expandPaths([]string{"root.d0.*"}), t)
+
+	// the newest file wins at 4 and 5
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0", "root.d0.s1"})
+	expected := [][]interface{}{{int64(1), int32(1), int32(1)}, {int64(2), int32(2), int32(2)},
+		{int64(3), int32(3), int32(3)}, {int64(4), int32(40), int32(4)}, {int64(5), int32(50), int32(5)},
+		{int64(6), int32(60), nil}, {int64(7), int32(70), nil}, {int64(8), int32(80), nil},
+		{int64(20), int32(2000), nil}, {int64(21), int32(2100), nil}, {int64(22), int32(2200), nil}}
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s0", "root.d0.s1"}, expected, t)
+
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetDescending(true)
+	exp.SetRowLimit(4, 0)
+	expected = [][]interface{}{{int64(22), int32(2200)}, {int64(21), int32(2100)}, {int64(20), int32(2000)},
+		{int64(8), int32(80)}}
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s0"}, expected, t)
+
+	// the value filter is checked against the merged series
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetConditionPaths([]string{"root.d0.s0"})
+	exp.SetFilter(filter.NewRowRecordValFilter("root.d0.s0", &operator.IntLtEqFilter{5}))
+	expected = [][]interface{}{{int64(1), int32(1)}, {int64(2), int32(2)}, {int64(3), int32(3)}}
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s0"}, expected, t)
+
+	// files whose time range cannot match are left out
+	timeFilter := &filter.RowRecordTimeFilter{&operator.LongGtEqFilter{10}}
+	if files := engine.filesOf("root.d0.s0", timeFilter); len(files) != 1 || files[0] != engine.files[2] {
+		t.Fatal(fmt.Sprintf("Expected only the newest file got %d files", len(files)))
+	}
+	aggregations := []constant.AggregationType{constant.COUNT, constant.SUM, constant.FIRST_VALUE,
+		constant.LAST_VALUE}
+	checkAggregation([]interface{}{int64(11), float64(6606), int32(1), int32(2200)},
+		aggregate(engine, "root.d0.s0", aggregations, nil, t), t)
+	checkAggregation([]interface{}{int64(3), float64(6300), int32(2000), int32(2200)},
+		aggregate(engine, "root.d0.s0", aggregations, &operator.LongGtEqFilter{10}, t), t)
+
+	last := engine.Last([]string{"root.d0.s0", "root.d0.s1", "root.d0.s9"})
+	if last[0] == nil || last[0].Timestamp != 22 || last[1] == nil || last[1].Timestamp != 5 || last[2] != nil {
+		t.Fatal(fmt.Sprintf("Expected [22, 2200], [5, 5] and nil got %v %v %v", last[0], last[1], last[2]))
+	}
+	if device := engine.LastOfDevice("root.d0"); len(device) != 2 || device["root.d0.s1"].Value != int32(5) {
+		t.Fatal(fmt.Sprintf("Expected the last values of s0 and s1 got %v", device))
+	}
+
+	// the oldest file wins at 4 and 5
+	engine.SetDuplicatePolicy(constant.OLDEST_FILE_WINS)
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetRowLimit(3, 3)
+	expected = [][]interface{}{{int64(4), int32(4)}, {int64(5), int32(5)}, {int64(6), int32(60)}}
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s0"}, expected, t)
+	checkAggregation([]interface{}{int64(11), float64(6525), int32(1), int32(2200)},
+		aggregate(engine, "root.d0.s0", aggregations, nil, t), t)
+
+	// the pages of the overlapping files are read within the budget of the query
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetAggregations([]constant.AggregationType{constant.COUNT})
+	engine.SetMemoryBudget(aggregation.Size(exp.Aggregations()) + 8)
+	var exceeded *memory.BudgetExceededError
+	if _, err := engine.QueryContext(context.Background(), exp); !errors.As(err, &exceeded) {
+		t.Fatal(fmt.Sprintf("Expected the budget to be exceeded got %v", err))
+	}
+}
+
+func TestEngineParseMissingValues(t *testing.T) {
+	err := prepareTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// root.d0.s1 has no value at 3, where the conditions below are satisfied
+	paths := []string{"root.d0.s0"}
+	cases := []struct {
+		sql      string
+		expected [][]interface{}
+	}{
+		{"SELECT s0 FROM root.d0 WHERE time > 2 OR s1 > 3", [][]interface{}{{int64(1), int32(1)}, {int64(2), int32(2)},
+			{int64(3), int32(3)}, {int64(4), int32(4)}, {int64(5), int32(5)}}},
+		{"SELECT s0 FROM root.d0 WHERE NOT s1 > 3", [][]interface{}{{int64(3), int32(3)}, {int64(4), int32(4)},
+			{int64(5), int32(5)}}},
+		{"SELECT s0 FROM root.d0 WHERE time > 2 AND s1 > 2", [][]interface{}{{int64(4), int32(4)}}},
+	}
+	for _, c := range cases {
+		exp, err := engine.Parse(c.sql)
+		if err != nil {
+			t.Fatal(fmt.Sprintf("%s: %v", c.sql, err))
+		}
+		checkAlignedRows(engine.Query(exp), paths, c.expected, t)
+	}
+}
+
+func TestEngineParse(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	paths := []string{"root.d0.s0", "root.d0.s1"}
+	cases := []struct {
+		sql      string
+		paths    []string
+		expected [][]interface{}
+	}{
+		{"SELECT s0, s1 FROM root.d0 WHERE time >= 90 AND s0 > 92 LIMIT 3", paths,
+			[][]interface{}{{int64(93), int32(93), 46.5}, {int64(94), int32(94), 47.0}, {int64(95), int32(95), 47.5}}},
+		{"select * from root.d0 where s1 < 2.5 || time == 100 order by time desc limit 3", paths,
+			[][]interface{}{{int64(100), int32(100), 50.0}, {int64(4), int32(4), 2.0}, {int64(3), int32(3), 1.5}}},
+		{"select s0 from root.d0 where not (root.d0.s0 > 2 and s1 != 0.5e1)", []string{"root.d0.s0"},
+			[][]interface{}{{int64(1), int32(1)}, {int64(2), int32(2)}, {int64(10), int32(10)}}},
+		{"SELECT count(s0), max_value(s0)\nFROM root.d0\nGROUP BY ([0, 100), 50)",
+			[]string{"count(root.d0.s0)", "max_value(root.d0.s0)"},
+			[][]interface{}{{int64(0), int64(49), int32(49)}, {int64(50), int64(50), int32(99)}}},
+	}
+	for _, c := range cases {
+		exp, err := engine.Parse(c.sql)
+		if err != nil {
+			t.Fatal(fmt.Sprintf("%s: %v", c.sql, err))
+		}
+		checkAlignedRows(engine.Query(exp), c.paths, c.expected, t)
+	}
+
+	// the series and their data types come from the file
+	for _, sql := range []string{"SELECT s0 FROM root.d0 WHERE s9 > 3", "SELECT s0 FROM root.d0 WHERE s1 > 'a'"} {
+		_, err := engine.Parse(sql)
+		if _, ok := err.(*parser.ParseError); !ok {
+			t.Fatal(fmt.Sprintf("%s: expected a ParseError got %v", sql, err))
+		}
+	}
+}
+
+// cancellingFilter cancels its context when checking the row at timestamp at and accepts no row.
+type cancellingFilter struct {
+	cancel  context.CancelFunc
+	at      int64
+	checked int
+}
+
+func (f *cancellingFilter) Satisfy(val interface{}) bool {
+	f.checked++
+	if val.(*datatype.RowRecord).Timestamp() == f.at {
+		f.cancel()
+	}
+	return false
+}
+
+// failingDataSet returns rows up to its first failing timestamp, at which Next returns err.
+type failingDataSet struct {
+	next      int64
+	failingAt int64
+	err       error
+}
+
+func (set *failingDataSet) HasNext() bool {
+	return true
+}
+
+func (set *failingDataSet) Next() (*datatype.RowRecord, error) {
+	set.next++
+	if set.next >= set.failingAt {
+		return nil, set.err
+	}
+	return datatype.NewRowRecordWithPaths([]string{"root.d0.s0"}), nil
+}
+
+func (set *failingDataSet) Close() {
+}
+
+func TestEngineLimitError(t *testing.T) {
+	// the error the offset rows are skipped with is returned instead of ending the rows
+	failure := errors.New("corrupted page")
+	dataSet := impl2.NewLimitQueryDataSet(&failingDataSet{failingAt: 3, err: failure}, 10, 5)
+	if !dataSet.HasNext() {
+		t.Fatal("Expected the error to be reported")
+	}
+	if _, err := dataSet.Next(); err != failure {
+		t.Fatal(fmt.Sprintf("Expected %v got %v", failure, err))
+	}
+	if dataSet.HasNext() {
+		t.Fatal("Expected no row after the error")
+	}
+}
+
+func TestEngineQueryContext(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// cancelled between two rows
+	ctx, cancel := context.WithCancel(context.Background())
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0", "root.d0.s1"})
+	dataSet, err := engine.QueryContext(ctx, exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := dataSet.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	if !dataSet.HasNext() {
+		t.Fatal("Expected the cancellation to be reported")
+	}
+	if _, err := dataSet.Next(); err != context.Canceled {
+		t.Fatal(fmt.Sprintf("Expected %v got %v", context.Canceled, err))
+	}
+	if dataSet.HasNext() {
+		t.Fatal("Expected no row after the cancellation")
+	}
+
+	// cancelled while scanning for a row matching the filter, the pages after the current one are not read
+	ctx, cancel = context.WithCancel(context.Background())
+	cancelling := &cancellingFilter{cancel: cancel, at: 15}
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetConditionPaths([]string{"root.d0.s0"})
+	exp.SetFilter(cancelling)
+	dataSet, err = engine.QueryContext(ctx, exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dataSet.Next(); err != context.Canceled {
+		t.Fatal(fmt.Sprintf("Expected %v got %v", context.Canceled, err))
+	}
+	if cancelling.checked != 15 {
+		t.Fatal(fmt.Sprintf("Expected 15 rows to be checked got %d", cancelling.checked))
+	}
+
+	// done before the query is constructed
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetAggregations([]constant.AggregationType{constant.COUNT})
+	if _, err := engine.QueryContext(ctx, exp); err != context.DeadlineExceeded {
+		t.Fatal(fmt.Sprintf("Expected %v got %v", context.DeadlineExceeded, err))
+	}
+
+	// an aggregation stops at the first page after the cancellation
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	target := &filterTarget{aggregator: aggregation.NewAggregator(constant.INT32)}
+	exp.SetContext(ctx)
+	engine.aggregateSeries("root.d0.s0", targetWithContext(target, exp), exp)
+	if count := target.aggregator.Result(constant.COUNT); count != int64(0) {
+		t.Fatal(fmt.Sprintf("Expected nothing to be aggregated got %v", count))
+	}
+}
+
+func TestEngineMemoryBudget(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// a page of each path at a time fits into the budget
+	engine.SetMemoryBudget(4096)
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0", "root.d0.s1"})
+	if rows := collectRows(engine.Query(exp), t); len(rows) != 100 {
+		t.Fatal(fmt.Sprintf("Expected 100 rows got %d", len(rows)))
+	}
+	if exp.Budget() != nil || exp.Context() != context.Background() {
+		t.Fatal("Expected the budget of the query to be left out of the expression")
+	}
+
+	// the first page does not
+	engine.SetMemoryBudget(16)
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	dataSet := engine.Query(exp)
+	if !dataSet.HasNext() {
+		t.Fatal("Expected the exceeded budget to be reported")
+	}
+	var exceeded *memory.BudgetExceededError
+	if _, err := dataSet.Next(); !errors.As(err, &exceeded) || exceeded.Limit != 16 {
+		t.Fatal(fmt.Sprintf("Expected the budget of 16 bytes to be exceeded got %v", err))
+	}
+	if dataSet.HasNext() {
+		t.Fatal("Expected no row after the budget is exceeded")
+	}
+
+	// the aggregators of 1000 windows do not either, which is known before the data set is returned
+	engine.SetMemoryBudget(10000)
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetAggregations([]constant.AggregationType{constant.COUNT})
+	exp.SetGroupBy(query.NewGroupBy(0, 1000, 1))
+	if _, err := engine.QueryContext(context.Background(), exp); !errors.As(err, &exceeded) {
+		t.Fatal(fmt.Sprintf("Expected the budget to be exceeded got %v", err))
+	}
+	exp.SetGroupBy(query.NewGroupBy(0, 100, 10))
+	dataSet, err = engine.QueryContext(context.Background(), exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := collectRows(dataSet, t); len(rows) != 10 {
+		t.Fatal(fmt.Sprintf("Expected 10 windows got %d", len(rows)))
+	}
+}
+
+func TestEngineExplain(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// only the last page of s0 may match, which is read for the filter and for the select path, and s1 is only sought
+	// at the matching timestamps
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.*"})
+	exp.SetConditionPaths([]string{"root.d0.s0"})
+	exp.SetFilter(filter.NewRowRecordValFilter("root.d0.s0", &operator.IntGtEqFilter{95}))
+	plan := engine.Explain(exp)
+	if plan.Err != nil {
+		t.Fatal(plan.Err)
+	}
+	checkPath([]string{"TimestampQueryDataSet"}, plan.DataSets, t)
+	checkPath([]string{"root.d0.s0", "root.d0.s1"}, plan.SelectPaths, t)
+	checkPath([]string{"root.d0.s0"}, plan.ConditionPaths, t)
+	if len(plan.Paths) != 2 {
+		t.Fatal(fmt.Sprintf("Expected the plan of 2 paths got %v", plan))
+	}
+	if p := plan.Paths[0]; p.Path != "root.d0.s0" || p.ChunksRead != 1 || p.PagesRead != 1 || p.PagesPruned != 9 {
+		t.Fatal(fmt.Sprintf("Expected 1 page of s0 to be read and 9 pruned got %+v", p))
+	}
+	if p := plan.Paths[1]; p.Path != "root.d0.s1" || p.PagesRead != 10 || p.PagesPruned != 0 || p.BytesToRead <= 0 {
+		t.Fatal(fmt.Sprintf("Expected 10 pages of s1 to be read got %+v", p))
+	}
+	if plan.Stats.Rows != 6 || plan.Stats.PagesDecoded != 3 || plan.Stats.ValuesFiltered != 4 ||
+		plan.Stats.BytesRead <= 0 {
+		t.Fatal(fmt.Sprintf("Expected 6 rows from 3 pages with 4 rows filtered got %+v", plan.Stats))
+	}
+	if _, ok := exp.Filter().(*filter.RowRecordValFilter); !ok || !strings.Contains(plan.String(), "values filtered: 4") {
+		t.Fatal(plan.String())
+	}
+	// the explained expression runs as if it had not been explained
+	if paths := exp.SelectPaths(); len(paths) != 1 || paths[0] != "root.d0.*" {
+		t.Fatal(fmt.Sprintf("Expected the select paths to be left unchanged got %v", paths))
+	}
+	if cnt, err := countRows(engine.Query(exp)); cnt != 6 || err != nil {
+		t.Fatal(fmt.Sprintf("Expected 6 rows got %d, %v", cnt, err))
+	}
+
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetRowLimit(5, 0)
+	exp.SetFill("root.d0.s0", &query.Fill{Type: constant.PREVIOUS_FILL})
+	plan = engine.Explain(exp)
+	checkPath([]string{"LimitQueryDataSet", "FillQueryDataSet", "TimestampQueryDataSet"}, plan.DataSets, t)
+	// the first page is read both to generate the timestamps and to select the values
+	if plan.Stats.Rows != 5 || plan.Stats.PagesDecoded != 2 {
+		t.Fatal(fmt.Sprintf("Expected 5 rows from 2 pages got %+v", plan.Stats))
+	}
+
+	// the whole chunk is aggregated from its statistics
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetAggregations([]constant.AggregationType{constant.COUNT})
+	plan = engine.Explain(exp)
+	checkPath([]string{"AggregationQueryDataSet"}, plan.DataSets, t)
+	if len(plan.Paths) != 0 || plan.Stats.Rows != 1 || plan.Stats.PagesDecoded != 0 {
+		t.Fatal(plan.String())
+	}
+}
+
+func collectRows(dataSet dataset.IQueryDataSet, t *testing.T) [][]interface{} {
+	var rows [][]interface{}
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, append([]interface{}{record.Timestamp()}, record.Values()...))
+	}
+	return rows
+}
+
+func TestEngineExpressions(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	s0, s1 := expression.Series("root.d0.s0"), expression.Series("root.d0.s1")
+	exp := new(query.QueryExpression)
+	exp.SetSelectExpressions([]expression.Expression{
+		expression.Binary('+', expression.Binary('*', s0, expression.Constant(1.8)), expression.Constant(int32(32))),
+		expression.Binary('-', s0, s1),
+		expression.Binary('/', s0, expression.Constant(int32(2))),
+		expression.Binary('*', s0, expression.Constant(int64(3))),
+		expression.Binary('%', s0, expression.Constant(int32(0))),
+	})
+	exp.SetRowLimit(3, 0)
+	columns := []string{"root.d0.s0 * 1.8 + 32", "root.d0.s0 - root.d0.s1", "root.d0.s0 / 2", "root.d0.s0 * 3",
+		"root.d0.s0 % 0"}
+	var expected [][]interface{}
+	for i := int64(1); i <= 3; i++ {
+		expected = append(expected, []interface{}{i, float64(i)*1.8 + 32, float64(i) * 0.5, int32(i) / 2, i * 3, nil})
+	}
+	checkAlignedRows(engine.Query(exp), columns, expected, t)
+
+	exp, err = engine.Parse("SELECT s0 * (s1 - 1), abs(-s0), diff(s1), derivative(s0), moving_avg(s0, 5), s0 " +
+		"FROM root.d0 WHERE time > 95")
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns = []string{"root.d0.s0 * (root.d0.s1 - 1)", "abs(-root.d0.s0)", "diff(root.d0.s1)",
+		"derivative(root.d0.s0)", "moving_avg(root.d0.s0, 5)", "root.d0.s0"}
+	expected = nil
+	for i := int64(96); i <= 100; i++ {
+		row := []interface{}{i, float64(i) * (float64(i)*0.5 - 1), int32(i), 0.5, 1.0, nil, int32(i)}
+		if i == 96 {
+			row[3], row[4] = nil, nil
+		}
+		if i == 100 {
+			row[5] = 98.0
+		}
+		expected = append(expected, row)
+	}
+	checkAlignedRows(engine.Query(exp), columns, expected, t)
+
+	errorCases := []struct {
+		sql string
+		at  string
+	}{
+		{"SELECT s0 + 1, count(s1) FROM root.d0", "count"},
+		{"SELECT s9 * 2 FROM root.d0", "s9"},
+		{"SELECT * + 1 FROM root.d0", "*"},
+		{"SELECT moving_avg(s0, 0) FROM root.d0", "0)"},
+		{"SELECT bar(s0) + 1 FROM root.d0", "bar"},
+		{"SELECT (s0 + 1 FROM root.d0", "FROM"},
+	}
+	for _, c := range errorCases {
+		_, err := engine.Parse(c.sql)
+		parseError, ok := err.(*parser.ParseError)
+		if !ok {
+			t.Fatal(fmt.Sprintf("%s: expected a ParseError got %v", c.sql, err))
+		}
+		if parseError.Offset != strings.LastIndex(c.sql, c.at) {
+			t.Fatal(fmt.Sprintf("%s: expected an error at %q got %v", c.sql, c.at, err))
+		}
+	}
+}
+
+// integral is the area under a series over time by the trapezoidal rule, e.g. the energy given power readings.
+type integral struct {
+	seen      bool
+	firstTime int64
+	lastTime  int64
+	first     float64
+	last      float64
+	area      float64
+}
+
+func (f *integral) Init(dataType constant.TSDataType) {
+}
+
+func (f *integral) Add(timestamp int64, value interface{}) {
+	var v float64
+	switch value := value.(type) {
+	case int32:
+		v = float64(value)
+	case float64:
+		v = value
+	}
+	f.Merge(&integral{seen: true, firstTime: timestamp, lastTime: timestamp, first: v, last: v})
+}
+
+func (f *integral) Merge(other udf.Aggregate) {
+	o := other.(*integral)
+	if !o.seen {
+		return
+	}
+	if !f.seen {
+		*f = *o
+		return
+	}
+	f.area += o.area + float64(o.firstTime-f.lastTime)*(o.first+f.last)/2
+	f.lastTime, f.last = o.lastTime, o.last
+}
+
+func (f *integral) Result() interface{} {
+	if !f.seen {
+		return nil
+	}
+	return f.area
+}
+
+func TestEngineFunctions(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	clamp := udf.ScalarFunc(func(timestamp int64, args []interface{}) interface{} {
+		v, min, max := args[0].(int32), args[1].(int32), args[2].(int32)
+		if v < min {
+			return min
+		} else if v > max {
+			return max
+		}
+		return v
+	})
+	newIntegral := func() udf.Aggregate {
+		return new(integral)
+	}
+	if err := engine.RegisterScalarFunction("clamp", clamp); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RegisterAggregateFunction("Integral", newIntegral); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"clamp", "INTEGRAL", "count", "abs", "moving_avg", "1x", ""} {
+		if engine.RegisterScalarFunction(name, clamp) == nil {
+			t.Fatal(fmt.Sprintf("Expected %q not to be registered", name))
+		}
+	}
+
+	cases := []struct {
+		sql      string
+		paths    []string
+		expected [][]interface{}
+	}{
+		{"SELECT clamp(s0, 10, 20) * 2 FROM root.d0 WHERE time > 8 AND time <= 11", []string{"clamp(root.d0.s0, 10, 20) * 2"},
+			[][]interface{}{{int64(9), int32(20)}, {int64(10), int32(20)}, {int64(11), int32(22)}}},
+		{"SELECT integral(s0), count(s0) FROM root.d0", []string{"count(root.d0.s0)", "integral(root.d0.s0)"},
+			[][]interface{}{{int64(0), int64(100), 4999.5}}},
+		// the sliding windows are merged from panes 10 long
+		{"SELECT count(s0), integral(s0) FROM root.d0 GROUP BY ([0, 50), 20, 10)",
+			[]string{"count(root.d0.s0)", "integral(root.d0.s0)"},
+			[][]interface{}{{int64(0), int64(19), 180.0}, {int64(10), int64(20), 370.5}, {int64(20), int64(20), 560.5},
+				{int64(30), int64(20), 750.5}, {int64(40), int64(10), 400.5}}},
+		{"SELECT integral(s0) FROM root.d0 GROUP BY ([0, 50), 10, 20) ORDER BY TIME DESC",
+			[]string{"integral(root.d0.s0)"},
+			[][]interface{}{{int64(40), 400.5}, {int64(20), 220.5}, {int64(0), 40.0}}},
+		{"SELECT integral(s0) FROM root.d0 WHERE time >= 90", []string{"integral(root.d0.s0)"},
+			[][]interface{}{{int64(0), 950.0}}},
+	}
+	for _, c := range cases {
+		exp, err := engine.Parse(c.sql)
+		if err != nil {
+			t.Fatal(fmt.Sprintf("%s: %v", c.sql, err))
+		}
+		checkAlignedRows(engine.Query(exp), c.paths, c.expected, t)
+	}
+
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s1"})
+	exp.SetAggregateFunctions([]string{"integral", "unknown"})
+	exp.SetGroupBy(query.NewGroupBy(0, 100, 50))
+	checkAlignedRows(engine.Query(exp), []string{"integral(root.d0.s1)", "unknown(root.d0.s1)"},
+		[][]interface{}{{int64(0), 600.0, nil}, {int64(50), 1825.25, nil}}, t)
+
+	for _, sql := range []string{"SELECT integral(s0) + 1 FROM root.d0", "SELECT clamp(s0) FROM root.d0 GROUP BY ([0, 50), 10)"} {
+		if _, err := engine.Parse(sql); err == nil {
+			t.Fatal(fmt.Sprintf("%s: expected an error", sql))
+		}
+	}
+}
+
+// checkApproximate checks that a float64 or int64 result is within tolerance of expected.
+func checkApproximate(name string, got interface{}, expected float64, tolerance float64, t *testing.T) {
+	var v float64
+	switch got := got.(type) {
+	case float64:
+		v = got
+	case int64:
+		v = float64(got)
+	default:
+		t.Fatal(fmt.Sprintf("%s: expected a number got %v", name, got))
+	}
+	if v < expected-tolerance || v > expected+tolerance {
+		t.Fatal(fmt.Sprintf("%s: expected %v within %v got %v", name, expected, tolerance, v))
+	}
+}
+
+func TestEngineApproximateAggregations(t *testing.T) {
+	/*
+		Assumed data layout, from the oldest file:
+		a.tsfile root.d0.s0 : [1,1], [2,2], ..., [10000,10000]
+		b.tsfile root.d0.s0 : [10001,5001], [10002,5002], ..., [20000,15000]
+	*/
+	os.RemoveAll(tempDirPath)
+	if err := os.Mkdir(tempDirPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"a", "b"} {
+		var times []int64
+		var values []int32
+		for j := 1; j <= 10000; j++ {
+			times = append(times, int64(i*10000+j))
+			values = append(values, int32(i*5000+j))
+		}
+		err := prepareSeriesTsFile(tempDirPath+"/"+name+".tsfile", times, map[string][]int32{"s0": values})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine := new(Engine)
+	if err := engine.OpenDir(tempDirPath); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		engine.Close()
+		os.RemoveAll(tempDirPath)
+	}()
+
+	// fewer values than the size of the sketch give exact percentiles
+	aggregations := []constant.AggregationType{constant.P50, constant.P95, constant.APPROX_COUNT_DISTINCT}
+	results := aggregate(engine, "root.d0.s0", aggregations, &operator.LongLtEqFilter{Ref: 20}, t)
+	if results[0] != 10.0 || results[1] != 19.0 {
+		t.Fatal(fmt.Sprintf("Expected exact percentiles got %v", results))
+	}
+	checkApproximate("approx_count_distinct", results[2], 20, 1, t)
+
+	// ranks within 2% of the number of values, i.e. 400 values over both files
+	exp, err := engine.Parse("SELECT p50(s0), p99(s0), approx_count_distinct(s0), count(s0) FROM root.d0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataSet := engine.Query(exp)
+	row, err := dataSet.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPath([]string{"p50(root.d0.s0)", "p99(root.d0.s0)", "approx_count_distinct(root.d0.s0)",
+		"count(root.d0.s0)"}, row.Paths(), t)
+	checkApproximate("p50", row.Values()[0], 7500, 200, t)
+	checkApproximate("p99", row.Values()[1], 14800, 400, t)
+	checkApproximate("approx_count_distinct", row.Values()[2], 15000, 15000*0.03, t)
+	if row.Values()[3] != int64(20000) {
+		t.Fatal(fmt.Sprintf("Expected 20000 points got %v", row.Values()[3]))
+	}
+
+	exp, err = engine.Parse("SELECT p50(s0), approx_count_distinct(s0) FROM root.d0 GROUP BY ([1, 20001), 10000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataSet = engine.Query(exp)
+	for _, expected := range [][]float64{{1, 5000}, {10001, 10000}} {
+		row, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row.Timestamp() != int64(expected[0]) {
+			t.Fatal(fmt.Sprintf("Expected the window at %v got %v", expected[0], row))
+		}
+		checkApproximate("p50", row.Values()[0], expected[1], 200, t)
+		checkApproximate("approx_count_distinct", row.Values()[1], 10000, 10000*0.03, t)
+	}
+	if dataSet.HasNext() {
+		t.Fatal("Expected 2 windows")
+	}
+
+	// the aggregators of both files merge into that of the whole series
+	aggregator, err := engine.files[0].aggregate("root.d0.s0", aggregations, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := engine.files[1].aggregate("root.d0.s0", aggregations, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggregator.Merge(other)
+	checkApproximate("p50", aggregator.Result(constant.P50), 7500, 200, t)
+	checkApproximate("approx_count_distinct", aggregator.Result(constant.APPROX_COUNT_DISTINCT), 15000, 15000*0.03, t)
+	if aggregator.Result(constant.COUNT) != int64(20000) || aggregator.Result(constant.MAX_VALUE) != int32(15000) {
+		t.Fatal("Unexpected merged statistics")
+	}
+}
+
+func TestEngineExtendedDigest(t *testing.T) {
+	/*
+		Assumed data layout, in 10 pages of 100 points:
+		root.d0.s0 : [1,1], [2,2], ..., [1000,1000]
+	*/
+	pagePoints, extended := conf.MaxNumberOfPointsInPage, conf.ExtendedDigest
+	conf.MaxNumberOfPointsInPage = 100
+	defer func() {
+		conf.MaxNumberOfPointsInPage, conf.ExtendedDigest = pagePoints, extended
+		os.Remove(tempFilePath)
+	}()
+	var times []int64
+	var values []int32
+	for i := 1; i <= 1000; i++ {
+		times = append(times, int64(i))
+		values = append(values, int32(i))
+	}
+
+	sql := "SELECT p50(s0), variance(s0), stddev(s0), avg(s0) FROM root.d0"
+	for _, extendedDigest := range []bool{true, false} {
+		conf.ExtendedDigest = extendedDigest
+		if err := prepareSeriesTsFile(tempFilePath, times, map[string][]int32{"s0": values}); err != nil {
+			t.Fatal(err)
+		}
+		f := new(read.TsFileSequenceReader)
+		f.Open(tempFilePath)
+		engine := new(Engine)
+		engine.Open(f)
+
+		digest := engine.fileMeta.DeviceMap()["root.d0"].GetRowGroups()[0].GetChunkMetaDataSli()[0].GetDigest()
+		statistics, ok := digest.GetExtendedStatistics()
+		if ok != extendedDigest {
+			t.Fatal(fmt.Sprintf("Expected extended statistics %v got %v", extendedDigest, ok))
+		}
+		if ok {
+			var histogramCount int64
+			for _, count := range statistics.Histogram {
+				histogramCount += count
+			}
+			// 512 to 1000 are in [2^9, 2^10)
+			if statistics.Count != 1000 || histogramCount != 1000 || statistics.Histogram[10+metadata.HistogramOffset] != 489 {
+				t.Fatal(fmt.Sprintf("Unexpected extended statistics %+v", statistics))
+			}
+		}
+
+		// the whole chunk is aggregated from its digest if it has extended statistics, otherwise every page is decoded
+		exp, err := engine.Parse(sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan := engine.Explain(exp)
+		if plan.Err != nil {
+			t.Fatal(plan.Err)
+		}
+		if decoded := plan.Stats.PagesDecoded; (extendedDigest && decoded != 0) || (!extendedDigest && decoded != 10) {
+			t.Fatal(fmt.Sprintf("Unexpected pages decoded with extended statistics %v: %v", extendedDigest, plan))
+		}
+		row, err := engine.Query(exp).Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkApproximate("p50", row.Values()[0], 500, 20, t)
+		checkApproximate("variance", row.Values()[1], (1000*1000-1)/12.0, 1e-6, t)
+		checkApproximate("stddev", row.Values()[2], math.Sqrt((1000*1000-1)/12.0), 1e-6, t)
+		if row.Values()[3] != 500.5 {
+			t.Fatal(fmt.Sprintf("Expected an average of 500.5 got %v", row.Values()[3]))
+		}
+
+		// chunks partially in the time range are still read page by page, whose statistics are not extended
+		results := aggregate(engine, "root.d0.s0", []constant.AggregationType{constant.VARIANCE, constant.P50},
+			&operator.LongLtEqFilter{Ref: 100}, t)
+		checkApproximate("variance", results[0], (100*100-1)/12.0, 1e-6, t)
+		checkApproximate("p50", results[1], 50, 0, t)
+		engine.Close()
+	}
+}
+
+func TestEngineSearch(t *testing.T) {
+	/*
+		Assumed data layout, in 40 pages of 50 points:
+		root.d0.s0 : [1,0], [2,7], ..., [i,(7*(i-1))%13], ..., [2000,...]
+		with the fault signature f at [501,510] + 100, at [1001,1010] as it is and at [1501,1510] * 2 + 5
+	*/
+	signature := []int32{0, 20, 40, 60, 40, 20, 0, -20, -40, -20}
+	pagePoints := conf.MaxNumberOfPointsInPage
+	conf.MaxNumberOfPointsInPage = 50
+	defer func() {
+		conf.MaxNumberOfPointsInPage = pagePoints
+		os.Remove(tempFilePath)
+	}()
+	var times []int64
+	var values []int32
+	for i := 1; i <= 2000; i++ {
+		times = append(times, int64(i))
+		values = append(values, int32((7*(i-1))%13))
+	}
+	for i, v := range signature {
+		values[500+i], values[1000+i], values[1500+i] = v+100, v, v*2+5
+	}
+	if err := prepareSeriesTsFile(tempFilePath, times, map[string][]int32{"s0": values}); err != nil {
+		t.Fatal(err)
+	}
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer engine.Close()
+
+	pattern := make([]float64, len(signature))
+	for i, v := range signature {
+		pattern[i] = float64(v)
+	}
+	checkMatches := func(q *query.SimilarityQuery, startTimes []int64) {
+		matches, err := engine.Search(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != len(startTimes) {
+			t.Fatal(fmt.Sprintf("Expected %d matches got %d", len(startTimes), len(matches)))
+		}
+		for i, match := range matches {
+			if match.StartTime != startTimes[i] || match.EndTime != startTimes[i]+9 || match.Distance > 1e-6 {
+				t.Fatal(fmt.Sprintf("Expected a match at %d got %+v", startTimes[i], match))
+			}
+		}
+	}
+
+	// z-normalized, every occurrence matches whatever its offset and amplitude, and no page is pruned
+	_, pagesBefore := engine.readCounts()
+	q := query.NewSimilarityQuery("root.d0.s0", pattern, constant.EUCLIDEAN, 3)
+	matches, err := engine.Search(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, pagesAfter := engine.readCounts(); pagesAfter-pagesBefore != 40 {
+		t.Fatal(fmt.Sprintf("Expected 40 pages read got %d", pagesAfter-pagesBefore))
+	}
+	found := map[int64]bool{}
+	for _, match := range matches {
+		found[match.StartTime] = match.Distance < 1e-6
+	}
+	if len(matches) != 3 || !found[501] || !found[1001] || !found[1501] {
+		t.Fatal(fmt.Sprintf("Expected the 3 occurrences got %v", matches))
+	}
+	q.Measure, q.K = constant.DTW, 2
+	matches, err = engine.Search(q)
+	if err != nil || len(matches) != 2 || matches[0].Distance > 1e-6 || matches[1].Distance > 1e-6 {
+		t.Fatal(fmt.Sprintf("Expected 2 exact DTW matches got %v %v", matches, err))
+	}
+
+	// as it is, only the occurrence at 1001 matches exactly, and the pages far from the pattern are pruned
+	_, pagesBefore = engine.readCounts()
+	q = &query.SimilarityQuery{Path: "root.d0.s0", Pattern: pattern, Measure: constant.EUCLIDEAN, K: 1}
+	checkMatches(q, []int64{1001})
+	if _, pagesAfter := engine.readCounts(); pagesAfter-pagesBefore >= 40 {
+		t.Fatal(fmt.Sprintf("Expected pages to be pruned got %d read", pagesAfter-pagesBefore))
+	}
+	q.Measure, q.Band = constant.DTW, 2
+	checkMatches(q, []int64{1001})
+
+	// the windows outside the time range are left out
+	q.TimeFilter = &operator.LongGtEqFilter{Ref: 1002}
+	q.Normalize = true
+	checkMatches(q, []int64{1501})
+
+	if _, err := engine.Search(query.NewSimilarityQuery("root.d0.s9", pattern, constant.EUCLIDEAN, 1)); err == nil {
+		t.Fatal("Expected an error for a missing series")
+	}
+	if _, err := engine.Search(query.NewSimilarityQuery("root.d0.s0", pattern[:1], constant.EUCLIDEAN, 1)); err == nil {
+		t.Fatal("Expected an error for a single point pattern")
+	}
+
+	// a closer window overlapping the best match so far does not leave out a later match that overlaps neither
+	overlapsPath := tempFilePath + ".overlaps"
+	defer os.Remove(overlapsPath)
+	if err := prepareSeriesTsFile(overlapsPath, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8},
+		map[string][]int32{"s0": {9, 9, 9, 1, 1, 0, 0, 1, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	f = new(read.TsFileSequenceReader)
+	f.Open(overlapsPath)
+	overlapsEngine := new(Engine)
+	overlapsEngine.Open(f)
+	defer overlapsEngine.Close()
+	matches, err = overlapsEngine.Search(&query.SimilarityQuery{Path: "root.d0.s0", Pattern: []float64{0, 0, 0},
+		Measure: constant.EUCLIDEAN, K: 2})
+	if err != nil || len(matches) != 2 || matches[0].StartTime != 4 || matches[1].StartTime != 1 ||
+		math.Abs(matches[1].Distance-math.Sqrt(163)) > 1e-6 {
+		t.Fatal(fmt.Sprintf("Expected matches at 4 and 1 got %v %v", matches, err))
+	}
+}
+
+func TestEngineOpenReaderAt(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(tempFilePath)
+	os.Remove(tempFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a TsFile in memory, and one embedded in a larger blob
+	blob := append(append([]byte("header of the archive"), data...), []byte("trailer")...)
+	inMemory := new(read.TsFileSequenceReader)
+	inMemory.OpenBytes(data)
+	embedded := new(read.TsFileSequenceReader)
+	embedded.OpenReaderAt(io.NewSectionReader(bytes.NewReader(blob), 21, int64(len(data))), int64(len(data)))
+	for _, f := range []*read.TsFileSequenceReader{inMemory, embedded} {
+		if f.ReadHeadMagic() != conf.MAGIC_STRING || f.ReadTailMagic() != conf.MAGIC_STRING {
+			t.Fatal("Expected the magic string at both ends")
+		}
+		engine := new(Engine)
+		engine.Open(f)
+		results := aggregate(engine, "root.d0.s0", []constant.AggregationType{constant.COUNT, constant.MAX_VALUE}, nil,
+			t)
+		if results[0] != int64(100) || results[1] != int32(100) {
+			t.Fatal(fmt.Sprintf("Expected 100 points up to 100 got %v", results))
+		}
+		exp := new(query.QueryExpression)
+		exp.SetSelectPaths([]string{"root.d0.s1"})
+		exp.SetFilter(filter.NewRowRecordValFilter("root.d0.s0", &operator.IntGtEqFilter{100}))
+		exp.SetConditionPaths([]string{"root.d0.s0"})
+		rows := collectRows(engine.Query(exp), t)
+		if len(rows) != 1 || rows[0][0] != int64(100) || rows[0][1] != 50.0 {
+			t.Fatal(fmt.Sprintf("Expected the last point of s1 got %v", rows))
+		}
+		engine.Close()
+	}
+}
+
+// limitedWriter fails once n bytes are written.
+func TestEngineDescending(t *testing.T) {
+	err := prepareAggregationTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	paths := []string{"root.d0.s0", "root.d0.s1"}
+	// every query must return the rows of the ascending query in reverse
+	newExps := []func() *query.QueryExpression{
+		func() *query.QueryExpression {
+			exp := new(query.QueryExpression)
+			exp.SetSelectPaths(paths)
+			return exp
+		},
+		func() *query.QueryExpression {
+			exp := new(query.QueryExpression)
+			exp.SetSelectPaths(paths)
+			exp.SetFilter(&filter.RowRecordTimeFilter{&operator.LongGtEqFilter{55}})
+			return exp
+		},
+		func() *query.QueryExpression {
+			exp := new(query.QueryExpression)
+			exp.SetSelectPaths([]string{"root.d0.s1"})
+			exp.SetConditionPaths([]string{"root.d0.s0"})
+			exp.SetFilter(filter.NewRowRecordValFilter("root.d0.s0", &operator.IntGtEqFilter{37}))
+			return exp
+		},
+		func() *query.QueryExpression {
+			exp := new(query.QueryExpression)
+			exp.SetSelectPaths(paths)
+			exp.SetAggregations([]constant.AggregationType{constant.COUNT, constant.LAST_VALUE})
+			exp.SetGroupBy(query.NewGroupBy(-4, 110, 25))
+			return exp
+		},
+	}
+	for i, newExp := range newExps {
+		ascending := collectRows(engine.Query(newExp()), t)
+		exp := newExp()
+		exp.SetDescending(true)
+		descending := collectRows(engine.Query(exp), t)
+		if len(ascending) == 0 || len(ascending) != len(descending) {
+			t.Fatal(fmt.Sprintf("Query %d: expected %d rows got %d", i, len(ascending), len(descending)))
+		}
+		for j := range ascending {
+			expected, row := ascending[len(ascending)-1-j], descending[j]
+			for k := range expected {
+				if expected[k] != row[k] {
+					t.Fatal(fmt.Sprintf("Query %d: expected %v got %v", i, expected, row))
+				}
+			}
+		}
+	}
+
+	// the last 2 pages are jumped over, then 3 rows of the 8th page are skipped
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetDescending(true)
+	exp.SetRowLimit(5, 23)
+	if _, offset := engine.skippingQuerySet(exp); offset != 3 {
+		t.Fatal(fmt.Sprintf("Expected 3 rows left to skip got %d", offset))
+	}
+	var expected [][]interface{}
+	for i := int64(77); i > 72; i-- {
+		expected = append(expected, []interface{}{i, int32(i)})
+	}
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s0"}, expected, t)
+}
+
+func TestEngineDescendingFill(t *testing.T) {
+	err := prepareTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := new(read.TsFileSequenceReader)
+	f.Open(tempFilePath)
+	engine := new(Engine)
+	engine.Open(f)
+	defer func() {
+		engine.Close()
+		os.Remove(tempFilePath)
+	}()
+
+	// the same rows as in TestEngineFill, from the latest to the earliest
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths(series)
+	exp.SetFill(series[0], query.NewPreviousFill(0))
+	exp.SetFill(series[1], query.NewLinearFill(0))
+	exp.SetFill(series[2], query.NewConstantFill(int32(-1)))
+	exp.SetDescending(true)
+	expected := [][]interface{}{
+		{int64(6), int32(5), int32(1), int32(-1)},
+		{int64(5), int32(5), int32(2), int32(5)},
+		{int64(4), int32(4), int32(3), int32(4)},
+		{int64(3), int32(3), int32(4), int32(3)},
+		{int64(2), int32(2), int32(4), int32(-1)},
+		{int64(1), int32(1), int32(5), int32(-1)},
+	}
+	checkAlignedRows(engine.Query(exp), series, expected, t)
+}
+
+func checkAlignedRows(dataSet dataset.IQueryDataSet, paths []string, expected [][]interface{}, t *testing.T) {
+	cnt := 0
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPath(paths, record.Paths(), t)
+		if cnt >= len(expected) {
+			t.Fatal(fmt.Sprintf("Unexpected row %v", record))
+		}
+		if record.Timestamp() != expected[cnt][0] {
+			t.Fatal(fmt.Sprintf("Expected %v got %v", expected[cnt], record))
+		}
+		for i, value := range record.Values() {
+			if value != expected[cnt][i+1] {
+				t.Fatal(fmt.Sprintf("Expected %v got %v", expected[cnt], record))
+			}
+		}
+		cnt++
+	}
+	if cnt != len(expected) {
+		t.Fatal(fmt.Sprintf("Expected %d rows got %d", len(expected), cnt))
+	}
+}
+
+func checkPath(pathA []string, pathB []string, t *testing.T) {
+	if len(pathA) != len(pathB) {
+		t.Fatal("SelectPaths not consistent")
+	}
+	for i, _ := range pathA {
+		if pathA[i] != pathB[i] {
+			t.Fatal("SelectPaths not consistent")
+		}
+	}
+}
+
+// baselineTsFile was written before the time ranges and the statistics of chunks were recorded, and before the page
+// statistics were serialized min first: root.d0.s0 (INT32) and root.d0.s1 (TEXT) hold [1,1], [2,2], ..., [10,10] and
+// [1,"a"], [2,"b"], ..., [10,"j"] in a single page.
+const baselineTsFile = "" +
+	"547346696c6576302e382e3000000007726f6f742e643000" +
+	"000000000001470000000200000002733000000075000100" +
+	"000001000000000000000000000000000000410000004100" +
+	"00000a000000000000000a00000000000000010000000a00" +
+	"000001000000010000000a404b8000000000001800000009" +
+	"000000000000000000000001000000000000000101000000" +
+	"020000000300000004000000050000000600000007000000" +
+	"08000000090000000a000000000000027331000000830005" +
+	"000000010000000000000000000000000000004b0000004b" +
+	"0000000a000000000000000a000000000000000100000001" +
+	"6a00000001610000000161000000016a0000000000000000" +
+	"180000000900000000000000000000000100000000000000" +
+	"010100000061010000006201000000630100000064010000" +
+	"006501000000660100000067010000006801000000690100" +
+	"00006a0000000100000007726f6f742e6430000000000000" +
+	"000000000000000000000000000100000007726f6f742e64" +
+	"300000000000000147000000000000000c00000002000000" +
+	"0273300000000000000023000000000000000a0000000000" +
+	"000091000000000000000000000000000000000000000500" +
+	"0000096d61785f76616c7565000000040000000000000009" +
+	"6d696e5f76616c75650000000400000000000000046c6173" +
+	"7400000004000000000000000373756d0000000800000000" +
+	"000000000000000566697273740000000400000000000000" +
+	"02733100000000000000b4000000000000000a0000000000" +
+	"00009f000000000000000000000000000000000000000500" +
+	"0000096d696e5f76616c756500000000000000046c617374" +
+	"000000000000000373756d00000008000000000000000000" +
+	"000005666972737400000000000000096d61785f76616c75" +
+	"650000000000000002010000000273300100010100000002" +
+	"733101000500000003000000000000000000000000000000" +
+	"0000000000000000000000000000000000000000018f5473" +
+	"46696c6576302e382e30"
+
+func TestEngineBaselineFile(t *testing.T) {
+	data, err := hex.DecodeString(baselineTsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := new(read.TsFileSequenceReader)
+	if err := f.OpenBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	if err := engine.Open(f); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	// the chunks are not left out by their time range
+	exp, err := engine.Parse("SELECT s0, s1 FROM root.d0 WHERE time >= 9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAlignedRows(engine.Query(exp), []string{"root.d0.s0", "root.d0.s1"},
+		[][]interface{}{{int64(9), int32(9), "i"}, {int64(10), int32(10), "j"}}, t)
+
+	// nor aggregated from their statistics
+	aggregations := []constant.AggregationType{constant.COUNT, constant.MIN_TIME, constant.MAX_TIME,
+		constant.FIRST_VALUE, constant.LAST_VALUE}
+	checkAggregation([]interface{}{int64(10), int64(1), int64(10), int32(1), int32(10)},
+		aggregate(engine, "root.d0.s0", aggregations, nil, t), t)
+	checkAggregation([]interface{}{int64(8), int64(3), int64(10), "c", "j"},
+		aggregate(engine, "root.d0.s1", aggregations, &operator.LongGtEqFilter{Ref: 3}, t), t)
+	if last := engine.Last([]string{"root.d0.s1"}); last[0] == nil || last[0].Timestamp != 10 ||
+		last[0].Value != "j" {
+		t.Fatal(fmt.Sprintf("Expected [10, j] got %v", last[0]))
+	}
+
+	// its page statistics, written max before min, leave no page out by its value range
+	for _, c := range []struct {
+		sql      string
+		expected [][]interface{}
+	}{
+		{"SELECT s0 FROM root.d0 WHERE s0 > 8", [][]interface{}{{int64(9), int32(9)}, {int64(10), int32(10)}}},
+		{"SELECT s0 FROM root.d0 WHERE s1 <= 'b'", [][]interface{}{{int64(1), int32(1)}, {int64(2), int32(2)}}},
+	} {
+		exp, err := engine.Parse(c.sql)
+		if err != nil {
+			t.Fatal(fmt.Sprintf("%s: %v", c.sql, err))
+		}
+		checkAlignedRows(engine.Query(exp), []string{"root.d0.s0"}, c.expected, t)
+	}
+	// and aggregate as those written min first
+	aggregations = []constant.AggregationType{constant.MIN_VALUE, constant.MAX_VALUE}
+	checkAggregation([]interface{}{int32(1), int32(10)},
+		aggregate(engine, "root.d0.s0", aggregations, &operator.LongLtEqFilter{Ref: 100}, t), t)
+	checkAggregation([]interface{}{"a", "j"},
+		aggregate(engine, "root.d0.s1", aggregations, &operator.LongLtEqFilter{Ref: 100}, t), t)
+	written, err := prepareEncodingsTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f = new(read.TsFileSequenceReader)
+	if err := f.OpenBytes(written); err != nil {
+		t.Fatal(err)
+	}
+	current := new(Engine)
+	if err := current.Open(f); err != nil {
+		t.Fatal(err)
+	}
+	defer current.Close()
+	checkAggregation([]interface{}{int32(1), int32(900)},
+		aggregate(current, "root.d0.i0", aggregations, &operator.LongLtEqFilter{Ref: 100}, t), t)
+}
+
+// encodingSeries are the sensors of root.d0 in the file of prepareEncodingsTsFile, one per encoding of every data type.
+var encodingSeries = []struct {
+	sensor      string
+	dataType    constant.TSDataType
+	encoding    constant.TSEncoding
+	compression constant.CompressionType
+}{
+	{"b0", constant.BOOLEAN, constant.PLAIN, constant.UNCOMPRESSED},
+	{"i0", constant.INT32, constant.RLE, constant.UNCOMPRESSED},
+	{"i1", constant.INT32, constant.TS_2DIFF, constant.SNAPPY},
+	{"i2", constant.INT32, constant.PLAIN, constant.UNCOMPRESSED},
+	{"l0", constant.INT64, constant.RLE, constant.SNAPPY},
+	{"l1", constant.INT64, constant.TS_2DIFF, constant.UNCOMPRESSED},
+	{"f0", constant.FLOAT, constant.RLE, constant.UNCOMPRESSED},
+	{"f1", constant.FLOAT, constant.TS_2DIFF, constant.UNCOMPRESSED},
+	{"f2", constant.FLOAT, constant.GORILLA, constant.SNAPPY},
+	{"d0", constant.DOUBLE, constant.RLE, constant.UNCOMPRESSED},
+	{"d1", constant.DOUBLE, constant.TS_2DIFF, constant.SNAPPY},
+	{"d2", constant.DOUBLE, constant.GORILLA, constant.UNCOMPRESSED},
+	{"t0", constant.TEXT, constant.PLAIN, constant.SNAPPY},
+}
+
+func prepareEncodingsTsFile() ([]byte, error) {
+	/*
+		Assumed data layout, 10 points per page:
+		root.d0.<every sensor of encodingSeries> : [1,v1], [2,v2], ..., [30,v30]
+	*/
+	pagePoints := conf.MaxNumberOfPointsInPage
+	conf.MaxNumberOfPointsInPage = 10
+	defer func() {
+		conf.MaxNumberOfPointsInPage = pagePoints
+	}()
+
+	buf := new(bytes.Buffer)
+	writer, err := tsFileWriter.NewTsFileWriterTo(buf)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range encodingSeries {
+		des, _ := sensorDescriptor.NewWithCompress(s.sensor, s.dataType, s.encoding, s.compression)
+		if err := writer.AddSensor(des); err != nil {
+			return nil, err
+		}
+	}
+	for t := int64(1); t <= 30; t++ {
+		record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
+		for _, s := range encodingSeries {
+			var pt *tsFileWriter.DataPoint
+			switch s.dataType {
+			case constant.BOOLEAN:
+				pt, _ = tsFileWriter.NewBool(s.sensor, s.dataType, t%3 == 0)
+			case constant.INT32:
+				pt, _ = tsFileWriter.NewInt(s.sensor, s.dataType, int32(t*t))
+			case constant.INT64:
+				pt, _ = tsFileWriter.NewLong(s.sensor, s.dataType, t*1000)
+			case constant.FLOAT:
+				pt, _ = tsFileWriter.NewFloat(s.sensor, s.dataType, float32(t)*1.5)
+			case constant.DOUBLE:
+				pt, _ = tsFileWriter.NewDouble(s.sensor, s.dataType, float64(t)*0.25)
+			case constant.TEXT:
+				pt, _ = tsFileWriter.NewString(s.sensor, s.dataType, fmt.Sprintf("v%d", t))
+			}
+			record.AddTuple(pt)
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// countRows returns the number of rows of a data set and the first error it returned.
+func countRows(dataSet dataset.IQueryDataSet) (int, error) {
+	cnt := 0
+	for dataSet.HasNext() {
+		if _, err := dataSet.Next(); err != nil {
+			return cnt, err
+		}
+		cnt++
+	}
+	return cnt, nil
+}
+
+func TestEngineCorruptedFile(t *testing.T) {
+	data, err := prepareEncodingsTsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every encoding of every data type reads back
+	f := new(read.TsFileSequenceReader)
+	if err := f.OpenBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	engine := new(Engine)
+	if err := engine.Open(f); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range encodingSeries {
+		exp := new(query.QueryExpression)
+		exp.SetSelectPaths([]string{"root.d0." + s.sensor})
+		rows := collectRows(engine.Query(exp), t)
+		if len(rows) != 30 || rows[29][0] != int64(30) {
+			t.Fatal(fmt.Sprintf("Expected 30 points of %s got %v", s.sensor, rows))
+		}
+	}
+	engine.Close()
+
+	var corrupted *utils.CorruptedError
+	if err := new(read.TsFileSequenceReader).OpenBytes([]byte("not a TsFile at all")); !errors.Is(err, read.ErrNotTsFile) {
+		t.Fatal(fmt.Sprintf("Expected ErrNotTsFile got %v", err))
+	}
+	if err := new(read.TsFileSequenceReader).OpenBytes(data[:len(data)-10]); !errors.As(err, &corrupted) ||
+		corrupted.Structure != "tail magic" {
+		t.Fatal(fmt.Sprintf("Expected a missing tail magic got %v", err))
+	}
+	truncatedMetadata := append(append([]byte{}, data[:len(data)-len(conf.MAGIC_STRING)-4]...), 0, 0, 0, 1)
+	truncatedMetadata = append(truncatedMetadata, []byte(conf.MAGIC_STRING)...)
+	f = new(read.TsFileSequenceReader)
+	if err := f.OpenBytes(truncatedMetadata); err != nil {
+		t.Fatal(err)
+	}
+	if err := new(Engine).Open(f); !errors.As(err, &corrupted) || corrupted.Structure != "FileMetaData" {
+		t.Fatal(fmt.Sprintf("Expected corrupted metadata got %v", err))
+	}
+
+	// locate the chunk of i0 and the first page of l1
+	f = new(read.TsFileSequenceReader)
+	f.OpenBytes(data)
+	fileMeta, err := f.ReadFileMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunkOffsets := make(map[string]int64)
+	for _, rowGroup := range fileMeta.DeviceMap()["root.d0"].GetRowGroups() {
+		for _, chunk := range rowGroup.GetChunkMetaDataSli() {
+			chunkOffsets[chunk.Sensor()] = chunk.FileOffsetOfCorrespondingData()
+		}
+	}
+	if _, err := f.ReadChunkHeaderAt(chunkOffsets["l1"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.ReadPageHeader(constant.INT64); err != nil {
+		t.Fatal(err)
+	}
+	pageOffset := f.Pos()
+	f.Close()
+
+	// an unknown encoding in the chunk header of i0, and a time stream length overflowing in the page of l1
+	corruptedData := append([]byte{}, data...)
+	encodingPos := chunkOffsets["i0"] + int64(constant.INT_LEN+len("i0")+constant.INT_LEN+constant.SHORT_LEN+
+		constant.INT_LEN+constant.SHORT_LEN)
+	corruptedData[encodingPos], corruptedData[encodingPos+1] = 0x7f, 0x7f
+	for i := int64(0); i < 5; i++ {
+		corruptedData[pageOffset+i] = 0xff
+	}
+	f = new(read.TsFileSequenceReader)
+	if err := f.OpenBytes(corruptedData); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.ReadChunkHeaderAt(chunkOffsets["i0"]); !errors.As(err, &corrupted) ||
+		corrupted.Structure != "ChunkHeader" || corrupted.Offset != chunkOffsets["i0"] {
+		t.Fatal(fmt.Sprintf("Expected a corrupted chunk header at %d got %v", chunkOffsets["i0"], err))
+	}
+	engine = new(Engine)
+	if err := engine.Open(f); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.i0"})
+	if cnt, err := countRows(engine.Query(exp)); cnt != 0 || err != nil {
+		t.Fatal(fmt.Sprintf("Expected the corrupted chunk to be skipped got %d rows, %v", cnt, err))
+	}
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.l1"})
+	if _, err := countRows(engine.Query(exp)); !errors.As(err, &corrupted) || corrupted.Structure != "page" ||
+		corrupted.Offset != pageOffset {
+		t.Fatal(fmt.Sprintf("Expected a corrupted page at %d got %v", pageOffset, err))
+	}
+	if _, err := engine.Search(query.NewSimilarityQuery("root.d0.l1", []float64{0, 1}, constant.EUCLIDEAN,
+		1)); !errors.As(err, &corrupted) || corrupted.Offset != pageOffset {
+		t.Fatal(fmt.Sprintf("Expected the search to fail on the corrupted page at %d got %v", pageOffset, err))
+	}
+	// as do aggregations decoding them, while those taken from the statistics of the chunks do not read them
+	aggregations := []constant.AggregationType{constant.COUNT}
+	if _, err := engine.Aggregate("root.d0.l1", aggregations, &operator.LongLtEqFilter{Ref: 1}); !errors.As(err,
+		&corrupted) || corrupted.Offset != pageOffset {
+		t.Fatal(fmt.Sprintf("Expected the aggregation to fail on the corrupted page at %d got %v", pageOffset, err))
+	}
+	if _, err := engine.Aggregate("root.d0.i0", aggregations, &operator.LongLtEqFilter{Ref: 1}); !errors.As(err,
+		&corrupted) || corrupted.Structure != "ChunkHeader" {
+		t.Fatal(fmt.Sprintf("Expected the aggregation to fail on the corrupted chunk header got %v", err))
+	}
+	if results := aggregate(engine, "root.d0.l1", aggregations, nil, t); results[0] != int64(30) {
+		t.Fatal(fmt.Sprintf("Expected 30 points got %v", results))
+	}
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.l1"})
+	exp.SetAggregations(aggregations)
+	exp.SetGroupBy(query.NewGroupBy(0, 100, 1))
+	if _, err := countRows(engine.Query(exp)); !errors.As(err, &corrupted) || corrupted.Offset != pageOffset {
+		t.Fatal(fmt.Sprintf("Expected the windows to fail on the corrupted page at %d got %v", pageOffset, err))
+	}
+	// the other series are unaffected
+	exp = new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.d2"})
+	if cnt, err := countRows(engine.Query(exp)); cnt != 30 || err != nil {
+		t.Fatal(fmt.Sprintf("Expected 30 points got %d, %v", cnt, err))
+	}
+
+	// a page that cannot be read is reported by the next call to Next rather than ending the series
+	seriesReader := basic.NewSeriesReader([]int64{int64(len(corruptedData))}, []int{100}, nil,
+		[]constant.TSEncoding{constant.PLAIN}, f, constant.INT32, false)
+	if !seriesReader.HasNext() {
+		t.Fatal("Expected the unreadable page to be reported")
+	}
+	if _, err := seriesReader.Next(); !errors.As(err, &corrupted) || corrupted.Offset != int64(len(corruptedData)) {
+		t.Fatal(fmt.Sprintf("Expected a truncated page at %d got %v", len(corruptedData), err))
+	}
+	if seriesReader.HasNext() {
+		t.Fatal("Expected no point after the unreadable page")
+	}
+}
+
+// The work done on a fuzzed input: the series queried and the points read from all of them.
+const (
+	fuzzPaths = 20
+	fuzzWork  = 10000
+)
+
+// fuzzedPaths returns the series of a fuzzed file.
+func fuzzedPaths(fileMeta *metadata.FileMetaData) []string {
+	var paths []string
+	for deviceId := range fileMeta.DeviceMap() {
+		for sensorId := range fileMeta.TimeSeriesMetadataMap() {
+			paths = append(paths, deviceId+"."+sensorId)
+		}
+	}
+	return paths
+}
+
+func FuzzEngineOpenBytes(f *testing.F) {
+	data, err := prepareEncodingsTsFile()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	for _, size := range []int{len(data) / 4, len(data) / 2, len(data) - 5} {
+		f.Add(data[:size])
+	}
+	for _, pos := range []int{len(conf.MAGIC_STRING) + 3, len(data) / 3, len(data) / 2, len(data) - 40} {
+		flipped := append([]byte{}, data...)
+		flipped[pos] ^= 0xff
+		f.Add(flipped)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := new(read.TsFileSequenceReader)
+		if err := reader.OpenBytes(data); err != nil {
+			return
+		}
+		engine := new(Engine)
+		if err := engine.Open(reader); err != nil {
+			return
+		}
+		defer engine.Close()
+		paths := fuzzedPaths(engine.fileMeta)
+		if len(paths) > fuzzPaths {
+			paths = paths[:fuzzPaths]
+		}
+		// the rows read from all the series, corrupted pages decoding to up to 8 points per byte
+		rows := 0
+		for _, path := range paths {
+			exp := new(query.QueryExpression)
+			exp.SetSelectPaths([]string{path})
+			dataSet := engine.Query(exp)
+			for ; rows < fuzzWork && dataSet.HasNext(); rows++ {
+				if _, err := dataSet.Next(); err != nil {
+					break
+				}
+			}
+			dataSet.Close()
+			engine.Aggregate(path, []constant.AggregationType{constant.COUNT, constant.MAX_VALUE}, nil)
+		}
+		engine.Last(paths)
+	})
+}
+
+func FuzzEngineHeaders(f *testing.F) {
+	data, err := prepareEncodingsTsFile()
+	if err != nil {
+		f.Fatal(err)
+	}
+	metadataSize := int(binary.BigEndian.Uint32(data[len(data)-len(conf.MAGIC_STRING)-4:]))
+	metadataPos := len(data) - len(conf.MAGIC_STRING) - 4 - metadataSize
+	f.Add(data[metadataPos : metadataPos+metadataSize])
+	f.Add(data[len(conf.MAGIC_STRING):metadataPos])
+	f.Add(data[metadataPos : metadataPos+metadataSize/2])
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		new(metadata.FileMetaData).Deserialize(data)
+		reader := utils.NewReaderAtFileReader(bytes.NewReader(data), int64(len(data)))
+		new(header.RowGroupHeader).Deserialize(reader)
+		reader.Seek(0, io.SeekStart)
+		new(header.ChunkHeader).Deserialize(reader)
+		for dataType := constant.BOOLEAN; dataType <= constant.TEXT; dataType++ {
+			reader.Seek(0, io.SeekStart)
+			new(header.PageHeader).Deserialize(reader, dataType)
+		}
+	})
 }
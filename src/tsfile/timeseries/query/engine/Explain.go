@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"tsfile/common/utils"
+	"tsfile/timeseries/filter"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/dataset"
+)
+
+// Plan describes how a query was run, see Engine.Explain.
+type Plan struct {
+	// DataSets names the data set implementations producing the rows from the outermost one, e.g.
+	// [LimitQueryDataSet TimestampQueryDataSet]
+	DataSets []string
+	// the select and condition paths after path patterns are expanded
+	SelectPaths    []string
+	ConditionPaths []string
+	// Paths describes the pages of every select and condition path. It is empty for aggregation queries, which read
+	// the statistics of chunks and pages instead wherever they can.
+	Paths []*PathPlan
+	Stats ExecutionStats
+	// Err is the error that ended the query early, if any
+	Err error
+}
+
+// PathPlan counts the chunks and pages of a path that are read and those left out since they cannot match the filter
+// of the query. The pages of the chunks left out are not counted, their headers are not even read.
+type PathPlan struct {
+	Path         string
+	ChunksRead   int
+	ChunksPruned int
+	PagesRead    int
+	PagesPruned  int
+	// BytesToRead is the size of the pages read as stored in the file, i.e. compressed
+	BytesToRead int64
+}
+
+func (p *PathPlan) addChunk(read bool) {
+	if p == nil {
+		return
+	}
+	if read {
+		p.ChunksRead++
+	} else {
+		p.ChunksPruned++
+	}
+}
+
+func (p *PathPlan) addPage(read bool, size int64) {
+	if p == nil {
+		return
+	}
+	if read {
+		p.PagesRead++
+		p.BytesToRead += size
+	} else {
+		p.PagesPruned++
+	}
+}
+
+// ExecutionStats measures the work done to run a query.
+type ExecutionStats struct {
+	Rows int64
+	// BytesRead counts the bytes read from the files, headers included
+	BytesRead int64
+	// PagesDecoded counts the pages whose data was read
+	PagesDecoded int64
+	// ValuesFiltered counts the rows checked against the filter of the query and rejected
+	ValuesFiltered int64
+}
+
+func (p *Plan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "data sets: %s\n", strings.Join(p.DataSets, " > "))
+	fmt.Fprintf(&b, "select paths: %s\n", strings.Join(p.SelectPaths, ", "))
+	fmt.Fprintf(&b, "condition paths: %s\n", strings.Join(p.ConditionPaths, ", "))
+	for _, path := range p.Paths {
+		fmt.Fprintf(&b, "%s: %d chunks read, %d pruned, %d pages read (%d bytes), %d pruned\n", path.Path,
+			path.ChunksRead, path.ChunksPruned, path.PagesRead, path.BytesToRead, path.PagesPruned)
+	}
+	fmt.Fprintf(&b, "rows: %d, bytes read: %d, pages decoded: %d, values filtered: %d", p.Stats.Rows,
+		p.Stats.BytesRead, p.Stats.PagesDecoded, p.Stats.ValuesFiltered)
+	if p.Err != nil {
+		fmt.Fprintf(&b, "\nerror: %v", p.Err)
+	}
+	return b.String()
+}
+
+// countingFilter counts the rows its filter rejects, leaving the pruning of pages to it.
+type countingFilter struct {
+	filter.Filter
+	rejected int64
+}
+
+func (f *countingFilter) Satisfy(val interface{}) bool {
+	if f.Filter.Satisfy(val) {
+		return true
+	}
+	f.rejected++
+	return false
+}
+
+func (f *countingFilter) MayMatch(path string, minTime int64, maxTime int64, minValue interface{},
+	maxValue interface{}) bool {
+	return filter.MayMatch(f.Filter, path, minTime, maxTime, minValue, maxValue)
+}
+
+func (f *countingFilter) SatisfyAny(min interface{}, max interface{}) bool {
+	return filter.SatisfyAny(f.Filter, min, max)
+}
+
+func (f *countingFilter) SatisfyAll(min interface{}, max interface{}) bool {
+	return filter.SatisfyAll(f.Filter, min, max)
+}
+
+// Explain runs exp to its end as Query does, discarding the rows, and describes how it was run: the data sets chosen,
+// the paths after expansion, the chunks and pages of every path read and pruned, and the work actually done. The pages
+// are counted from their headers after the query has run, so that reading them is not part of the statistics.
+func (e *Engine) Explain(exp *query.QueryExpression) *Plan {
+	plan := new(Plan)
+	// exp is left unchanged, the paths reported are those the copy run is resolved to
+	resolved := *exp
+	pageFilter := exp.Filter()
+	var counting *countingFilter
+	if pageFilter != nil {
+		counting = &countingFilter{Filter: pageFilter}
+		resolved.SetFilter(counting)
+	}
+
+	bytesRead, pagesRead := e.readCounts()
+	dataSet := e.runQuery(&resolved)
+	for set := dataSet; ; {
+		plan.DataSets = append(plan.DataSets, reflect.TypeOf(set).Elem().Name())
+		wrapper, ok := set.(interface{ Inner() dataset.IQueryDataSet })
+		if !ok {
+			break
+		}
+		set = wrapper.Inner()
+	}
+	for dataSet.HasNext() {
+		if _, err := dataSet.Next(); err != nil {
+			plan.Err = err
+			break
+		}
+		plan.Stats.Rows++
+	}
+	dataSet.Close()
+	bytesReadAfter, pagesReadAfter := e.readCounts()
+	plan.Stats.BytesRead = bytesReadAfter - bytesRead
+	plan.Stats.PagesDecoded = pagesReadAfter - pagesRead
+	if counting != nil {
+		plan.Stats.ValuesFiltered = counting.rejected
+	}
+
+	plan.SelectPaths = resolved.SelectPaths()
+	plan.ConditionPaths = resolved.ConditionPaths()
+	if !exp.IsAggregation() {
+		for _, path := range utils.MergeStrings(plan.SelectPaths, plan.ConditionPaths) {
+			plan.Paths = append(plan.Paths, e.planPath(path, pageFilter))
+		}
+	}
+	return plan
+}
+
+// readCounts returns the bytes and the pages read from the file (or files) so far.
+func (e *Engine) readCounts() (bytesRead int64, pagesRead int64) {
+	if len(e.files) == 0 {
+		return e.reader.BytesRead(), e.reader.PagesRead()
+	}
+	for _, file := range e.files {
+		fileBytes, filePages := file.readCounts()
+		bytesRead += fileBytes
+		pagesRead += filePages
+	}
+	return bytesRead, pagesRead
+}
+
+// planPath counts the chunks and pages of path read and pruned under pageFilter. The chunks of the files left out by
+// their time range are all pruned.
+func (e *Engine) planPath(path string, pageFilter filter.Filter) *PathPlan {
+	plan := &PathPlan{Path: path}
+	if len(e.files) == 0 {
+		e.collectPages(path, false, pageFilter, plan)
+		return plan
+	}
+	matching := make(map[*Engine]bool)
+	for _, file := range e.filesOf(path, pageFilter) {
+		matching[file] = true
+	}
+	for _, file := range e.files {
+		if matching[file] {
+			file.collectPages(path, false, timeRangeFilter{pageFilter}, plan)
+		} else {
+			plan.ChunksPruned += file.chunkCount(path)
+		}
+	}
+	return plan
+}
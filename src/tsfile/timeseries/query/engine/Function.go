@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"tsfile/common/constant"
+	"tsfile/file/metadata"
+	"tsfile/timeseries/filter"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/parser"
+	"tsfile/timeseries/query/udf"
+)
+
+// RegisterScalarFunction makes f callable by name in the expressions of the queries given to Parse, e.g.
+// power(s0, s1) * 2. The name must not be taken by an aggregation, a function of the query language or another
+// user-defined function.
+func (e *Engine) RegisterScalarFunction(name string, f udf.Scalar) error {
+	if parser.IsReserved(name) {
+		return fmt.Errorf("function name %s is reserved", name)
+	}
+	return e.functions.RegisterScalar(name, f)
+}
+
+// RegisterAggregateFunction makes the aggregate function created by newAggregate usable by name as an aggregation,
+// with or without group by, in the queries given to Parse and in QueryExpression.SetAggregateFunctions. The name must
+// be free as for RegisterScalarFunction. The series it is applied to are decoded point by point, since it cannot be
+// computed from the statistics of chunks and pages.
+func (e *Engine) RegisterAggregateFunction(name string, newAggregate func() udf.Aggregate) error {
+	if parser.IsReserved(name) {
+		return fmt.Errorf("function name %s is reserved", name)
+	}
+	return e.functions.RegisterAggregate(name, newAggregate)
+}
+
+// aggregateFunctions returns how to create the aggregate functions registered under names, nil for unknown ones.
+func (e *Engine) aggregateFunctions(names []string) []func() udf.Aggregate {
+	newFunctions := make([]func() udf.Aggregate, len(names))
+	for i, name := range names {
+		newAggregate, ok := e.functions.Aggregate(name)
+		if !ok {
+			log.Println(fmt.Sprintf("No such aggregate function : %s", name))
+		}
+		newFunctions[i] = newAggregate
+	}
+	return newFunctions
+}
+
+// functionTarget computes aggregate functions over the points of a series whose timestamps satisfy a filter, or all of
+// them if the filter is nil, per group by window if any. When the windows overlap and the sliding step divides their
+// interval, each point is added only once, to the pane a sliding step long it falls into, and the panes of every window
+// are merged afterwards.
+type functionTarget struct {
+	dataType     constant.TSDataType
+	newFunctions []func() udf.Aggregate
+	// panes split the time range into the ranges the functions are computed over, nil without group by
+	panes          *query.GroupBy
+	panesPerWindow int
+	// functions[i][j] is the j-th function over the points of the i-th pane, nil if it is unknown
+	functions  [][]udf.Aggregate
+	timeFilter filter.Filter
+}
+
+func newFunctionTarget(groupBy *query.GroupBy, dataType constant.TSDataType, newFunctions []func() udf.Aggregate,
+	timeFilter filter.Filter) *functionTarget {
+	t := &functionTarget{dataType: dataType, newFunctions: newFunctions, panes: groupBy, panesPerWindow: 1,
+		timeFilter: timeFilter}
+	paneCount := 1
+	if groupBy != nil {
+		if step := groupBy.Step(); step < groupBy.Interval && groupBy.Interval%step == 0 {
+			t.panes = query.NewGroupBy(groupBy.StartTime, groupBy.EndTime, step)
+			t.panesPerWindow = int(groupBy.Interval / step)
+		}
+		paneCount = t.panes.WindowCount()
+	}
+	t.functions = make([][]udf.Aggregate, paneCount)
+	for i := range t.functions {
+		t.functions[i] = t.newPane()
+	}
+	return t
+}
+
+func (t *functionTarget) newPane() []udf.Aggregate {
+	functions := make([]udf.Aggregate, len(t.newFunctions))
+	for i, newAggregate := range t.newFunctions {
+		if newAggregate != nil {
+			functions[i] = newAggregate()
+			functions[i].Init(t.dataType)
+		}
+	}
+	return functions
+}
+
+func (t *functionTarget) overlapping(minTime int64, maxTime int64) (first int, last int) {
+	if t.panes == nil {
+		return 0, 0
+	}
+	return overlappingWindows(t.panes, len(t.functions), minTime, maxTime)
+}
+
+func (t *functionTarget) accepts(minTime int64, maxTime int64, extended bool) (bool, bool) {
+	first, last := t.overlapping(minTime, maxTime)
+	some, _ := acceptsTimes(t.timeFilter, minTime, maxTime)
+	return first <= last && some, false
+}
+
+// updateFromStatistics is never called since no chunk or page is accepted entirely.
+func (t *functionTarget) updateFromStatistics(count int64, minTime int64, maxTime int64, min interface{},
+	max interface{}, first interface{}, last interface{}, sum float64, extended *metadata.ExtendedStatistics) {
+}
+
+func (t *functionTarget) update(timestamp int64, value interface{}) {
+	if t.timeFilter != nil && !t.timeFilter.Satisfy(timestamp) {
+		return
+	}
+	from, to := t.overlapping(timestamp, timestamp)
+	for i := from; i <= to; i++ {
+		for _, f := range t.functions[i] {
+			if f != nil {
+				f.Add(timestamp, value)
+			}
+		}
+	}
+}
+
+// results returns the result of every function in each of the windowCount windows.
+func (t *functionTarget) results(windowCount int) [][]interface{} {
+	results := make([][]interface{}, windowCount)
+	for i := range results {
+		window := t.functions[i]
+		if t.panesPerWindow > 1 {
+			window = t.newPane()
+			for j := i; j < i+t.panesPerWindow && j < len(t.functions); j++ {
+				for k, f := range window {
+					if f != nil {
+						f.Merge(t.functions[j][k])
+					}
+				}
+			}
+		}
+		results[i] = make([]interface{}, len(window))
+		for k, f := range window {
+			if f != nil {
+				results[i][k] = f.Result()
+			}
+		}
+	}
+	return results
+}
+
+// targets feeds a series to several targets, decoding the chunks and pages that any of them does not accept entirely.
+type targets []aggregationTarget
+
+func (ts targets) accepts(minTime int64, maxTime int64, extended bool) (bool, bool) {
+	anyTarget, allTargets := false, true
+	for _, t := range ts {
+		some, all := t.accepts(minTime, maxTime, extended)
+		anyTarget = anyTarget || some
+		allTargets = allTargets && (all || !some)
+	}
+	return anyTarget, anyTarget && allTargets
+}
+
+func (ts targets) updateFromStatistics(count int64, minTime int64, maxTime int64, min interface{}, max interface{},
+	first interface{}, last interface{}, sum float64, extended *metadata.ExtendedStatistics) {
+	for _, t := range ts {
+		if some, _ := t.accepts(minTime, maxTime, extended != nil); some {
+			t.updateFromStatistics(count, minTime, maxTime, min, max, first, last, sum, extended)
+		}
+	}
+}
+
+func (ts targets) update(timestamp int64, value interface{}) {
+	for _, t := range ts {
+		t.update(timestamp, value)
+	}
+}
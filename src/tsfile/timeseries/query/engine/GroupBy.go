@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"tsfile/common/constant"
+	"tsfile/file/metadata"
+	"tsfile/timeseries/filter"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/aggregation"
+	"tsfile/timeseries/query/dataset"
+	impl2 "tsfile/timeseries/query/dataset/impl"
+)
+
+// windowTarget aggregates the points of every group by window whose timestamps satisfy a filter, or all of them if
+// the filter is nil, into an aggregator of its own. A chunk or page is taken from its statistics only if each window it
+// overlaps contains it entirely, if the filter is satisfied by its whole time range and if the aggregators can use them.
+type windowTarget struct {
+	groupBy     *query.GroupBy
+	aggregators []*aggregation.Aggregator
+	timeFilter  filter.Filter
+}
+
+func newWindowTarget(groupBy *query.GroupBy, dataType constant.TSDataType, aggregations []constant.AggregationType,
+	timeFilter filter.Filter) *windowTarget {
+	aggregators := make([]*aggregation.Aggregator, groupBy.WindowCount())
+	for i := range aggregators {
+		aggregators[i] = aggregation.NewAggregatorFor(dataType, aggregations)
+	}
+	return &windowTarget{groupBy: groupBy, aggregators: aggregators, timeFilter: timeFilter}
+}
+
+// overlapping returns the indexes [first, last] of the windows overlapping [minTime, maxTime], first > last if none.
+func (t *windowTarget) overlapping(minTime int64, maxTime int64) (first int, last int) {
+	return overlappingWindows(t.groupBy, len(t.aggregators), minTime, maxTime)
+}
+
+// overlappingWindows returns the indexes [first, last] of the windows of g overlapping [minTime, maxTime], first >
+// last if none, given the number of windows.
+func overlappingWindows(g *query.GroupBy, windowCount int, minTime int64, maxTime int64) (first int, last int) {
+	if windowCount == 0 || maxTime < g.StartTime || minTime >= g.EndTime {
+		return 0, -1
+	}
+	step := g.Step()
+	// the last window starting no later than maxTime
+	last = int((maxTime - g.StartTime) / step)
+	if last >= windowCount {
+		last = windowCount - 1
+	}
+	// the first window ending after minTime
+	if minTime >= g.StartTime+g.Interval {
+		first = int((minTime-g.StartTime-g.Interval)/step) + 1
+	}
+	return first, last
+}
+
+func (t *windowTarget) accepts(minTime int64, maxTime int64, extended bool) (bool, bool) {
+	first, last := t.overlapping(minTime, maxTime)
+	some, all := acceptsTimes(t.timeFilter, minTime, maxTime)
+	if first > last || !some {
+		return false, false
+	}
+	if !all || !t.aggregators[first].CanUseStatistics(extended) {
+		return true, false
+	}
+	for i := first; i <= last; i++ {
+		start, end := t.groupBy.Window(i)
+		if minTime < start || maxTime >= end {
+			return true, false
+		}
+	}
+	return true, true
+}
+
+func (t *windowTarget) updateFromStatistics(count int64, minTime int64, maxTime int64, min interface{},
+	max interface{}, first interface{}, last interface{}, sum float64, extended *metadata.ExtendedStatistics) {
+	from, to := t.overlapping(minTime, maxTime)
+	for i := from; i <= to; i++ {
+		t.aggregators[i].UpdateFromStatistics(count, minTime, maxTime, min, max, first, last, sum)
+		if extended != nil {
+			t.aggregators[i].UpdateFromExtendedStatistics(extended)
+		}
+	}
+}
+
+func (t *windowTarget) update(timestamp int64, value interface{}) {
+	if t.timeFilter != nil && !t.timeFilter.Satisfy(timestamp) {
+		return
+	}
+	from, to := t.overlapping(timestamp, timestamp)
+	for i := from; i <= to; i++ {
+		t.aggregators[i].Update(timestamp, value)
+	}
+}
+
+// aggregationQuerySet computes the aggregations and the aggregate functions of every select path, per group by window
+// if there is one, over the points whose timestamps satisfy the filter of exp. The filter may only check timestamps,
+// a filter reading the values of series fails the query.
+func (e *Engine) aggregationQuerySet(exp *query.QueryExpression) dataset.IQueryDataSet {
+	var timeFilter filter.Filter
+	if f := exp.Filter(); f != nil {
+		if paths := filter.Paths(f); len(paths) > 0 {
+			return impl2.NewFailedQueryDataSet(fmt.Errorf("aggregations cannot be filtered by the values of %s",
+				strings.Join(paths, ", ")))
+		}
+		timeFilter = &rowTimeFilter{Filter: f}
+	}
+	paths := exp.SelectPaths()
+	aggregators := make([][]*aggregation.Aggregator, len(paths))
+	newFunctions := e.aggregateFunctions(exp.AggregateFunctions())
+	var functionResults [][][]interface{}
+	if len(newFunctions) > 0 {
+		functionResults = make([][][]interface{}, len(paths))
+	}
+	groupBy := exp.GroupBy()
+	timestamps := []int64{0}
+	if groupBy != nil {
+		timestamps = make([]int64, groupBy.WindowCount())
+		for i := range timestamps {
+			timestamps[i], _ = groupBy.Window(i)
+		}
+	}
+	// the aggregators and the functions are held for as long as the query
+	size := int64(len(paths)*len(timestamps)) *
+		(aggregation.Size(exp.Aggregations()) + int64(len(newFunctions))*aggregation.AggregatorSize)
+	if exp.Budget().Reserve(size) != nil {
+		return impl2.NewFunctionAggregationQueryDataSet(paths, exp.Aggregations(), exp.AggregateFunctions(), nil, nil,
+			nil)
+	}
+	for i, path := range paths {
+		dataType := e.getSeriesDataType(path)
+		var target aggregationTarget
+		if groupBy == nil {
+			aggregator := aggregation.NewAggregatorFor(dataType, exp.Aggregations())
+			target = &filterTarget{aggregator: aggregator, timeFilter: timeFilter}
+			aggregators[i] = []*aggregation.Aggregator{aggregator}
+		} else {
+			windows := newWindowTarget(groupBy, dataType, exp.Aggregations(), timeFilter)
+			target, aggregators[i] = windows, windows.aggregators
+		}
+		var functions *functionTarget
+		if len(newFunctions) > 0 {
+			functions = newFunctionTarget(groupBy, dataType, newFunctions, timeFilter)
+			target = targets{target, functions}
+		}
+		if err := e.aggregateSeries(path, targetWithContext(target, exp), exp); err != nil {
+			return impl2.NewFailedQueryDataSet(err)
+		}
+		if functions != nil {
+			functionResults[i] = functions.results(len(timestamps))
+		}
+	}
+	if groupBy != nil && exp.Descending() {
+		reverseWindows(timestamps, aggregators, functionResults)
+	}
+	return impl2.NewFunctionAggregationQueryDataSet(paths, exp.Aggregations(), exp.AggregateFunctions(), timestamps,
+		aggregators, functionResults)
+}
+
+// reverseWindows puts the windows, the aggregators and the function results of every path in descending time order.
+func reverseWindows(timestamps []int64, aggregators [][]*aggregation.Aggregator, functionResults [][][]interface{}) {
+	for i, j := 0, len(timestamps)-1; i < j; i, j = i+1, j-1 {
+		timestamps[i], timestamps[j] = timestamps[j], timestamps[i]
+		for _, pathAggregators := range aggregators {
+			pathAggregators[i], pathAggregators[j] = pathAggregators[j], pathAggregators[i]
+		}
+		for _, pathResults := range functionResults {
+			pathResults[i], pathResults[j] = pathResults[j], pathResults[i]
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"tsfile/common/constant"
+	"tsfile/file/metadata"
+	"tsfile/timeseries/read/datatype"
+)
+
+// Last returns the latest point of every path, nil for the paths not in this file. Only the metadata and, where the
+// chunk statistics are incomplete, the page headers of the newest chunk of each path are read.
+func (e *Engine) Last(paths []string) []*datatype.TimeValuePair {
+	results := make([]*datatype.TimeValuePair, len(paths))
+	for i, path := range paths {
+		results[i] = e.last(path)
+	}
+	return results
+}
+
+// LastOfDevice returns the latest point of every sensor of the device, keyed by the full path of the sensor.
+func (e *Engine) LastOfDevice(deviceId string) map[string]*datatype.TimeValuePair {
+	if len(e.files) > 0 {
+		return e.lastOfDeviceInFiles(deviceId)
+	}
+	results := make(map[string]*datatype.TimeValuePair)
+	deviceMeta, ok := e.fileMeta.DeviceMap()[deviceId]
+	if !ok {
+		return results
+	}
+	for _, rowGroupMeta := range deviceMeta.GetRowGroups() {
+		for _, chunkMeta := range rowGroupMeta.GetChunkMetaDataSli() {
+			path := deviceId + constant.PATH_SEPARATOR + chunkMeta.Sensor()
+			if _, ok := results[path]; !ok {
+				results[path] = e.last(path)
+			}
+		}
+	}
+	return results
+}
+
+func (e *Engine) last(path string) *datatype.TimeValuePair {
+	if len(e.files) > 0 {
+		return e.lastOfFiles(path)
+	}
+	deviceId, sensorId, ok := splitPath(path)
+	if !ok {
+		log.Println(fmt.Sprintf("Invalid path : %s", path))
+		return nil
+	}
+	dataType := e.getDataType(sensorId)
+	deviceMeta, ok := e.fileMeta.DeviceMap()[deviceId]
+	if dataType == constant.INVALID || !ok {
+		return nil
+	}
+
+	// walk from the newest row group, so that it wins among chunks ending at the same time
+	var newest *metadata.ChunkMetaData
+	rowGroups := deviceMeta.GetRowGroups()
+	for i := len(rowGroups) - 1; i >= 0; i-- {
+		for _, chunkMeta := range rowGroups[i].GetChunkMetaDataSli() {
+			if chunkMeta.Sensor() == sensorId && (newest == nil || chunkMeta.GetEndTime() > newest.GetEndTime()) {
+				newest = chunkMeta
+			}
+		}
+	}
+	if newest == nil {
+		return nil
+	}
+
+	if digest := newest.GetDigest(); digest != nil && newest.GetNumOfPoints() > 0 {
+		if value, ok := digest.GetValue(metadata.LAST, dataType); ok {
+			return &datatype.TimeValuePair{Timestamp: newest.GetEndTime(), Value: value}
+		}
+	}
+
+	// the statistics of the newest page hold the same
+	var result *datatype.TimeValuePair
+	chunkHeader, err := e.reader.ReadChunkHeaderAt(newest.FileOffsetOfCorrespondingData())
+	if err != nil {
+		log.Println(fmt.Sprintf("Cannot read chunk of %s : %v", newest.Sensor(), err))
+		return nil
+	}
+	pos := e.reader.Pos()
+	for i := 0; i < chunkHeader.GetNumberOfPages(); i++ {
+		pageHeader, err := e.reader.ReadPageHeaderAt(dataType, pos)
+		if err != nil {
+			log.Println(fmt.Sprintf("Cannot read page of %s : %v", newest.Sensor(), err))
+			break
+		}
+		pos = e.reader.Pos() + int64(pageHeader.GetCompressedSize())
+		if result == nil || pageHeader.Max_timestamp() >= result.Timestamp {
+			result = &datatype.TimeValuePair{Timestamp: pageHeader.Max_timestamp(),
+				Value: (*pageHeader.GetStatistics()).GetLast()}
+		}
+	}
+	return result
+}
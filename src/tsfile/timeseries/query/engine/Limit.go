@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/dataset"
+	impl2 "tsfile/timeseries/query/dataset/impl"
+	"tsfile/timeseries/read/reader"
+	"tsfile/timeseries/read/reader/impl/basic"
+)
+
+// limitSeries applies SLIMIT/SOFFSET to the (expanded) select paths.
+func limitSeries(paths []string, limit int, offset int) []string {
+	if offset >= len(paths) {
+		return nil
+	}
+	if offset > 0 {
+		paths = paths[offset:]
+	}
+	if limit > 0 && limit < len(paths) {
+		paths = paths[:limit]
+	}
+	return paths
+}
+
+// canSkipPages tells whether every row of the query is exactly one point of its only series, in which case the pages
+// before the offset can be jumped over using the number of values in their headers.
+func canSkipPages(exp *query.QueryExpression) bool {
+	if exp.RowOffset() <= 0 || len(exp.SelectPaths()) != 1 || exp.Filter() != nil || exp.IsAggregation() ||
+		len(exp.Fills()) > 0 || len(exp.SelectExpressions()) > 0 {
+		return false
+	}
+	conditionPaths := exp.ConditionPaths()
+	return len(conditionPaths) == 0 || (len(conditionPaths) == 1 && conditionPaths[0] == exp.SelectPaths()[0])
+}
+
+// skippingQuerySet reads the only select path of the query starting from the first page that holds the row at the
+// offset, and returns the number of rows that are still to be skipped in that page. In descending order the pages are
+// skipped from the end of the series.
+func (e *Engine) skippingQuerySet(exp *query.QueryExpression) (dataset.IQueryDataSet, int64) {
+	path := exp.SelectPaths()[0]
+	offset := exp.RowOffset()
+	dataType, encodings, offsets, sizes, compressions, headers := e.getPageInfo(path, true, nil)
+	from, to := 0, len(headers)
+	for from < to {
+		next := from
+		if exp.Descending() {
+			next = to - 1
+		}
+		if int64(headers[next].GetNumberOfValues()) > offset {
+			break
+		}
+		offset -= int64(headers[next].GetNumberOfValues())
+		if exp.Descending() {
+			to--
+		} else {
+			from++
+		}
+	}
+	seriesReader := basic.NewSeriesReader(offsets[from:to], sizes[from:to], compressions[from:to], encodings[from:to],
+		e.reader, dataType, exp.Descending())
+	seriesReader.Budget = exp.Budget()
+	exp.SetConditionPaths(exp.SelectPaths())
+	readerMap := map[string]reader.TimeValuePairReader{path: withContext(seriesReader, exp)}
+	return impl2.NewMergeQueryDataSet(exp.SelectPaths(), nil, readerMap, nil, exp.Descending()), offset
+}
@@ -0,0 +1,256 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"tsfile/common/constant"
+	"tsfile/timeseries/filter"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/read"
+	"tsfile/timeseries/read/datatype"
+	"tsfile/timeseries/read/reader"
+	"tsfile/timeseries/read/reader/impl/basic"
+	"tsfile/timeseries/read/reader/impl/seek"
+)
+
+// OpenFiles makes the engine query several files as one, e.g. the files a series is rotated into. The files are given
+// from the oldest to the newest, which decides the point kept when more than one of them hold a point of a series at
+// the same timestamp, see SetDuplicatePolicy.
+func (e *Engine) OpenFiles(readers []*read.TsFileSequenceReader) error {
+	files := make([]*Engine, len(readers))
+	for i, reader := range readers {
+		files[i] = new(Engine)
+		if err := files[i].Open(reader); err != nil {
+			return fmt.Errorf("file %d: %w", i, err)
+		}
+	}
+	e.files = files
+	return nil
+}
+
+// OpenDir opens the TsFiles in dir as OpenFiles does, ordered by their names. The files not starting with the TsFile
+// magic string are ignored, a corrupted or truncated TsFile fails.
+func (e *Engine) OpenDir(dir string) error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var readers []*read.TsFileSequenceReader
+	closeAll := func() {
+		for _, reader := range readers {
+			reader.Close()
+		}
+	}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		reader := new(read.TsFileSequenceReader)
+		if err := reader.Open(filepath.Join(dir, info.Name())); err != nil {
+			if errors.Is(err, read.ErrNotTsFile) {
+				continue
+			}
+			closeAll()
+			return err
+		}
+		readers = append(readers, reader)
+	}
+	if err := e.OpenFiles(readers); err != nil {
+		closeAll()
+		return err
+	}
+	return nil
+}
+
+// SetDuplicatePolicy decides which file wins when several files hold a point at the same timestamp, the newest one by
+// default.
+func (e *Engine) SetDuplicatePolicy(policy constant.DuplicatePolicy) {
+	e.duplicatePolicy = policy
+}
+
+// filesByPrecedence returns the files of a multi-file engine, the file whose points win first.
+func (e *Engine) filesByPrecedence() []*Engine {
+	if e.duplicatePolicy == constant.OLDEST_FILE_WINS {
+		return e.files
+	}
+	files := make([]*Engine, len(e.files))
+	for i, file := range e.files {
+		files[len(e.files)-1-i] = file
+	}
+	return files
+}
+
+// filesOf returns the files holding path during a time range that may match f, by precedence.
+func (e *Engine) filesOf(path string, f filter.Filter) []*Engine {
+	deviceId, sensorId, ok := splitPath(path)
+	if !ok {
+		return nil
+	}
+	var files []*Engine
+	for _, file := range e.filesByPrecedence() {
+		deviceMeta, ok := file.fileMeta.DeviceMap()[deviceId]
+		if ok && file.getDataType(sensorId) != constant.INVALID && filter.MayMatch(f, path, deviceMeta.GetStartTime(), deviceMeta.GetEndTime(), nil, nil) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// timeRangeFilter prunes pages by their time range only. The value range of a page cannot be used to leave it out when
+// several files are merged, since its points may hide those of other files at the same timestamps.
+type timeRangeFilter struct {
+	filter.Filter
+}
+
+func (f timeRangeFilter) MayMatch(path string, minTime int64, maxTime int64, minValue interface{},
+	maxValue interface{}) bool {
+	return filter.MayMatch(f.Filter, path, minTime, maxTime, nil, nil)
+}
+
+// fileExpression returns the expression the readers of every single file are constructed with.
+func fileExpression(exp *query.QueryExpression) *query.QueryExpression {
+	fileExp := *exp
+	if exp.Filter() != nil {
+		fileExp.SetFilter(timeRangeFilter{exp.Filter()})
+	}
+	return &fileExp
+}
+
+func (e *Engine) constructMergeReader(path string, exp *query.QueryExpression) reader.TimeValuePairReader {
+	files := e.filesOf(path, exp.Filter())
+	readers := make([]reader.TimeValuePairReader, len(files))
+	for i, file := range files {
+		readers[i] = file.constructReader(path, fileExpression(exp))
+	}
+	return basic.NewMergeSeriesReader(readers, exp.Descending())
+}
+
+func (e *Engine) constructMergeSeekableReader(path string, exp *query.QueryExpression) reader.ISeekableTimeValuePairReader {
+	files := e.filesOf(path, exp.Filter())
+	readers := make([]reader.ISeekableTimeValuePairReader, len(files))
+	for i, file := range files {
+		readers[i] = file.constructSeekableReader(path, fileExpression(exp))
+	}
+	return seek.NewMergeSeekableSeriesReader(readers, exp.Descending())
+}
+
+// aggregateFiles feeds the series at path in every file to target. Files are aggregated one by one, from their
+// statistics where possible, unless their time ranges overlap, in which case their points are merged and decoded so
+// that duplicates are counted once.
+func (e *Engine) aggregateFiles(path string, target aggregationTarget, exp *query.QueryExpression) error {
+	deviceId, _, _ := splitPath(path)
+	var files []*Engine
+	for _, file := range e.filesOf(path, nil) {
+		deviceMeta := file.fileMeta.DeviceMap()[deviceId]
+		if some, _ := target.accepts(deviceMeta.GetStartTime(), deviceMeta.GetEndTime(), false); some {
+			files = append(files, file)
+		}
+	}
+
+	if !overlapping(files, deviceId) {
+		for _, file := range files {
+			if err := file.aggregateSeries(path, target, exp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// the readers keep the context and the budget of the query, and skip the pages target is not interested in
+	fileExp := *exp
+	fileExp.SetFilter(targetFilter{target})
+	readers := make([]reader.TimeValuePairReader, len(files))
+	for i, file := range files {
+		readers[i] = file.constructReader(path, &fileExp)
+	}
+	mergeReader := basic.NewMergeSeriesReader(readers, false)
+	defer mergeReader.Close()
+	for mergeReader.HasNext() {
+		pair, err := mergeReader.Next()
+		if err != nil {
+			return err
+		}
+		target.update(pair.Timestamp, pair.Value)
+	}
+	return nil
+}
+
+// targetFilter prunes the pages of the merged files by the time range of an aggregation target, the points being
+// checked by the target itself.
+type targetFilter struct {
+	target aggregationTarget
+}
+
+func (f targetFilter) Satisfy(val interface{}) bool {
+	return true
+}
+
+func (f targetFilter) MayMatch(path string, minTime int64, maxTime int64, minValue interface{},
+	maxValue interface{}) bool {
+	some, _ := f.target.accepts(minTime, maxTime, false)
+	return some
+}
+
+// overlapping tells whether the time ranges of the device in any two of the files overlap.
+func overlapping(files []*Engine, deviceId string) bool {
+	ranges := make([][2]int64, len(files))
+	for i, file := range files {
+		deviceMeta := file.fileMeta.DeviceMap()[deviceId]
+		ranges[i] = [2]int64{deviceMeta.GetStartTime(), deviceMeta.GetEndTime()}
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i][0] <= ranges[i-1][1] {
+			return true
+		}
+	}
+	return false
+}
+
+// lastOfFiles returns the latest point of path among the files, the file with precedence winning a tie.
+func (e *Engine) lastOfFiles(path string) *datatype.TimeValuePair {
+	var result *datatype.TimeValuePair
+	for _, file := range e.filesByPrecedence() {
+		if pair := file.last(path); pair != nil && (result == nil || pair.Timestamp > result.Timestamp) {
+			result = pair
+		}
+	}
+	return result
+}
+
+func (e *Engine) lastOfDeviceInFiles(deviceId string) map[string]*datatype.TimeValuePair {
+	results := make(map[string]*datatype.TimeValuePair)
+	for _, file := range e.filesByPrecedence() {
+		for path, pair := range file.LastOfDevice(deviceId) {
+			if result, ok := results[path]; pair != nil && (!ok || result == nil || pair.Timestamp > result.Timestamp) {
+				results[path] = pair
+			}
+		}
+	}
+	return results
+}
+
+// chunkCount returns the number of chunks of path in this file.
+func (e *Engine) chunkCount(path string) int {
+	deviceId, sensorId, ok := splitPath(path)
+	if !ok {
+		return 0
+	}
+	deviceMeta, ok := e.fileMeta.DeviceMap()[deviceId]
+	if !ok {
+		return 0
+	}
+	count := 0
+	for _, rowGroupMeta := range deviceMeta.GetRowGroups() {
+		for _, chunkMeta := range rowGroupMeta.GetChunkMetaDataSli() {
+			if chunkMeta.Sensor() == sensorId {
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,303 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"tsfile/common/constant"
+	"tsfile/file/header"
+	"tsfile/timeseries/filter"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/similarity"
+	"tsfile/timeseries/read/reader/impl/basic"
+)
+
+// Search returns the matches of the pattern of q in its series, the closest first, see query.SimilarityQuery.
+// The points are streamed page by page through series readers. The pages outside the time range of q are not decoded,
+// nor, once K matches are found and unless q normalizes the windows, the pages whose values and those of the pages
+// around them (as far as a window holding any of their points reaches) are too far from the pattern for any such window
+// to be closer than the matches.
+func (e *Engine) Search(q *query.SimilarityQuery) ([]*query.Match, error) {
+	if len(q.Pattern) < 2 {
+		return nil, errors.New("the pattern of a similarity search needs at least 2 points")
+	}
+	if q.K <= 0 {
+		return nil, fmt.Errorf("invalid number of matches %d", q.K)
+	}
+	if q.Measure != constant.EUCLIDEAN && q.Measure != constant.DTW {
+		return nil, fmt.Errorf("unknown distance measure %d", q.Measure)
+	}
+	if q.Band < 0 {
+		return nil, fmt.Errorf("invalid DTW band %d", q.Band)
+	}
+	switch e.getSeriesDataType(q.Path) {
+	case constant.INT32, constant.INT64, constant.FLOAT, constant.DOUBLE:
+	case constant.INVALID:
+		return nil, fmt.Errorf("no such timeseries %s", q.Path)
+	default:
+		return nil, fmt.Errorf("timeseries %s is not numeric", q.Path)
+	}
+
+	s := newSearcher(q)
+	if len(e.files) == 0 {
+		if err := s.searchPages(e.searchPagesOf(q.Path)); err != nil {
+			return nil, err
+		}
+		return s.matches(), nil
+	}
+
+	deviceId, _, _ := splitPath(q.Path)
+	files := e.filesOf(q.Path, nil)
+	if !overlapping(files, deviceId) {
+		// windows may span consecutive files, whose pages are searched as those of a single file
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].fileMeta.DeviceMap()[deviceId].GetStartTime() <
+				files[j].fileMeta.DeviceMap()[deviceId].GetStartTime()
+		})
+		var pages []searchPage
+		for _, file := range files {
+			pages = append(pages, file.searchPagesOf(q.Path)...)
+		}
+		if err := s.searchPages(pages); err != nil {
+			return nil, err
+		}
+		return s.matches(), nil
+	}
+	mergeReader := e.constructMergeReader(q.Path, new(query.QueryExpression))
+	defer mergeReader.Close()
+	for mergeReader.HasNext() {
+		pair, err := mergeReader.Next()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s : %w", q.Path, err)
+		}
+		s.add(pair.Timestamp, pair.Value)
+	}
+	return s.matches(), nil
+}
+
+// searchPage is a page of the searched series in the file it belongs to.
+type searchPage struct {
+	file        *Engine
+	header      *header.PageHeader
+	offset      int64
+	size        int
+	compression constant.CompressionType
+	encoding    constant.TSEncoding
+	dataType    constant.TSDataType
+}
+
+func (e *Engine) searchPagesOf(path string) []searchPage {
+	dataType, encodings, offsets, sizes, compressions, headers := e.getPageInfo(path, true, nil)
+	pages := make([]searchPage, len(headers))
+	for i, pageHeader := range headers {
+		pages[i] = searchPage{file: e, header: pageHeader, offset: offsets[i], size: sizes[i],
+			compression: compressions[i], encoding: encodings[i], dataType: dataType}
+	}
+	return pages
+}
+
+// searcher slides a window over the points of a series and keeps the closest windows, among which the matches are
+// picked once the series is searched. Windows and pages are pruned against a bound that only decreases: the distance of
+// the K-th of candidates far enough apart for no window to overlap two of them. Each match leaves out at most one of
+// those, so K matches are as close as the bound at least. The matches found so far are never used as a bound, since a
+// closer window overlapping several of them may make them farther.
+type searcher struct {
+	query *query.SimilarityQuery
+	// pattern is z-normalized if the windows are
+	pattern []float64
+	// times and values hold the window, up to as many consecutive points as the pattern
+	times  []int64
+	values []float64
+	// normalized is the z-normalized window
+	normalized []float64
+	// index is the number of points the window was slid to, the skipped pages aside
+	index int64
+	// candidates are the windows at most as far as bound, sorted by distance
+	candidates []candidate
+	bound      float64
+}
+
+// candidate is a window starting at the start-th point the searcher was slid to.
+type candidate struct {
+	match *query.Match
+	start int64
+}
+
+func newSearcher(q *query.SimilarityQuery) *searcher {
+	s := &searcher{query: q, pattern: q.Pattern, bound: math.Inf(1)}
+	if q.Normalize {
+		s.pattern = make([]float64, len(q.Pattern))
+		similarity.ZNormalize(s.pattern, q.Pattern)
+		s.normalized = make([]float64, len(q.Pattern))
+	}
+	return s
+}
+
+// searchPages slides the window over the points of the pages, failing with the error a page cannot be read with.
+func (s *searcher) searchPages(pages []searchPage) error {
+	for i, page := range pages {
+		if s.prunes(pages, i) {
+			// no window may hold points of both sides of the page
+			s.reset()
+			continue
+		}
+		pageReader := basic.NewSeriesReader([]int64{page.offset}, []int{page.size},
+			[]constant.CompressionType{page.compression}, []constant.TSEncoding{page.encoding}, page.file.reader,
+			page.dataType, false)
+		for pageReader.HasNext() {
+			pair, err := pageReader.Next()
+			if err != nil {
+				return fmt.Errorf("cannot read page of %s : %w", s.query.Path, err)
+			}
+			s.add(pair.Timestamp, pair.Value)
+		}
+	}
+	return nil
+}
+
+// prunes tells whether no window holding a point of the i-th page may be among the matches.
+func (s *searcher) prunes(pages []searchPage, i int) bool {
+	pageHeader := pages[i].header
+	if s.query.TimeFilter != nil &&
+		!filter.SatisfyAny(s.query.TimeFilter, pageHeader.Min_timestamp(), pageHeader.Max_timestamp()) {
+		return true
+	}
+	limit := s.limit()
+	if s.query.Normalize || math.IsInf(limit, 1) {
+		return false
+	}
+
+	// the value range of every page a window holding a point of this one may reach
+	min, max := pageRange(pageHeader)
+	reach := len(s.pattern) - 1
+	for j, n := i-1, 0; j >= 0 && n < reach; j-- {
+		min, max = widenRange(min, max, pages[j].header)
+		n += int(pages[j].header.GetNumberOfValues())
+	}
+	for j, n := i+1, 0; j < len(pages) && n < reach; j++ {
+		min, max = widenRange(min, max, pages[j].header)
+		n += int(pages[j].header.GetNumberOfValues())
+	}
+	return similarity.LowerBound(s.pattern, min, max) >= limit
+}
+
+func pageRange(pageHeader *header.PageHeader) (min float64, max float64) {
+	stats := *pageHeader.GetStatistics()
+	return toDouble(stats.GetMin()), toDouble(stats.GetMax())
+}
+
+func widenRange(min float64, max float64, pageHeader *header.PageHeader) (float64, float64) {
+	pageMin, pageMax := pageRange(pageHeader)
+	return math.Min(min, pageMin), math.Max(max, pageMax)
+}
+
+// limit returns the distance a window must be closer than to be among the candidates.
+func (s *searcher) limit() float64 {
+	return s.bound
+}
+
+func (s *searcher) reset() {
+	s.times, s.values = s.times[:0], s.values[:0]
+}
+
+// add slides the window to a point, the points not satisfying the time filter of the query breaking the series.
+func (s *searcher) add(timestamp int64, value interface{}) {
+	s.index++
+	if s.query.TimeFilter != nil && !s.query.TimeFilter.Satisfy(timestamp) {
+		s.reset()
+		return
+	}
+	m := len(s.pattern)
+	if len(s.values) == m {
+		copy(s.times, s.times[1:])
+		copy(s.values, s.values[1:])
+		s.times, s.values = s.times[:m-1], s.values[:m-1]
+	}
+	s.times, s.values = append(s.times, timestamp), append(s.values, toDouble(value))
+	if len(s.values) < m {
+		return
+	}
+
+	window := s.values
+	if s.query.Normalize {
+		similarity.ZNormalize(s.normalized, s.values)
+		window = s.normalized
+	}
+	limit := s.limit()
+	if distance := similarity.Distance(s.query.Measure, s.pattern, window, s.query.Band, limit); distance < limit {
+		s.insert(candidate{match: &query.Match{StartTime: s.times[0], EndTime: s.times[m-1], Distance: distance},
+			start: s.index - int64(m)})
+	}
+}
+
+// insert adds a window to the candidates after those at least as close, then lowers the bound if K candidates far
+// enough apart are closer than it, dropping those farther than the bound.
+func (s *searcher) insert(c candidate) {
+	i := sort.Search(len(s.candidates), func(i int) bool {
+		return s.candidates[i].match.Distance > c.match.Distance
+	})
+	s.candidates = append(s.candidates, candidate{})
+	copy(s.candidates[i+1:], s.candidates[i:])
+	s.candidates[i] = c
+
+	// no window overlaps two windows starting 2 * len(pattern) - 1 points apart
+	apart := int64(2*len(s.pattern) - 1)
+	starts := make([]int64, 0, s.query.K)
+	for _, c := range s.candidates {
+		far := true
+		for _, start := range starts {
+			if c.start-start < apart && start-c.start < apart {
+				far = false
+				break
+			}
+		}
+		if !far {
+			continue
+		}
+		if starts = append(starts, c.start); len(starts) == s.query.K {
+			s.bound = math.Min(s.bound, c.match.Distance)
+			break
+		}
+	}
+	i = sort.Search(len(s.candidates), func(i int) bool {
+		return s.candidates[i].match.Distance > s.bound
+	})
+	s.candidates = s.candidates[:i]
+}
+
+// matches picks up to K candidates, the closest first, leaving out those overlapping a closer match. Of windows as
+// close, the earliest is picked.
+func (s *searcher) matches() []*query.Match {
+	var matches []*query.Match
+	for _, c := range s.candidates {
+		if len(matches) == s.query.K {
+			break
+		}
+		overlapping := false
+		for _, match := range matches {
+			if match.StartTime <= c.match.EndTime && c.match.StartTime <= match.EndTime {
+				overlapping = true
+				break
+			}
+		}
+		if !overlapping {
+			matches = append(matches, c.match)
+		}
+	}
+	return matches
+}
+
+func toDouble(value interface{}) float64 {
+	switch v := value.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
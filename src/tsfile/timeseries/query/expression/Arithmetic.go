@@ -0,0 +1,150 @@
+package expression
+
+import (
+	"math"
+	"tsfile/common/constant"
+)
+
+// operator precedences, for writing the operands of binary expressions with as few parentheses as needed
+var precedence = map[byte]int{'+': 1, '-': 1, '*': 2, '/': 2, '%': 2}
+
+type binary struct {
+	op    byte
+	left  Expression
+	right Expression
+}
+
+// Binary combines two expressions with one of the operators + - * / %, see Arithmetic.
+func Binary(op byte, left Expression, right Expression) Expression {
+	return &binary{op: op, left: left, right: right}
+}
+
+func (e *binary) Paths() []string {
+	return PathsOf([]Expression{e.left, e.right})
+}
+
+func (e *binary) String() string {
+	left, right := e.left.String(), e.right.String()
+	if l, ok := e.left.(*binary); ok && precedence[l.op] < precedence[e.op] {
+		left = "(" + left + ")"
+	}
+	// a - (b - c) and a * (b / c) differ from a - b - c and a * b / c, with integer division
+	if r, ok := e.right.(*binary); ok && (precedence[r.op] < precedence[e.op] ||
+		(precedence[r.op] == precedence[e.op] && e.op != '+' && (e.op != '*' || r.op != '*'))) {
+		right = "(" + right + ")"
+	}
+	return left + " " + string(e.op) + " " + right
+}
+
+func (e *binary) NewEvaluator(index map[string]int) Evaluator {
+	left, right := e.left.NewEvaluator(index), e.right.NewEvaluator(index)
+	return func(timestamp int64, values []interface{}) interface{} {
+		return Arithmetic(e.op, left(timestamp, values), right(timestamp, values))
+	}
+}
+
+// Arithmetic applies one of the operators + - * / % to two values of numeric data types after converting both to the
+// type given by Promote, which is the type of the result. Integer division truncates, and dividing an integer by zero
+// gives nil as does any operand which is nil or not numeric.
+func Arithmetic(op byte, a interface{}, b interface{}) interface{} {
+	switch Promote(numericType(a), numericType(b)) {
+	case constant.INT32:
+		x, y := a.(int32), b.(int32)
+		switch op {
+		case '+':
+			return x + y
+		case '-':
+			return x - y
+		case '*':
+			return x * y
+		case '/':
+			if y != 0 {
+				return x / y
+			}
+		case '%':
+			if y != 0 {
+				return x % y
+			}
+		}
+	case constant.INT64:
+		x, y := toInt64(a), toInt64(b)
+		switch op {
+		case '+':
+			return x + y
+		case '-':
+			return x - y
+		case '*':
+			return x * y
+		case '/':
+			if y != 0 {
+				return x / y
+			}
+		case '%':
+			if y != 0 {
+				return x % y
+			}
+		}
+	case constant.FLOAT:
+		if result, ok := floatArithmetic(op, toFloat64(a), toFloat64(b)); ok {
+			return float32(result)
+		}
+	case constant.DOUBLE:
+		if result, ok := floatArithmetic(op, toFloat64(a), toFloat64(b)); ok {
+			return result
+		}
+	}
+	return nil
+}
+
+func floatArithmetic(op byte, x float64, y float64) (float64, bool) {
+	switch op {
+	case '+':
+		return x + y, true
+	case '-':
+		return x - y, true
+	case '*':
+		return x * y, true
+	case '/':
+		return x / y, true
+	case '%':
+		return math.Mod(x, y), true
+	}
+	return 0, false
+}
+
+type negation struct {
+	operand Expression
+}
+
+// Negate changes the sign of an expression, keeping its data type.
+func Negate(operand Expression) Expression {
+	return &negation{operand: operand}
+}
+
+func (e *negation) Paths() []string {
+	return e.operand.Paths()
+}
+
+func (e *negation) String() string {
+	if _, ok := e.operand.(*binary); ok {
+		return "-(" + e.operand.String() + ")"
+	}
+	return "-" + e.operand.String()
+}
+
+func (e *negation) NewEvaluator(index map[string]int) Evaluator {
+	operand := e.operand.NewEvaluator(index)
+	return func(timestamp int64, values []interface{}) interface{} {
+		switch v := operand(timestamp, values).(type) {
+		case int32:
+			return -v
+		case int64:
+			return -v
+		case float32:
+			return -v
+		case float64:
+			return -v
+		}
+		return nil
+	}
+}
@@ -0,0 +1,155 @@
+package expression
+
+import (
+	"strconv"
+	"tsfile/common/constant"
+)
+
+// Expression computes a value from the values of some series at the same timestamp, e.g. root.d0.s0 * 1.8 + 32.
+// Series of the numeric data types (INT32, INT64, FLOAT and DOUBLE) are combined as described in Arithmetic, the
+// values of other series and missing values make the result nil.
+type Expression interface {
+	// Paths lists the series read by the expression in the order they first appear.
+	Paths() []string
+	// String writes the expression as in a query, which names the column of its results.
+	String() string
+	// NewEvaluator returns a function computing the expression on the rows of a query, to be called on every row in the
+	// order the rows are returned since functions like diff depend on the previous rows. index gives the position of
+	// the value of every path in the values of a row.
+	NewEvaluator(index map[string]int) Evaluator
+}
+
+type Evaluator func(timestamp int64, values []interface{}) interface{}
+
+// PathsOf lists the series read by any of the expressions, in the order they first appear.
+func PathsOf(expressions []Expression) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, e := range expressions {
+		for _, path := range e.Paths() {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+type series struct {
+	path string
+}
+
+// Series is the value of the series at path.
+func Series(path string) Expression {
+	return &series{path: path}
+}
+
+func (e *series) Paths() []string {
+	return []string{e.path}
+}
+
+func (e *series) String() string {
+	return e.path
+}
+
+func (e *series) NewEvaluator(index map[string]int) Evaluator {
+	i, ok := index[e.path]
+	return func(timestamp int64, values []interface{}) interface{} {
+		if !ok {
+			return nil
+		}
+		return values[i]
+	}
+}
+
+type constantValue struct {
+	value interface{}
+}
+
+// Constant is a constant value of the go type of a numeric data type, e.g. int32 for INT32.
+func Constant(value interface{}) Expression {
+	return &constantValue{value: value}
+}
+
+func (e *constantValue) Paths() []string {
+	return nil
+}
+
+func (e *constantValue) String() string {
+	switch v := e.value.(type) {
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return "null"
+}
+
+func (e *constantValue) NewEvaluator(index map[string]int) Evaluator {
+	return func(timestamp int64, values []interface{}) interface{} {
+		return e.value
+	}
+}
+
+// numericType returns the numeric data type of a value, constant.INVALID if it is not numeric.
+func numericType(v interface{}) constant.TSDataType {
+	switch v.(type) {
+	case int32:
+		return constant.INT32
+	case int64:
+		return constant.INT64
+	case float32:
+		return constant.FLOAT
+	case float64:
+		return constant.DOUBLE
+	}
+	return constant.INVALID
+}
+
+// rank orders the numeric data types by width, 0 for the others
+var rank = map[constant.TSDataType]int{constant.INT32: 1, constant.INT64: 2, constant.FLOAT: 3, constant.DOUBLE: 4}
+
+// Promote returns the data type two numeric data types are combined in: the wider of both in the order INT32, INT64,
+// FLOAT, DOUBLE, except INT64 and FLOAT giving DOUBLE so that no INT64 loses precision to a FLOAT. It returns
+// constant.INVALID if either is not numeric.
+func Promote(a constant.TSDataType, b constant.TSDataType) constant.TSDataType {
+	if rank[a] == 0 || rank[b] == 0 {
+		return constant.INVALID
+	}
+	if (a == constant.INT64 && b == constant.FLOAT) || (a == constant.FLOAT && b == constant.INT64) {
+		return constant.DOUBLE
+	}
+	if rank[a] > rank[b] {
+		return a
+	}
+	return b
+}
+
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	}
+	return 0
+}
+
+func toFloat64(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
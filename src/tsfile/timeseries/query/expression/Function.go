@@ -0,0 +1,167 @@
+package expression
+
+import (
+	"strconv"
+	"strings"
+	"tsfile/common/constant"
+	"tsfile/timeseries/query/udf"
+)
+
+type function struct {
+	name     string
+	argument Expression
+	// window is the number of values of moving_avg
+	window int
+	// newState returns the function applied to the value of the argument at a timestamp, with a state of its own
+	newState func() func(timestamp int64, value interface{}) interface{}
+}
+
+// Abs is the absolute value of an expression, of the same data type.
+func Abs(argument Expression) Expression {
+	return &function{name: "abs", argument: argument, newState: func() func(int64, interface{}) interface{} {
+		return func(timestamp int64, value interface{}) interface{} {
+			switch v := value.(type) {
+			case int32:
+				if v < 0 {
+					return -v
+				}
+				return v
+			case int64:
+				if v < 0 {
+					return -v
+				}
+				return v
+			case float32:
+				if v < 0 {
+					return -v
+				}
+				return v
+			case float64:
+				if v < 0 {
+					return -v
+				}
+				return v
+			}
+			return nil
+		}
+	}}
+}
+
+// Diff is the difference between the value of an expression and its previous value, in the order of the rows and
+// skipping the rows where it is nil, of the data type of the expression. It is nil at the first value.
+func Diff(argument Expression) Expression {
+	return &function{name: "diff", argument: argument, newState: func() func(int64, interface{}) interface{} {
+		var previous interface{}
+		return func(timestamp int64, value interface{}) interface{} {
+			if numericType(value) == constant.INVALID {
+				return nil
+			}
+			result := Arithmetic('-', value, previous)
+			previous = value
+			return result
+		}
+	}}
+}
+
+// Derivative is the change of an expression per time unit since its previous value, as Diff, as a DOUBLE.
+func Derivative(argument Expression) Expression {
+	return &function{name: "derivative", argument: argument, newState: func() func(int64, interface{}) interface{} {
+		var previous interface{}
+		var previousTime int64
+		return func(timestamp int64, value interface{}) interface{} {
+			if numericType(value) == constant.INVALID {
+				return nil
+			}
+			var result interface{}
+			if previous != nil && timestamp != previousTime {
+				result = (toFloat64(value) - toFloat64(previous)) / float64(timestamp-previousTime)
+			}
+			previous, previousTime = value, timestamp
+			return result
+		}
+	}}
+}
+
+// MovingAvg is the average of the last window values of an expression, skipping the rows where it is nil, as a
+// DOUBLE. It is nil until window values are seen, and always nil if window is not positive.
+func MovingAvg(argument Expression, window int) Expression {
+	return &function{name: "moving_avg", argument: argument, window: window,
+		newState: func() func(int64, interface{}) interface{} {
+			// a ring of the last window values
+			var values []float64
+			if window > 0 {
+				values = make([]float64, window)
+			}
+			var count int
+			return func(timestamp int64, value interface{}) interface{} {
+				if numericType(value) == constant.INVALID || window <= 0 {
+					return nil
+				}
+				values[count%window] = toFloat64(value)
+				count++
+				if count < window {
+					return nil
+				}
+				var sum float64
+				for _, v := range values {
+					sum += v
+				}
+				return sum / float64(window)
+			}
+		}}
+}
+
+func (e *function) Paths() []string {
+	return e.argument.Paths()
+}
+
+func (e *function) String() string {
+	if e.name == "moving_avg" {
+		return e.name + "(" + e.argument.String() + ", " + strconv.Itoa(e.window) + ")"
+	}
+	return e.name + "(" + e.argument.String() + ")"
+}
+
+func (e *function) NewEvaluator(index map[string]int) Evaluator {
+	argument, apply := e.argument.NewEvaluator(index), e.newState()
+	return func(timestamp int64, values []interface{}) interface{} {
+		return apply(timestamp, argument(timestamp, values))
+	}
+}
+
+type call struct {
+	name      string
+	f         udf.Scalar
+	arguments []Expression
+}
+
+// Call applies a user-defined scalar function to the values of its arguments, nil ones included.
+func Call(name string, f udf.Scalar, arguments ...Expression) Expression {
+	return &call{name: name, f: f, arguments: arguments}
+}
+
+func (e *call) Paths() []string {
+	return PathsOf(e.arguments)
+}
+
+func (e *call) String() string {
+	arguments := make([]string, len(e.arguments))
+	for i, argument := range e.arguments {
+		arguments[i] = argument.String()
+	}
+	return e.name + "(" + strings.Join(arguments, ", ") + ")"
+}
+
+func (e *call) NewEvaluator(index map[string]int) Evaluator {
+	evaluators := make([]Evaluator, len(e.arguments))
+	for i, argument := range e.arguments {
+		evaluators[i] = argument.NewEvaluator(index)
+	}
+	args := make([]interface{}, len(evaluators))
+	return func(timestamp int64, values []interface{}) interface{} {
+		for i, evaluate := range evaluators {
+			args[i] = evaluate(timestamp, values)
+		}
+		return e.f.Evaluate(timestamp, args)
+	}
+}
@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/timeseries/query/expression"
+)
+
+// exprNode is an expression of the select clause as written, its paths being relative to the from clause until it is
+// built under one of its paths.
+type exprNode struct {
+	pos int
+	// path is set for a series, value for a constant, function for a function call and op for an arithmetic operator
+	path     string
+	value    interface{}
+	function string
+	window   int
+	op       byte
+	operands []*exprNode
+}
+
+// functions are the functions of the select clause taking an expression, moving_avg taking a window as well. They
+// take precedence over user-defined functions.
+var functions = map[string]func(expression.Expression) expression.Expression{
+	"abs":        expression.Abs,
+	"diff":       expression.Diff,
+	"derivative": expression.Derivative,
+}
+
+const movingAvg = "moving_avg"
+
+// IsReserved tells whether name is taken by an aggregation or a function of the query language, and so cannot name a
+// user-defined function.
+func IsReserved(name string) bool {
+	_, isFunction := functions[strings.ToLower(name)]
+	_, isAggregation := constant.LookupAggregation(name)
+	return isFunction || isAggregation || strings.EqualFold(name, movingAvg)
+}
+
+// isFunction tells whether name is a function that may be called in expressions, a user-defined scalar function
+// included.
+func (p *parser) isFunction(name string) bool {
+	_, isScalar := p.functions.Scalar(name)
+	_, isFunction := functions[strings.ToLower(name)]
+	return isFunction || isScalar || strings.EqualFold(name, movingAvg)
+}
+
+// parseExpression reads a sum of terms.
+func (p *parser) parseExpression() (*exprNode, error) {
+	node, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("+") || p.peek().is("-") {
+		t := p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		node = &exprNode{pos: t.pos, op: t.text[0], operands: []*exprNode{node, right}}
+	}
+	return node, nil
+}
+
+// parseTerm reads a product of factors.
+func (p *parser) parseTerm() (*exprNode, error) {
+	node, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("*") || p.peek().is("/") || p.peek().is("%") {
+		t := p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		node = &exprNode{pos: t.pos, op: t.text[0], operands: []*exprNode{node, right}}
+	}
+	return node, nil
+}
+
+// parseFactor reads a negation, a number, a function call, an expression in parentheses or a path.
+func (p *parser) parseFactor() (*exprNode, error) {
+	t := p.peek()
+	switch {
+	case t.is("-"):
+		p.next()
+		if next := p.peek(); next.kind == tokenInteger || next.kind == tokenDecimal {
+			return p.parseNumberLiteral("-")
+		}
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &exprNode{pos: t.pos, op: '-', operands: []*exprNode{operand}}, nil
+	case (t.kind == tokenInteger && !p.tokens[p.pos+1].is(constant.PATH_SEPARATOR)) || t.kind == tokenDecimal:
+		return p.parseNumberLiteral("")
+	case t.kind == tokenIdent && p.tokens[p.pos+1].is("("):
+		return p.parseFunction()
+	case t.is("("):
+		p.next()
+		node, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	path, pos, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	return &exprNode{pos: pos, path: path}, nil
+}
+
+// parseNumberLiteral reads a number, an INT32 if it is an integer in its range, else an INT64, or a DOUBLE if it is a
+// decimal.
+func (p *parser) parseNumberLiteral(sign string) (*exprNode, error) {
+	t := p.next()
+	if t.kind == tokenDecimal {
+		v, err := strconv.ParseFloat(sign+t.text, 64)
+		if err != nil {
+			return nil, p.errorAt(t.pos, "number %s%s out of range", sign, t.text)
+		}
+		return &exprNode{pos: t.pos, value: v}, nil
+	}
+	v, err := strconv.ParseInt(sign+t.text, 10, 64)
+	if err != nil {
+		return nil, p.errorAt(t.pos, "integer %s%s out of range", sign, t.text)
+	}
+	if int64(int32(v)) == v {
+		return &exprNode{pos: t.pos, value: int32(v)}, nil
+	}
+	return &exprNode{pos: t.pos, value: v}, nil
+}
+
+// parseFunction reads a call to a function, user-defined scalar functions taking any number of arguments.
+func (p *parser) parseFunction() (*exprNode, error) {
+	t := p.next()
+	name := strings.ToLower(t.text)
+	if !p.isFunction(name) {
+		return nil, p.errorAt(t.pos, "unknown function %s", t.text)
+	}
+	p.next()
+	argument, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	node := &exprNode{pos: t.pos, function: name, operands: []*exprNode{argument}}
+	if _, isScalar := p.functions.Scalar(name); isScalar && !IsReserved(name) {
+		for p.accept(",") {
+			if argument, err = p.parseExpression(); err != nil {
+				return nil, err
+			}
+			node.operands = append(node.operands, argument)
+		}
+	} else if name == movingAvg {
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		window, err := p.parsePositive("the window of " + movingAvg)
+		if err != nil {
+			return nil, err
+		}
+		node.window = int(window)
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// build creates the expression with its relative paths under prefix. Its series must exist and be numeric.
+func (p *parser) build(node *exprNode, prefix string) (expression.Expression, error) {
+	switch {
+	case node.path != "":
+		path := node.path
+		if !strings.HasPrefix(path, "root"+constant.PATH_SEPARATOR) {
+			path = prefix + constant.PATH_SEPARATOR + path
+		}
+		if utils.IsPathPattern(path) {
+			return nil, p.errorAt(node.pos, "%s must name a single series in an expression", path)
+		}
+		switch dataType := p.schema(path); dataType {
+		case constant.INT32, constant.INT64, constant.FLOAT, constant.DOUBLE:
+			return expression.Series(path), nil
+		case constant.INVALID:
+			return nil, p.errorAt(node.pos, "unknown series %s", path)
+		default:
+			return nil, p.errorAt(node.pos, "%s series %s cannot be computed with", dataType, path)
+		}
+	case node.value != nil:
+		return expression.Constant(node.value), nil
+	}
+
+	operands := make([]expression.Expression, len(node.operands))
+	for i, operand := range node.operands {
+		var err error
+		if operands[i], err = p.build(operand, prefix); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case node.function == movingAvg:
+		return expression.MovingAvg(operands[0], node.window), nil
+	case node.function != "":
+		if f, ok := functions[node.function]; ok {
+			return f(operands[0]), nil
+		}
+		f, _ := p.functions.Scalar(node.function)
+		return expression.Call(node.function, f, operands...), nil
+	case len(operands) == 1:
+		return expression.Negate(operands[0]), nil
+	}
+	return expression.Binary(node.op, operands[0], operands[1]), nil
+}
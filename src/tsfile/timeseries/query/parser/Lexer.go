@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenInteger
+	tokenDecimal
+	tokenString
+	// tokenOperator is a comparison, one of = == != <> > >= < <=
+	tokenOperator
+	// tokenSymbol is one of ( ) [ ] , . * ** - + / % ! && ||
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	// text is the token as written, except for strings whose quotes and escapes are removed
+	text string
+	pos  int
+}
+
+// is tells whether the token is the given symbol or operator, or the given keyword, which is case insensitive.
+func (t token) is(text string) bool {
+	if t.kind == tokenIdent {
+		return strings.EqualFold(t.text, text)
+	}
+	return (t.kind == tokenSymbol || t.kind == tokenOperator) && t.text == text
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return "string '" + t.text + "'"
+	}
+	return "\"" + t.text + "\""
+}
+
+var twoCharTokens = map[string]tokenKind{"==": tokenOperator, "!=": tokenOperator, "<>": tokenOperator, ">=": tokenOperator,
+	"<=": tokenOperator, "**": tokenSymbol, "&&": tokenSymbol, "||": tokenSymbol}
+
+var oneCharTokens = map[byte]tokenKind{'=': tokenOperator, '>': tokenOperator, '<': tokenOperator, '(': tokenSymbol, ')': tokenSymbol,
+	'[': tokenSymbol, ']': tokenSymbol, ',': tokenSymbol, '.': tokenSymbol, '*': tokenSymbol, '-': tokenSymbol, '+': tokenSymbol, '/': tokenSymbol,
+	'%': tokenSymbol, '!': tokenSymbol}
+
+// tokenize splits a query into tokens, the last one being tokenEOF.
+func tokenize(sql string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isLetter(c):
+			start := i
+			for i < len(sql) && (isLetter(sql[i]) || isDigit(sql[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, sql[start:i], start})
+		case isDigit(c):
+			start := i
+			kind := tokenInteger
+			for i < len(sql) && isDigit(sql[i]) {
+				i++
+			}
+			if i+1 < len(sql) && sql[i] == '.' && isDigit(sql[i+1]) {
+				kind = tokenDecimal
+				for i++; i < len(sql) && isDigit(sql[i]); i++ {
+				}
+			}
+			if i < len(sql) && (sql[i] == 'e' || sql[i] == 'E') {
+				j := i + 1
+				if j < len(sql) && (sql[j] == '+' || sql[j] == '-') {
+					j++
+				}
+				if j < len(sql) && isDigit(sql[j]) {
+					kind = tokenDecimal
+					for i = j; i < len(sql) && isDigit(sql[i]); i++ {
+					}
+				}
+			}
+			if i < len(sql) && isLetter(sql[i]) {
+				return nil, newParseError(sql, start, "invalid number %q", sql[start:i+1])
+			}
+			tokens = append(tokens, token{kind, sql[start:i], start})
+		case c == '\'' || c == '"':
+			value, end, ok := unquote(sql, i)
+			if !ok {
+				return nil, newParseError(sql, i, "unterminated string")
+			}
+			tokens = append(tokens, token{tokenString, value, i})
+			i = end
+		default:
+			if i+1 < len(sql) {
+				if kind, ok := twoCharTokens[sql[i:i+2]]; ok {
+					tokens = append(tokens, token{kind, sql[i : i+2], i})
+					i += 2
+					continue
+				}
+			}
+			kind, ok := oneCharTokens[c]
+			if !ok {
+				return nil, newParseError(sql, i, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind, sql[i : i+1], i})
+			i++
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(sql)}), nil
+}
+
+// unquote reads the string starting with the quote at start, a backslash escaping the next character. It returns the
+// value of the string and the offset after its closing quote.
+func unquote(sql string, start int) (string, int, bool) {
+	quote := sql[start]
+	var value []byte
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if i+1 == len(sql) {
+				return "", 0, false
+			}
+			i++
+			value = append(value, sql[i])
+		case quote:
+			return string(value), i + 1, true
+		default:
+			value = append(value, sql[i])
+		}
+	}
+	return "", 0, false
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
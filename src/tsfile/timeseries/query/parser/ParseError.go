@@ -0,0 +1,28 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError reports a syntax or type error in a query. Offset is the byte offset of the offending token in the query,
+// Line and Column (both from 1) locate it for humans.
+type ParseError struct {
+	Offset int
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func newParseError(sql string, offset int, format string, args ...interface{}) *ParseError {
+	if offset > len(sql) {
+		offset = len(sql)
+	}
+	line := strings.Count(sql[:offset], "\n") + 1
+	column := offset - strings.LastIndex(sql[:offset], "\n")
+	return &ParseError{Offset: offset, Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,729 @@
+package parser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/timeseries/filter"
+	"tsfile/timeseries/filter/operator"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/expression"
+	"tsfile/timeseries/query/udf"
+)
+
+// Schema gives the data type of a series, constant.INVALID if there is no such series.
+type Schema func(path string) constant.TSDataType
+
+// Parse turns a query into a QueryExpression, e.g.
+//
+//	SELECT s0, s1 FROM root.d0 WHERE time >= 100 AND s0 > 3 LIMIT 10
+//
+// The grammar, keywords being case insensitive:
+//
+//	query      : SELECT item (',' item)* FROM path (',' path)* [WHERE condition] clause* [ALIGN BY DEVICE]
+//	item       : expression | (aggregation | aggregate function) '(' path ')'
+//	expression : term (('+' | '-') term)*
+//	term       : factor (('*' | '/' | '%') factor)*
+//	factor     : '-' factor | number | function '(' expression ')' | MOVING_AVG '(' expression ',' integer ')'
+//	           | scalar function '(' expression (',' expression)* ')' | '(' expression ')' | path
+//	function   : ABS | DIFF | DERIVATIVE
+//	clause     : GROUP BY '(' '[' integer ',' integer ')' ',' integer [',' integer] ')'
+//	           | ORDER BY TIME [ASC | DESC] | LIMIT integer | OFFSET integer | SLIMIT integer | SOFFSET integer
+//	condition  : and ((OR | '||') and)*
+//	and        : unary ((AND | '&&') unary)*
+//	unary      : (NOT | '!') unary | '(' condition ')' | TIME op integer | path op literal
+//	op         : '=' | '==' | '!=' | '<>' | '>' | '>=' | '<' | '<='
+//
+// The paths in the select clause are relative to every path of the from clause and may contain the wildcards * and **.
+// Once an item is more than a path, every item is computed as an expression under every path of the from clause
+// (once if all its paths start with root), see QueryExpression.SetSelectExpressions. The paths of expressions must
+// then name numeric series and there can be no aggregations. Integers are INT32 if in range, else INT64, and decimals
+// are DOUBLE.
+// The paths in the where clause must name a single series, either relative to the only path of the from clause or
+// starting with root. Literals must match the data type of the series they are compared to, which is given by
+// schema. The where clause of a query with aggregations may only compare time. Errors are reported as *ParseError.
+func Parse(sql string, schema Schema) (*query.QueryExpression, error) {
+	return ParseWithFunctions(sql, schema, nil)
+}
+
+// ParseWithFunctions is Parse with user-defined functions, which are called as the functions and the aggregations of
+// the query language: scalar functions in expressions, with any number of arguments, and aggregate functions as
+// aggregations.
+func ParseWithFunctions(sql string, schema Schema, functions *udf.Registry) (*query.QueryExpression, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{sql: sql, tokens: tokens, schema: schema, functions: functions, conditionSet: make(map[string]bool)}
+	return p.parseQuery()
+}
+
+type parser struct {
+	sql       string
+	tokens    []token
+	pos       int
+	schema    Schema
+	functions *udf.Registry
+
+	prefixes       []string
+	conditionPaths []string
+	conditionSet   map[string]bool
+	// aggregation is set for the where clause of an aggregation query, which may only compare time
+	aggregation bool
+}
+
+// selectItem is a path of the select clause relative to the from clause, with the aggregation or the user-defined
+// aggregate function applied to it if any, or an expression.
+type selectItem struct {
+	path        string
+	expr        *exprNode
+	aggregation constant.AggregationType
+	function    string
+	aggregated  bool
+	pos         int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given keyword, symbol or operator.
+func (p *parser) accept(text string) bool {
+	if p.peek().is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected(strings.ToUpper(text))
+	}
+	return nil
+}
+
+func (p *parser) errorAt(pos int, format string, args ...interface{}) error {
+	return newParseError(p.sql, pos, format, args...)
+}
+
+func (p *parser) unexpected(expected string) error {
+	t := p.peek()
+	return p.errorAt(t.pos, "expected %s but found %s", expected, t)
+}
+
+func (p *parser) parseQuery() (*query.QueryExpression, error) {
+	if err := p.expect("select"); err != nil {
+		return nil, err
+	}
+	var items []*selectItem
+	for {
+		item, err := p.parseSelectItem()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.expect("from"); err != nil {
+		return nil, err
+	}
+	for {
+		prefix, _, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		p.prefixes = append(p.prefixes, prefix)
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	exp := new(query.QueryExpression)
+	if err := p.setSelect(exp, items); err != nil {
+		return nil, err
+	}
+	if p.accept("where") {
+		p.aggregation = exp.IsAggregation()
+		f, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		exp.SetFilter(f)
+		exp.SetConditionPaths(p.conditionPaths)
+	}
+	if err := p.parseClauses(exp); err != nil {
+		return nil, err
+	}
+	if p.accept("align") {
+		if err := p.expect("by"); err != nil {
+			return nil, err
+		}
+		if err := p.expect("device"); err != nil {
+			return nil, err
+		}
+		exp.SetAlignByDevice(true)
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.unexpected("end of query")
+	}
+	return exp, nil
+}
+
+func (p *parser) parseSelectItem() (*selectItem, error) {
+	t := p.peek()
+	if t.kind == tokenIdent && p.tokens[p.pos+1].is("(") {
+		item := &selectItem{aggregated: true, pos: t.pos}
+		var ok bool
+		if item.aggregation, ok = constant.LookupAggregation(t.text); !ok {
+			if p.isFunction(t.text) {
+				return p.parseExpressionItem()
+			}
+			if _, ok := p.functions.Aggregate(t.text); !ok {
+				return nil, p.errorAt(t.pos, "unknown aggregation or function %s", t.text)
+			}
+			item.function = strings.ToLower(t.text)
+		}
+		p.next()
+		p.next()
+		path, _, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		item.path = path
+		return item, nil
+	}
+	return p.parseExpressionItem()
+}
+
+// parseExpressionItem reads an item that is a path or an expression.
+func (p *parser) parseExpressionItem() (*selectItem, error) {
+	node, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if node.path != "" {
+		return &selectItem{path: node.path, pos: node.pos}, nil
+	}
+	return &selectItem{expr: node, pos: node.pos}, nil
+}
+
+// parsePath reads a path made of identifiers, integers and wildcards separated by dots.
+func (p *parser) parsePath() (string, int, error) {
+	start := p.peek().pos
+	var nodes []string
+	for {
+		t := p.peek()
+		if t.kind != tokenIdent && t.kind != tokenInteger && !t.is(utils.ONE_LEVEL) && !t.is(utils.ANY_LEVELS) {
+			return "", 0, p.unexpected("a path")
+		}
+		p.next()
+		nodes = append(nodes, t.text)
+		if !p.accept(constant.PATH_SEPARATOR) {
+			return strings.Join(nodes, constant.PATH_SEPARATOR), start, nil
+		}
+	}
+}
+
+// setSelect sets the select paths, made of every item under every prefix, and the aggregations and aggregate
+// functions. Since the same aggregations are computed for every path, aggregated items must apply every aggregation
+// to every path.
+func (p *parser) setSelect(exp *query.QueryExpression, items []*selectItem) error {
+	for _, item := range items {
+		if item.expr != nil {
+			return p.setSelectExpressions(exp, items)
+		}
+	}
+	var suffixes, functions []string
+	var aggregations []constant.AggregationType
+	seenSuffixes := make(map[string]bool)
+	seenAggregations := make(map[string]bool)
+	seenItems := make(map[string]bool)
+	for _, item := range items {
+		if item.aggregated != items[0].aggregated {
+			return p.errorAt(item.pos, "cannot select both aggregations and raw series")
+		}
+		name := item.function
+		if name == "" {
+			name = item.aggregation.String()
+		}
+		key := name + "(" + item.path + ")"
+		if seenItems[key] {
+			return p.errorAt(item.pos, "%s is selected twice", item.path)
+		}
+		seenItems[key] = true
+		if !seenSuffixes[item.path] {
+			seenSuffixes[item.path] = true
+			suffixes = append(suffixes, item.path)
+		}
+		if !item.aggregated || seenAggregations[name] {
+			continue
+		}
+		seenAggregations[name] = true
+		if item.function != "" {
+			functions = append(functions, item.function)
+		} else {
+			aggregations = append(aggregations, item.aggregation)
+		}
+	}
+	if len(seenAggregations) > 0 && len(items) != len(seenAggregations)*len(suffixes) {
+		return p.errorAt(items[0].pos, "every aggregation must be applied to every selected path")
+	}
+
+	var paths []string
+	for _, prefix := range p.prefixes {
+		for _, suffix := range suffixes {
+			paths = append(paths, prefix+constant.PATH_SEPARATOR+suffix)
+		}
+	}
+	exp.SetSelectPaths(paths)
+	exp.SetAggregations(aggregations)
+	exp.SetAggregateFunctions(functions)
+	return nil
+}
+
+// setSelectExpressions sets an expression per item and per prefix, skipping those computed already.
+func (p *parser) setSelectExpressions(exp *query.QueryExpression, items []*selectItem) error {
+	var expressions []expression.Expression
+	seen := make(map[string]bool)
+	for _, prefix := range p.prefixes {
+		for _, item := range items {
+			if item.aggregated {
+				return p.errorAt(item.pos, "cannot select both aggregations and expressions")
+			}
+			node := item.expr
+			if node == nil {
+				node = &exprNode{pos: item.pos, path: item.path}
+			}
+			e, err := p.build(node, prefix)
+			if err != nil {
+				return err
+			}
+			if !seen[e.String()] {
+				seen[e.String()] = true
+				expressions = append(expressions, e)
+			}
+		}
+	}
+	exp.SetSelectExpressions(expressions)
+	return nil
+}
+
+func (p *parser) parseCondition() (filter.Filter, error) {
+	var filters []filter.Filter
+	for {
+		f, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+		if !p.accept("or") && !p.accept("||") {
+			break
+		}
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return &operator.OrFilter{Filters: filters}, nil
+}
+
+func (p *parser) parseAnd() (filter.Filter, error) {
+	var filters []filter.Filter
+	for {
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+		if !p.accept("and") && !p.accept("&&") {
+			break
+		}
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return &operator.AndFilter{Filters: filters}, nil
+}
+
+func (p *parser) parseUnary() (filter.Filter, error) {
+	if p.accept("not") || p.accept("!") {
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &operator.NotFilter{Filter: f}, nil
+	}
+	if p.accept("(") {
+		f, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	if p.accept("time") {
+		op, err := p.parseOperator()
+		if err != nil {
+			return nil, err
+		}
+		t := p.peek()
+		timestamp, err := p.parseInteger(64)
+		if err != nil {
+			return nil, err
+		}
+		f := newFilter(op, timestamp)
+		if f == nil {
+			return nil, p.errorAt(t.pos, "operator %s cannot be applied to time", op)
+		}
+		return filter.NewRowRecordTimeFilter(f), nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseOperator() (string, error) {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return "", p.unexpected("a comparison operator")
+	}
+	p.next()
+	return t.text, nil
+}
+
+// parseComparison reads the comparison of a series to a literal of its data type.
+func (p *parser) parseComparison() (filter.Filter, error) {
+	path, pos, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	if p.aggregation {
+		return nil, p.errorAt(pos, "aggregations can only be filtered by time, not by the values of %s", path)
+	}
+	if !strings.HasPrefix(path, "root"+constant.PATH_SEPARATOR) {
+		if len(p.prefixes) != 1 {
+			return nil, p.errorAt(pos, "%s is ambiguous with several paths in the from clause, use its full path", path)
+		}
+		path = p.prefixes[0] + constant.PATH_SEPARATOR + path
+	}
+	if utils.IsPathPattern(path) {
+		return nil, p.errorAt(pos, "%s must name a single series", path)
+	}
+	dataType := p.schema(path)
+	if dataType == constant.INVALID {
+		return nil, p.errorAt(pos, "unknown series %s", path)
+	}
+	op, err := p.parseOperator()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	ref, err := p.parseLiteral(dataType)
+	if err != nil {
+		return nil, err
+	}
+	f := newFilter(op, ref)
+	if f == nil {
+		return nil, p.errorAt(t.pos, "operator %s cannot be applied to %s series %s", op, dataType, path)
+	}
+	if !p.conditionSet[path] {
+		p.conditionSet[path] = true
+		p.conditionPaths = append(p.conditionPaths, path)
+	}
+	return filter.NewRowRecordValFilter(path, f), nil
+}
+
+// parseLiteral reads a literal and converts it to the go type of dataType.
+func (p *parser) parseLiteral(dataType constant.TSDataType) (interface{}, error) {
+	t := p.peek()
+	switch dataType {
+	case constant.INT32:
+		v, err := p.parseInteger(32)
+		return int32(v), err
+	case constant.INT64:
+		return p.parseInteger(64)
+	case constant.FLOAT:
+		v, err := p.parseNumber(32)
+		return float32(v), err
+	case constant.DOUBLE:
+		return p.parseNumber(64)
+	case constant.BOOLEAN:
+		if p.accept("true") {
+			return true, nil
+		}
+		if p.accept("false") {
+			return false, nil
+		}
+	case constant.TEXT:
+		if t.kind == tokenString {
+			p.next()
+			return t.text, nil
+		}
+	}
+	return nil, p.errorAt(t.pos, "expected a %s literal but found %s", dataType, t)
+}
+
+// parseInteger reads an optionally signed integer that fits in the given number of bits.
+func (p *parser) parseInteger(bitSize int) (int64, error) {
+	sign, t := p.parseSign()
+	if t.kind != tokenInteger {
+		return 0, p.errorAt(t.pos, "expected an integer but found %s", t)
+	}
+	v, err := strconv.ParseInt(sign+t.text, 10, bitSize)
+	if err != nil {
+		return 0, p.errorAt(t.pos, "integer %s%s out of range", sign, t.text)
+	}
+	return v, nil
+}
+
+// parseNumber reads an optionally signed integer or decimal.
+func (p *parser) parseNumber(bitSize int) (float64, error) {
+	sign, t := p.parseSign()
+	if t.kind != tokenInteger && t.kind != tokenDecimal {
+		return 0, p.errorAt(t.pos, "expected a number but found %s", t)
+	}
+	v, err := strconv.ParseFloat(sign+t.text, bitSize)
+	if err != nil || math.IsInf(v, 0) {
+		return 0, p.errorAt(t.pos, "number %s%s out of range", sign, t.text)
+	}
+	return v, nil
+}
+
+// parseSign consumes an optional sign and the token after it.
+func (p *parser) parseSign() (string, token) {
+	sign := ""
+	if p.accept("-") {
+		sign = "-"
+	} else {
+		p.accept("+")
+	}
+	t := p.peek()
+	if t.kind == tokenInteger || t.kind == tokenDecimal {
+		p.next()
+	}
+	return sign, t
+}
+
+// parseClauses reads the clauses after the where clause, each at most once and in any order.
+func (p *parser) parseClauses(exp *query.QueryExpression) error {
+	seen := make(map[string]bool)
+	var limit, offset, seriesLimit, seriesOffset int64
+	for {
+		t := p.peek()
+		clause := strings.ToUpper(t.text)
+		if t.kind != tokenIdent || (clause != "GROUP" && clause != "ORDER" && clause != "LIMIT" && clause != "OFFSET" &&
+			clause != "SLIMIT" && clause != "SOFFSET") {
+			break
+		}
+		if seen[clause] {
+			return p.errorAt(t.pos, "duplicate %s clause", clause)
+		}
+		seen[clause] = true
+		p.next()
+
+		var err error
+		switch clause {
+		case "GROUP":
+			err = p.parseGroupBy(exp)
+		case "ORDER":
+			err = p.parseOrderBy(exp)
+		case "LIMIT":
+			limit, err = p.parsePositive(clause)
+		case "OFFSET":
+			offset, err = p.parseInteger(64)
+		case "SLIMIT":
+			seriesLimit, err = p.parsePositive(clause)
+		case "SOFFSET":
+			seriesOffset, err = p.parseInteger(32)
+		}
+		if err != nil {
+			return err
+		}
+		if offset < 0 || seriesOffset < 0 {
+			return p.errorAt(t.pos, "%s cannot be negative", clause)
+		}
+	}
+	exp.SetRowLimit(limit, offset)
+	exp.SetSeriesLimit(int(seriesLimit), int(seriesOffset))
+	return nil
+}
+
+func (p *parser) parsePositive(clause string) (int64, error) {
+	t := p.peek()
+	v, err := p.parseInteger(32)
+	if err != nil {
+		return 0, err
+	}
+	if v <= 0 {
+		return 0, p.errorAt(t.pos, "%s must be positive", clause)
+	}
+	return v, nil
+}
+
+func (p *parser) parseGroupBy(exp *query.QueryExpression) error {
+	start := p.peek().pos
+	for _, text := range []string{"by", "(", "["} {
+		if err := p.expect(text); err != nil {
+			return err
+		}
+	}
+	startTime, err := p.parseInteger(64)
+	if err != nil {
+		return err
+	}
+	if err := p.expect(","); err != nil {
+		return err
+	}
+	endTime, err := p.parseInteger(64)
+	if err != nil {
+		return err
+	}
+	for _, text := range []string{")", ","} {
+		if err := p.expect(text); err != nil {
+			return err
+		}
+	}
+	t := p.peek()
+	interval, err := p.parsePositive("interval")
+	if err != nil {
+		return err
+	}
+	step := interval
+	if p.accept(",") {
+		if step, err = p.parsePositive("sliding step"); err != nil {
+			return err
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return err
+	}
+	if endTime <= startTime {
+		return p.errorAt(t.pos, "the end time of GROUP BY must be after its start time")
+	}
+	if !exp.IsAggregation() {
+		return p.errorAt(start, "GROUP BY needs aggregations in the select clause")
+	}
+	exp.SetGroupBy(&query.GroupBy{StartTime: startTime, EndTime: endTime, Interval: interval, SlidingStep: step})
+	return nil
+}
+
+func (p *parser) parseOrderBy(exp *query.QueryExpression) error {
+	if err := p.expect("by"); err != nil {
+		return err
+	}
+	if err := p.expect("time"); err != nil {
+		return err
+	}
+	if p.accept("desc") {
+		exp.SetDescending(true)
+	} else {
+		p.accept("asc")
+	}
+	return nil
+}
+
+// newFilter creates the filter comparing values to ref with op, nil if op does not apply to the type of ref.
+func newFilter(op string, ref interface{}) filter.Filter {
+	switch v := ref.(type) {
+	case int32:
+		switch op {
+		case "=", "==":
+			return &operator.IntEqFilter{Ref: v}
+		case "!=", "<>":
+			return &operator.IntNeqFilter{Ref: v}
+		case ">":
+			return &operator.IntGtFilter{Ref: v}
+		case ">=":
+			return &operator.IntGtEqFilter{Ref: v}
+		case "<":
+			return &operator.IntLtFilter{Ref: v}
+		case "<=":
+			return &operator.IntLtEqFilter{Ref: v}
+		}
+	case int64:
+		switch op {
+		case "=", "==":
+			return &operator.LongEqFilter{Ref: v}
+		case "!=", "<>":
+			return &operator.LongNeqFilter{Ref: v}
+		case ">":
+			return &operator.LongGtFilter{Ref: v}
+		case ">=":
+			return &operator.LongGtEqFilter{Ref: v}
+		case "<":
+			return &operator.LongLtFilter{Ref: v}
+		case "<=":
+			return &operator.LongLtEqFilter{Ref: v}
+		}
+	case float32:
+		switch op {
+		case "=", "==":
+			return &operator.FloatEqFilter{Ref: v}
+		case "!=", "<>":
+			return &operator.FloatNeqFilter{Ref: v}
+		case ">":
+			return &operator.FloatGtFilter{Ref: v}
+		case ">=":
+			return &operator.FloatGtEqFilter{Ref: v}
+		case "<":
+			return &operator.FloatLtFilter{Ref: v}
+		case "<=":
+			return &operator.FloatLtEqFilter{Ref: v}
+		}
+	case float64:
+		switch op {
+		case "=", "==":
+			return &operator.DoubleEqFilter{Ref: v}
+		case "!=", "<>":
+			return &operator.DoubleNeqFilter{Ref: v}
+		case ">":
+			return &operator.DoubleGtFilter{Ref: v}
+		case ">=":
+			return &operator.DoubleGtEqFilter{Ref: v}
+		case "<":
+			return &operator.DoubleLtFilter{Ref: v}
+		case "<=":
+			return &operator.DoubleLtEqFilter{Ref: v}
+		}
+	case string:
+		switch op {
+		case "=", "==":
+			return &operator.StrEqFilter{Ref: v}
+		case "!=", "<>":
+			return &operator.StrNeqFilter{Ref: v}
+		case ">":
+			return &operator.StrGtFilter{Ref: v}
+		case ">=":
+			return &operator.StrGtEqFilter{Ref: v}
+		case "<":
+			return &operator.StrLtFilter{Ref: v}
+		case "<=":
+			return &operator.StrLtEqFilter{Ref: v}
+		}
+	case bool:
+		switch op {
+		case "=", "==":
+			return &operator.BoolEqFilter{Ref: v}
+		case "!=", "<>":
+			return &operator.BoolNeqFilter{Ref: v}
+		}
+	}
+	return nil
+}
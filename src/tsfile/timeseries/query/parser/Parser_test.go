@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"tsfile/common/constant"
+	"tsfile/timeseries/query"
+)
+
+// schema holds root.d0.s0 (INT32) and root.d0.s1 (DOUBLE).
+func schema(path string) constant.TSDataType {
+	switch path {
+	case "root.d0.s0":
+		return constant.INT32
+	case "root.d0.s1":
+		return constant.DOUBLE
+	}
+	return constant.INVALID
+}
+
+func TestParse(t *testing.T) {
+	exp, err := Parse("select s0, s1 from root.d0 where time >= 90 and s0 > 92 order by time desc limit 3 "+
+		"offset 1", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(exp.SelectPaths()) != "[root.d0.s0 root.d0.s1]" ||
+		fmt.Sprint(exp.ConditionPaths()) != "[root.d0.s0]" {
+		t.Fatal(fmt.Sprintf("Unexpected paths %v, %v", exp.SelectPaths(), exp.ConditionPaths()))
+	}
+	if exp.Filter() == nil || !exp.Descending() || exp.RowLimit() != 3 || exp.RowOffset() != 1 {
+		t.Fatal(fmt.Sprintf("Unexpected clauses %+v", exp))
+	}
+
+	exp, err = Parse("SELECT count(s0), max_value(s0)\nFROM root.d0\nGROUP BY ([0, 100), 50)", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(exp.Aggregations()) != fmt.Sprint([]constant.AggregationType{constant.COUNT,
+		constant.MAX_VALUE}) || *exp.GroupBy() != *query.NewGroupBy(0, 100, 50) {
+		t.Fatal(fmt.Sprintf("Unexpected aggregations %v by %+v", exp.Aggregations(), exp.GroupBy()))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		sql string
+		// the text of the query the error must point at
+		at string
+	}{
+		{"SELECT s0 FROM root.d0 WHERE s0 > 3.5", "3.5"},
+		{"SELECT s0 FROM root.d0 WHERE s1 > 'a'", "'a'"},
+		{"SELECT s0 FROM root.d0 WHERE s9 > 3", "s9"},
+		{"SELECT s0 FROM root.d0, root.d1 WHERE s0 > 3", "s0 >"},
+		{"SELECT s0 FROM root.d0 WHERE time > 1 AND", ""},
+		{"SELECT s0 FROM root.d0 WHERE s0 >> 3", "> 3"},
+		{"SELECT s0 FROM root.d0 WHERE s0 > 99999999999", "99999999999"},
+		{"SELECT s0\nFROM root.d0\nLIMIT x", "x"},
+		{"SELECT s0 FROM root.d0 LIMIT 1 LIMIT 2", "LIMIT 2"},
+		{"SELECT foo(s0) FROM root.d0", "foo"},
+		{"SELECT count(s0), s1 FROM root.d0", "s1"},
+		{"SELECT count(s0), sum(s1) FROM root.d0", "count"},
+		{"SELECT s0 FROM root.d0 GROUP BY ([0, 100), 10)", "BY ("},
+		{"SELECT s0 FROM root.d0 WHERE s0 > 1 $", "$"},
+		{"SELECT s0 FROM root.d0 WHERE s0 = 'abc", "'abc"},
+		// aggregations are only filtered by time
+		{"SELECT count(s0) FROM root.d0 WHERE s0 > 3", "s0 > 3"},
+		{"SELECT count(s0) FROM root.d0 WHERE time > 3 AND NOT s1 > 3 GROUP BY ([0, 100), 10)", "s1 > 3"},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.sql, schema)
+		parseError, ok := err.(*ParseError)
+		if !ok {
+			t.Fatal(fmt.Sprintf("%s: expected a ParseError got %v", c.sql, err))
+		}
+		expected := strings.LastIndex(c.sql, c.at)
+		if c.at == "" {
+			expected = len(c.sql)
+		}
+		if parseError.Offset != expected {
+			t.Fatal(fmt.Sprintf("%s: expected an error at %d got %v", c.sql, expected, err))
+		}
+	}
+	_, err := Parse("SELECT s0\nFROM root.d0\nLIMIT x", schema)
+	if err.Error() != "line 3, column 7: expected an integer but found \"x\"" {
+		t.Fatal(fmt.Sprintf("Unexpected error message %v", err))
+	}
+}
@@ -0,0 +1,117 @@
+package similarity
+
+import (
+	"math"
+	"tsfile/common/constant"
+)
+
+// Distance returns the distance between a pattern and a window of the same length, or +Inf if it is at least limit, so
+// that windows that cannot be among the best matches of a search are abandoned early. band is the number of points DTW
+// may shift a point by, see DTW.
+func Distance(measure constant.DistanceMeasure, pattern []float64, window []float64, band int, limit float64) float64 {
+	if measure == constant.DTW {
+		return DTW(pattern, window, band, limit)
+	}
+	return Euclidean(pattern, window, limit)
+}
+
+// Euclidean returns the euclidean distance between a pattern and a window of the same length, or +Inf if it is at least
+// limit.
+func Euclidean(pattern []float64, window []float64, limit float64) float64 {
+	limit *= limit
+	var sum float64
+	for i := range pattern {
+		d := pattern[i] - window[i]
+		sum += d * d
+		if sum >= limit {
+			return math.Inf(1)
+		}
+	}
+	return math.Sqrt(sum)
+}
+
+// DTW returns the dynamic time warping distance between a pattern and a window of the same length: the square root of
+// the least sum of the squared differences of matched points, the i-th point of one matching points of the other
+// within [i-band, i+band] (the Sakoe-Chiba band), or +Inf if it is at least limit. A band of 0 gives the euclidean
+// distance.
+func DTW(pattern []float64, window []float64, band int, limit float64) float64 {
+	limit *= limit
+	m := len(pattern)
+	previous, current := make([]float64, m), make([]float64, m)
+	for i := 0; i < m; i++ {
+		for j := range current {
+			current[j] = math.Inf(1)
+		}
+		rowMin := math.Inf(1)
+		for j := maxInt(0, i-band); j <= minInt(m-1, i+band); j++ {
+			var best float64
+			switch {
+			case i == 0 && j == 0:
+				best = 0
+			case i == 0:
+				best = current[j-1]
+			case j == 0:
+				best = previous[j]
+			default:
+				best = math.Min(previous[j-1], math.Min(previous[j], current[j-1]))
+			}
+			d := pattern[i] - window[j]
+			current[j] = best + d*d
+			rowMin = math.Min(rowMin, current[j])
+		}
+		// every warping path goes through this row
+		if rowMin >= limit {
+			return math.Inf(1)
+		}
+		previous, current = current, previous
+	}
+	return math.Sqrt(previous[m-1])
+}
+
+// LowerBound returns a lower bound of both the euclidean and the DTW distances between a pattern and any window whose
+// values are within [min, max], since every point of the pattern is matched to at least one of them.
+func LowerBound(pattern []float64, min float64, max float64) float64 {
+	var sum float64
+	for _, v := range pattern {
+		if v < min {
+			sum += (min - v) * (min - v)
+		} else if v > max {
+			sum += (v - max) * (v - max)
+		}
+	}
+	return math.Sqrt(sum)
+}
+
+// ZNormalize writes into dst, as long as values, the values shifted and scaled to a mean of 0 and a standard deviation
+// of 1, all 0 if they are constant, so that windows match a pattern whatever their offset and amplitude.
+func ZNormalize(dst []float64, values []float64) {
+	var sum, sumOfSquares float64
+	for _, v := range values {
+		sum += v
+		sumOfSquares += v * v
+	}
+	n := float64(len(values))
+	mean := sum / n
+	std := math.Sqrt(math.Max(sumOfSquares/n-mean*mean, 0))
+	for i, v := range values {
+		if std > 1e-12*math.Max(math.Abs(mean), 1) {
+			dst[i] = (v - mean) / std
+		} else {
+			dst[i] = 0
+		}
+	}
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,61 @@
+package similarity
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"tsfile/common/constant"
+)
+
+func TestDistance(t *testing.T) {
+	pattern := []float64{0, 20, 40, 60, 40, 20, 0, -20, -40, -20}
+	window := []float64{1, 21, 41, 61, 41, 21, 1, -19, -39, -19}
+	if d := Distance(constant.EUCLIDEAN, pattern, window, 0, math.Inf(1)); math.Abs(d-math.Sqrt(10)) > 1e-9 {
+		t.Fatal(fmt.Sprintf("Expected sqrt(10) got %v", d))
+	}
+	// the windows at least as far as the limit are abandoned
+	for _, measure := range []constant.DistanceMeasure{constant.EUCLIDEAN, constant.DTW} {
+		if d := Distance(measure, pattern, window, 2, 3); !math.IsInf(d, 1) {
+			t.Fatal(fmt.Sprintf("%v: expected the window to be abandoned got %v", measure, d))
+		}
+	}
+
+	// DTW matches a pattern shifted in time more closely than the euclidean distance, and is the euclidean distance
+	// without a band
+	shifted := append(append([]float64{}, pattern[1:]...), pattern[0])
+	euclidean := Euclidean(pattern, shifted, math.Inf(1))
+	if DTW(pattern, shifted, 1, math.Inf(1)) >= euclidean {
+		t.Fatal("Expected DTW to be closer than the euclidean distance")
+	}
+	if d := DTW(pattern, shifted, 0, math.Inf(1)); math.Abs(d-euclidean) > 1e-9 {
+		t.Fatal(fmt.Sprintf("Expected DTW without a band to be %v got %v", euclidean, d))
+	}
+
+	// no window within the value range of the bound is closer than it
+	bound := LowerBound(pattern, -10, 30)
+	for _, measure := range []constant.DistanceMeasure{constant.EUCLIDEAN, constant.DTW} {
+		for _, window := range [][]float64{{-10, -10, -10, -10, -10, -10, -10, -10, -10, -10},
+			{0, 20, 30, 30, 30, 20, 0, -10, -10, -10}, {30, 30, 30, 30, 30, 30, 30, 30, 30, 30}} {
+			if d := Distance(measure, pattern, window, 2, math.Inf(1)); d < bound {
+				t.Fatal(fmt.Sprintf("%v: expected %v to be at least %v away got %v", measure, window, bound, d))
+			}
+		}
+	}
+	if LowerBound(pattern, -40, 60) != 0 {
+		t.Fatal("Expected no bound for the range of the pattern")
+	}
+
+	normalized := make([]float64, len(pattern))
+	ZNormalize(normalized, window)
+	normalizedPattern := make([]float64, len(pattern))
+	ZNormalize(normalizedPattern, pattern)
+	if d := Euclidean(normalizedPattern, normalized, math.Inf(1)); d > 1e-9 {
+		t.Fatal(fmt.Sprintf("Expected the offset window to match once normalized got %v", d))
+	}
+	ZNormalize(normalized, []float64{3, 3, 3, 3, 3, 3, 3, 3, 3, 3})
+	for _, v := range normalized {
+		if v != 0 {
+			t.Fatal(fmt.Sprintf("Expected a constant window to normalize to 0 got %v", normalized))
+		}
+	}
+}
@@ -22,7 +22,7 @@ func (gen *RowRecordTimestampGenerator) Close() {
 }
 
 func NewRowRecordTimestampGenerator(paths []string, readerMap map[string]reader.TimeValuePairReader, filter filter.Filter) *RowRecordTimestampGenerator {
-	reader := basic.NewRecordReader(paths, readerMap)
+	reader := basic.NewRecordReader(paths, readerMap, false)
 	return &RowRecordTimestampGenerator{reader: reader, filter: filter, currTime: constant.INVALID_TIMESTAMP, exhausted:false}
 }
 
@@ -0,0 +1,32 @@
+package udf
+
+import "tsfile/common/constant"
+
+// Scalar is a user-defined function computing a value from the values of its arguments at the same timestamp, e.g. the
+// power of a device from its voltage and current. It is called in expressions, see expression.Call.
+type Scalar interface {
+	// Evaluate returns the result for the given arguments, some of which may be nil where a series has no value at
+	// timestamp, or nil if there is none.
+	Evaluate(timestamp int64, args []interface{}) interface{}
+}
+
+// ScalarFunc makes a function a Scalar.
+type ScalarFunc func(timestamp int64, args []interface{}) interface{}
+
+func (f ScalarFunc) Evaluate(timestamp int64, args []interface{}) interface{} {
+	return f(timestamp, args)
+}
+
+// Aggregate is a user-defined function computing a value from the points of a series, per group by window in group by
+// queries, e.g. the energy consumed given the readings of a power meter. A new Aggregate is created for every series
+// and window, or for parts of windows that are merged afterwards.
+type Aggregate interface {
+	// Init is called once before the first point, with the data type of the series.
+	Init(dataType constant.TSDataType)
+	// Add adds a point, the points coming in ascending time order.
+	Add(timestamp int64, value interface{})
+	// Merge adds the points added to other, which are all later than those added to this Aggregate.
+	Merge(other Aggregate)
+	// Result returns the value over the points added so far, nil if there is none.
+	Result() interface{}
+}
@@ -0,0 +1,74 @@
+package udf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Registry holds user-defined functions by name, names being case insensitive. The zero value is an empty registry
+// and a nil *Registry has no functions.
+type Registry struct {
+	scalars    map[string]Scalar
+	aggregates map[string]func() Aggregate
+}
+
+// RegisterScalar adds a scalar function, whose name must be an identifier not taken by another function.
+func (r *Registry) RegisterScalar(name string, f Scalar) error {
+	name, err := r.checkName(name)
+	if err != nil {
+		return err
+	}
+	if r.scalars == nil {
+		r.scalars = make(map[string]Scalar)
+	}
+	r.scalars[name] = f
+	return nil
+}
+
+// RegisterAggregate adds an aggregate function given how to create it, as RegisterScalar.
+func (r *Registry) RegisterAggregate(name string, newAggregate func() Aggregate) error {
+	name, err := r.checkName(name)
+	if err != nil {
+		return err
+	}
+	if r.aggregates == nil {
+		r.aggregates = make(map[string]func() Aggregate)
+	}
+	r.aggregates[name] = newAggregate
+	return nil
+}
+
+// checkName returns the name functions are kept under.
+func (r *Registry) checkName(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("invalid function name %q", name)
+	}
+	for i, c := range name {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			return "", fmt.Errorf("invalid function name %q", name)
+		}
+	}
+	name = strings.ToLower(name)
+	_, isScalar := r.Scalar(name)
+	_, isAggregate := r.Aggregate(name)
+	if isScalar || isAggregate {
+		return "", fmt.Errorf("function %s is already registered", name)
+	}
+	return name, nil
+}
+
+func (r *Registry) Scalar(name string) (Scalar, bool) {
+	if r == nil {
+		return nil, false
+	}
+	f, ok := r.scalars[strings.ToLower(name)]
+	return f, ok
+}
+
+func (r *Registry) Aggregate(name string) (func() Aggregate, bool) {
+	if r == nil {
+		return nil, false
+	}
+	newAggregate, ok := r.aggregates[strings.ToLower(name)]
+	return newAggregate, ok
+}
@@ -2,9 +2,11 @@ package read
 
 import (
 	//"bufio"
+	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
-	"log"
 	"os"
 	"tsfile/common/conf"
 	"tsfile/common/constant"
@@ -20,32 +22,73 @@ type TsFileSequenceReader struct {
 	size          int64
 	metadata_pos  int64
 	metadata_size int
+	// pagesRead counts the calls to ReadRaw and ReadPage
+	pagesRead int64
 }
 
-func (f *TsFileSequenceReader) Open(file string) {
+// ErrNotTsFile is wrapped by the error opening a file that does not start with the TsFile magic string.
+var ErrNotTsFile = errors.New("not a TsFile")
+
+// Open opens a TsFile, a *utils.CorruptedError if it is not a TsFile or is truncated.
+func (f *TsFileSequenceReader) Open(file string) error {
 	f.fileName = file
 
 	fin, err := os.Open(file)
-	if err == nil {
-		stat, _ := fin.Stat()
-		f.size = stat.Size()
-
-		// get matadata pos&size
-		buf := make([]byte, 4)
-		_, err := fin.ReadAt(buf, f.size-int64(len(conf.MAGIC_STRING))-4)
-		if err == nil {
-			f.metadata_size = int(binary.BigEndian.Uint32(buf))
-			f.metadata_pos = f.size - int64(len(conf.MAGIC_STRING)) - 4 - int64(f.metadata_size)
-		}
+	if err != nil {
+		return err
+	}
+	stat, err := fin.Stat()
+	if err != nil {
+		fin.Close()
+		return err
+	}
+	f.reader = utils.NewFileReader(fin)
+	if err := f.open(stat.Size()); err != nil {
+		fin.Close()
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
 
-		// get pointer ready for reading RowGroupHeader
-		f.reader = utils.NewFileReader(fin)
-		f.reader.Seek(int64(len(conf.MAGIC_STRING)), io.SeekStart)
+// OpenReaderAt opens the TsFile held by the size bytes of reader, e.g. a blob, a member of an archive or data received
+// over the network. Close leaves reader open.
+func (f *TsFileSequenceReader) OpenReaderAt(reader io.ReaderAt, size int64) error {
+	f.reader = utils.NewReaderAtFileReader(reader, size)
+	return f.open(size)
+}
 
-	} else {
-		log.Println("Failed to open file: " + file)
-		panic(err)
+// OpenBytes opens the TsFile held in memory by data, which must not be modified while it is read.
+func (f *TsFileSequenceReader) OpenBytes(data []byte) error {
+	return f.OpenReaderAt(bytes.NewReader(data), int64(len(data)))
+}
+
+// open checks the magic strings and locates the metadata at the end of the file.
+func (f *TsFileSequenceReader) open(size int64) error {
+	f.size = size
+	magicSize := int64(len(conf.MAGIC_STRING))
+
+	if size < magicSize || f.ReadHeadMagic() != conf.MAGIC_STRING {
+		return &utils.CorruptedError{Structure: "head magic", Offset: 0, Err: ErrNotTsFile}
 	}
+	if size < 2*magicSize+4 || f.ReadTailMagic() != conf.MAGIC_STRING {
+		return &utils.CorruptedError{Structure: "tail magic", Offset: size - magicSize,
+			Err: errors.New("missing, the file may be truncated")}
+	}
+
+	// get matadata pos&size
+	buf := f.reader.ReadAt(4, size-magicSize-4)
+	if buf == nil {
+		return &utils.CorruptedError{Structure: "metadata size", Offset: size - magicSize - 4, Err: f.reader.Err()}
+	}
+	f.metadata_size = int(binary.BigEndian.Uint32(buf))
+	f.metadata_pos = size - magicSize - 4 - int64(f.metadata_size)
+	if f.metadata_size < 0 || f.metadata_pos < magicSize {
+		return &utils.CorruptedError{Structure: "metadata size", Offset: size - magicSize - 4,
+			Err: fmt.Errorf("invalid metadata size %d", f.metadata_size)}
+	}
+
+	f.reader.Seek(magicSize, io.SeekStart)
+	return nil
 }
 
 func (f *TsFileSequenceReader) ReadHeadMagic() string {
@@ -61,80 +104,139 @@ func (f *TsFileSequenceReader) ReadTailMagic() string {
 	return string(buf[:])
 }
 
-func (f *TsFileSequenceReader) ReadFileMetadata() *metadata.FileMetaData {
+// ReadFileMetadata returns the metadata of the file, a *utils.CorruptedError if it is corrupted.
+func (f *TsFileSequenceReader) ReadFileMetadata() (*metadata.FileMetaData, error) {
 	fileMetadata := new(metadata.FileMetaData)
 
 	data := f.reader.ReadAt(f.metadata_size, f.metadata_pos)
-	fileMetadata.Deserialize(data)
+	if data == nil {
+		return nil, &utils.CorruptedError{Structure: "FileMetaData", Offset: f.metadata_pos, Err: f.reader.Err()}
+	}
+	if err := fileMetadata.Deserialize(data); err != nil {
+		var corrupted *utils.CorruptedError
+		if errors.As(err, &corrupted) {
+			corrupted.Offset += f.metadata_pos
+		}
+		return nil, err
+	}
 
-	return fileMetadata
+	return fileMetadata, nil
 }
 
 func (f *TsFileSequenceReader) HasNextRowGroup() bool {
 	return f.reader.Pos() < f.metadata_pos
 }
 
-func (f *TsFileSequenceReader) ReadRowGroupHeader() *header.RowGroupHeader {
+func (f *TsFileSequenceReader) ReadRowGroupHeader() (*header.RowGroupHeader, error) {
 	header := new(header.RowGroupHeader)
-	header.Deserialize(f.reader)
+	if err := header.Deserialize(f.reader); err != nil {
+		return nil, err
+	}
 
-	return header
+	return header, nil
 }
 
-func (f *TsFileSequenceReader) ReadChunkHeader() *header.ChunkHeader {
+func (f *TsFileSequenceReader) ReadChunkHeader() (*header.ChunkHeader, error) {
 	header := new(header.ChunkHeader)
-	header.Deserialize(f.reader)
+	if err := header.Deserialize(f.reader); err != nil {
+		return nil, err
+	}
 
-	return header
+	return header, nil
 }
 
-func (f *TsFileSequenceReader) ReadChunkHeaderAt(offset int64) *header.ChunkHeader {
+func (f *TsFileSequenceReader) ReadChunkHeaderAt(offset int64) (*header.ChunkHeader, error) {
 	f.reader.Seek(offset, io.SeekStart)
 	return f.ReadChunkHeader()
 }
 
-func (f *TsFileSequenceReader) ReadChunk(header *header.ChunkHeader) []byte {
-	return f.reader.ReadSlice(header.GetDataSize())
+func (f *TsFileSequenceReader) ReadChunk(header *header.ChunkHeader) ([]byte, error) {
+	return f.readSlice("chunk", header.GetDataSize())
 }
 
-func (f *TsFileSequenceReader) ReadChunkAt(header *header.ChunkHeader, positionOfChunkHeader int64) []byte {
+func (f *TsFileSequenceReader) ReadChunkAt(header *header.ChunkHeader, positionOfChunkHeader int64) ([]byte, error) {
 	f.reader.Seek(positionOfChunkHeader, io.SeekStart)
 	return f.ReadChunk(header)
 }
 
-func (f *TsFileSequenceReader) ReadChunkAndHeader(position int64) []byte {
-	header := f.ReadChunkHeaderAt(position)
+func (f *TsFileSequenceReader) ReadChunkAndHeader(position int64) ([]byte, error) {
+	header, err := f.ReadChunkHeaderAt(position)
+	if err != nil {
+		return nil, err
+	}
 	length := header.GetSerializedSize() + header.GetDataSize()
 
-	return f.reader.ReadSlice(length)
+	return f.readSlice("chunk", length)
 }
 
-func (f *TsFileSequenceReader) ReadRaw(position int64, length int) []byte {
+// ReadRaw returns a copy of the bytes at [position, position+length), which stays valid after later reads. Page data
+// must not alias the read buffer since readers of several series decode their current pages concurrently.
+func (f *TsFileSequenceReader) ReadRaw(position int64, length int) ([]byte, error) {
+	f.pagesRead++
 	f.reader.Seek(position, io.SeekStart)
-	return f.reader.ReadSlice(length)
+	slice, err := f.readSlice("page", length)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, length)
+	copy(data, slice)
+	return data, nil
+}
+
+// readSlice reads length bytes of a structure at the current position, a *utils.CorruptedError if the file is
+// truncated.
+func (f *TsFileSequenceReader) readSlice(structure string, length int) ([]byte, error) {
+	offset := f.reader.Pos()
+	data := f.reader.ReadSlice(length)
+	if data == nil {
+		return nil, &utils.CorruptedError{Structure: structure, Offset: offset, Err: f.reader.Err()}
+	}
+	return data, nil
 }
 
-func (f *TsFileSequenceReader) ReadPageHeader(dataType constant.TSDataType) *header.PageHeader {
+func (f *TsFileSequenceReader) ReadPageHeader(dataType constant.TSDataType) (*header.PageHeader, error) {
 	header := new(header.PageHeader)
-	header.Deserialize(f.reader, dataType)
+	if err := header.Deserialize(f.reader, dataType); err != nil {
+		return nil, err
+	}
 
-	return header
+	return header, nil
 }
 
-func (f *TsFileSequenceReader) ReadPageHeaderAt(dataType constant.TSDataType, offset int64) *header.PageHeader {
+func (f *TsFileSequenceReader) ReadPageHeaderAt(dataType constant.TSDataType, offset int64) (*header.PageHeader, error) {
 	f.reader.Seek(offset, io.SeekStart)
 	return f.ReadPageHeader(dataType)
 }
 
-func (f *TsFileSequenceReader) ReadPage(header *header.PageHeader, compression constant.CompressionType) []byte {
-	unCompressor := compress.GetDecompressor(compression)
-	data := f.reader.ReadSlice(int(header.GetCompressedSize()))
+// ReadPage returns the uncompressed data of the page following header, a *utils.CorruptedError if it is truncated or
+// cannot be decompressed.
+func (f *TsFileSequenceReader) ReadPage(header *header.PageHeader, compression constant.CompressionType) ([]byte, error) {
+	f.pagesRead++
+	offset := f.reader.Pos()
+	unCompressor, err := compress.NewDecompressor(compression)
+	if err != nil {
+		return nil, &utils.CorruptedError{Structure: "page", Offset: offset, Err: err}
+	}
+	data, err := f.readSlice("page", int(header.GetCompressedSize()))
+	if err != nil {
+		return nil, err
+	}
 
-	if unCompressedData, err := unCompressor.Decompress(data); err == nil {
-		return unCompressedData
-	} else {
-		panic(err)
+	unCompressedData, err := unCompressor.Decompress(data)
+	if err != nil {
+		return nil, &utils.CorruptedError{Structure: "page", Offset: offset, Err: err}
 	}
+	return unCompressedData, nil
+}
+
+// BytesRead returns the number of bytes read from the file so far, headers and metadata included.
+func (f *TsFileSequenceReader) BytesRead() int64 {
+	return f.reader.BytesRead()
+}
+
+// PagesRead returns the number of pages whose data was read so far.
+func (f *TsFileSequenceReader) PagesRead() int64 {
+	return f.pagesRead
 }
 
 func (f *TsFileSequenceReader) Pos() int64 {
@@ -0,0 +1,35 @@
+package basic
+
+import (
+	"context"
+	"tsfile/timeseries/read/datatype"
+	"tsfile/timeseries/read/reader"
+)
+
+// ContextSeriesReader stops reading another reader as soon as its context is done: HasNext reports false and Next
+// returns the error of the context, so no further page is read.
+type ContextSeriesReader struct {
+	reader.TimeValuePairReader
+	ctx context.Context
+}
+
+func NewContextSeriesReader(ctx context.Context, r reader.TimeValuePairReader) *ContextSeriesReader {
+	return &ContextSeriesReader{TimeValuePairReader: r, ctx: ctx}
+}
+
+func (r *ContextSeriesReader) HasNext() bool {
+	return r.ctx.Err() == nil && r.TimeValuePairReader.HasNext()
+}
+
+func (r *ContextSeriesReader) Next() (*datatype.TimeValuePair, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.TimeValuePairReader.Next()
+}
+
+func (r *ContextSeriesReader) Skip() {
+	if r.ctx.Err() == nil {
+		r.TimeValuePairReader.Skip()
+	}
+}
@@ -13,6 +13,8 @@ type FilteredRowReader struct {
 
 	row *datatype.RowRecord
 	exhausted bool
+	// err is the error reading the rows failed with, returned by the next call to Next
+	err error
 }
 
 func (r *FilteredRowReader) fillCache() {
@@ -23,6 +25,7 @@ func (r *FilteredRowReader) fillCache() {
 			row, err := r.reader.Next()
 			if err != nil {
 				r.row = nil
+				r.err = err
 				return
 			}
 			if r.filter == nil || r.filter.Satisfy(row) {
@@ -40,6 +43,9 @@ func (r *FilteredRowReader) HasNext() bool {
 	}
 	if r.row == nil {
 		r.fillCache()
+		if r.err != nil {
+			return true
+		}
 		if r.row == nil {
 			r.exhausted = true
 			return false
@@ -50,7 +56,15 @@ func (r *FilteredRowReader) HasNext() bool {
 
 func (r *FilteredRowReader) Next() (*datatype.RowRecord, error) {
 	if r.row == nil {
-		r.fillCache()
+		if r.err == nil {
+			r.fillCache()
+		}
+		if r.err != nil {
+			err := r.err
+			r.err = nil
+			r.exhausted = true
+			return nil, err
+		}
 		if r.row == nil {
 			r.exhausted = true
 			return nil, errors.New("RowReader exhausted")
@@ -66,8 +80,9 @@ func (r *FilteredRowReader) Close() {
 	r.reader.Close()
 }
 
-func NewFilteredRowReader(paths []string, readerMap map[string]reader.TimeValuePairReader, filter filter.Filter) *FilteredRowReader {
-	rowReader := NewRecordReader(paths, readerMap)
+func NewFilteredRowReader(paths []string, readerMap map[string]reader.TimeValuePairReader, filter filter.Filter,
+	descending bool) *FilteredRowReader {
+	rowReader := NewRecordReader(paths, readerMap, descending)
 	dataSet := &FilteredRowReader{reader: rowReader, filter: filter, exhausted:false}
 	return dataSet
 }
@@ -0,0 +1,111 @@
+package basic
+
+import (
+	"errors"
+	"tsfile/timeseries/read/datatype"
+	"tsfile/timeseries/read/reader"
+)
+
+// MergeSeriesReader merges readers of the same series, e.g. from several files, into one series in time order. When
+// several readers hold a point at the same timestamp, the one of the reader earliest in the list is kept.
+type MergeSeriesReader struct {
+	readers    []reader.TimeValuePairReader
+	descending bool
+
+	// the next point of every reader, nil if not read yet or exhausted
+	heads []*datatype.TimeValuePair
+	// err is the error a reader failed with in HasNext, returned by the next call to Next, after which the series ends
+	err    error
+	failed bool
+}
+
+func NewMergeSeriesReader(readers []reader.TimeValuePairReader, descending bool) *MergeSeriesReader {
+	return &MergeSeriesReader{readers: readers, descending: descending,
+		heads: make([]*datatype.TimeValuePair, len(readers))}
+}
+
+func (r *MergeSeriesReader) Read(data []byte) {
+	panic("implement me")
+}
+
+func (r *MergeSeriesReader) Skip() {
+	r.Next()
+}
+
+func (r *MergeSeriesReader) HasNext() bool {
+	if r.err != nil {
+		return true
+	}
+	if r.failed {
+		return false
+	}
+	if err := r.fillHeads(); err != nil {
+		r.err = err
+		return true
+	}
+	return r.nextIndex() != -1
+}
+
+func (r *MergeSeriesReader) Next() (*datatype.TimeValuePair, error) {
+	if r.failed {
+		return nil, errors.New("series exhausted")
+	}
+	if r.err == nil {
+		r.err = r.fillHeads()
+	}
+	if r.err != nil {
+		// the points after the failing one are not read
+		err := r.err
+		r.err = nil
+		r.failed = true
+		return nil, err
+	}
+	index := r.nextIndex()
+	if index == -1 {
+		return nil, errors.New("series exhausted")
+	}
+	ret := r.heads[index]
+	// the points of the other readers at the same timestamp are dropped
+	for i, head := range r.heads {
+		if head != nil && head.Timestamp == ret.Timestamp {
+			r.heads[i] = nil
+		}
+	}
+	return ret, nil
+}
+
+func (r *MergeSeriesReader) Close() {
+	for _, rd := range r.readers {
+		rd.Close()
+	}
+	r.heads = make([]*datatype.TimeValuePair, len(r.readers))
+}
+
+func (r *MergeSeriesReader) fillHeads() error {
+	for i, rd := range r.readers {
+		if r.heads[i] == nil && rd.HasNext() {
+			tv, err := rd.Next()
+			if err != nil {
+				return err
+			}
+			r.heads[i] = tv
+		}
+	}
+	return nil
+}
+
+// nextIndex returns the index of the reader whose head comes first, the earliest reader among equal heads, or -1 if
+// all readers are exhausted.
+func (r *MergeSeriesReader) nextIndex() int {
+	index := -1
+	for i, head := range r.heads {
+		if head == nil {
+			continue
+		}
+		if index == -1 || (r.descending && head.Timestamp > r.heads[index].Timestamp) ||
+			(!r.descending && head.Timestamp < r.heads[index].Timestamp) {
+			index = i
+		}
+	}
+	return index
+}
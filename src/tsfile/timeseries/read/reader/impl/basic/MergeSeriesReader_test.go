@@ -0,0 +1,73 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"tsfile/timeseries/read/datatype"
+	"tsfile/timeseries/read/reader"
+)
+
+// pairsReader returns points at the given timestamps, then fails with err if it is not nil.
+type pairsReader struct {
+	times []int64
+	err   error
+	next  int
+}
+
+func (r *pairsReader) Read(data []byte) {
+}
+
+func (r *pairsReader) HasNext() bool {
+	return r.next < len(r.times) || (r.next == len(r.times) && r.err != nil)
+}
+
+func (r *pairsReader) Next() (*datatype.TimeValuePair, error) {
+	r.next++
+	if r.next > len(r.times) {
+		return nil, r.err
+	}
+	return &datatype.TimeValuePair{Timestamp: r.times[r.next-1], Value: int32(r.next)}, nil
+}
+
+func (r *pairsReader) Skip() {
+	r.Next()
+}
+
+func (r *pairsReader) Close() {
+}
+
+func TestMergeSeriesReader(t *testing.T) {
+	merged := NewMergeSeriesReader([]reader.TimeValuePairReader{&pairsReader{times: []int64{1, 3, 5}},
+		&pairsReader{times: []int64{2, 3, 6}}}, false)
+	var times []int64
+	for merged.HasNext() {
+		pair, err := merged.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		times = append(times, pair.Timestamp)
+	}
+	if fmt.Sprint(times) != "[1 2 3 5 6]" {
+		t.Fatal(fmt.Sprintf("Expected [1 2 3 5 6] got %v", times))
+	}
+
+	// a reader failing is reported by the next call to Next rather than ending the series
+	failure := errors.New("cannot read the next page")
+	merged = NewMergeSeriesReader([]reader.TimeValuePairReader{&pairsReader{times: []int64{1, 3, 5}},
+		&pairsReader{times: []int64{2}, err: failure}}, false)
+	for _, expected := range []int64{1, 2} {
+		if pair, err := merged.Next(); err != nil || pair.Timestamp != expected {
+			t.Fatal(fmt.Sprintf("Expected a point at %d got %v, %v", expected, pair, err))
+		}
+	}
+	if !merged.HasNext() {
+		t.Fatal("Expected the failure to be reported")
+	}
+	if _, err := merged.Next(); err != failure {
+		t.Fatal(fmt.Sprintf("Expected the read failure got %v", err))
+	}
+	if merged.HasNext() {
+		t.Fatal("Expected no point after the failure")
+	}
+}
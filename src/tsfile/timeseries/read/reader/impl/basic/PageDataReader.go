@@ -3,6 +3,7 @@ package basic
 import (
 	_ "bytes"
 	_ "encoding/binary"
+	"fmt"
 	"tsfile/common/constant"
 	"tsfile/common/utils"
 	"tsfile/encoding/decoder"
@@ -13,31 +14,99 @@ type PageDataReader struct {
 	DataType     constant.TSDataType
 	ValueDecoder decoder.Decoder
 	TimeDecoder  decoder.Decoder
+	// Descending yields the points of the page from the last one to the first one, the page being decoded at once
+	Descending bool
+
+	pairs []*datatype.TimeValuePair
+	// err is the error the page cannot be decoded with, returned once by Next
+	err error
 }
 
+// Read starts decoding a page. The errors the page cannot be decoded with are returned by Next.
 func (r *PageDataReader) Read(data []byte) {
 	reader := utils.NewBytesReader(data)
 	timeInputStreamLength := reader.ReadUnsignedVarInt()
-	pos := reader.Pos()
+	timeData := reader.ReadSlice(timeInputStreamLength)
+	r.pairs = r.pairs[:0]
+	r.err = reader.Err()
+	if r.err != nil {
+		r.err = fmt.Errorf("invalid length of the timestamps: %w", r.err)
+		return
+	}
+
+	r.TimeDecoder.Init(timeData)
+	r.ValueDecoder.Init(reader.Remaining())
+	if r.Descending {
+		for r.TimeDecoder.HasNext() && r.ValueDecoder.HasNext() {
+			r.pairs = append(r.pairs, &datatype.TimeValuePair{Timestamp: r.TimeDecoder.NextInt64(),
+				Value: r.ValueDecoder.Next()})
+		}
+		if r.err = r.decodeErr(); r.err != nil {
+			r.pairs = r.pairs[:0]
+		}
+	}
+}
 
-	r.TimeDecoder.Init(data[pos : timeInputStreamLength+pos])
-	r.ValueDecoder.Init(data[timeInputStreamLength+pos:])
+// decodeErr returns the error decoding the timestamps or the values failed with.
+func (r *PageDataReader) decodeErr() error {
+	if err := r.TimeDecoder.Err(); err != nil {
+		return fmt.Errorf("cannot decode timestamps: %w", err)
+	}
+	if err := r.ValueDecoder.Err(); err != nil {
+		return fmt.Errorf("cannot decode values: %w", err)
+	}
+	return nil
 }
 
 func (r *PageDataReader) HasNext() bool {
+	if r.err != nil {
+		return true
+	}
+	if r.Descending {
+		return len(r.pairs) > 0
+	}
 	return r.TimeDecoder.HasNext() && r.ValueDecoder.HasNext()
 }
 
 func (r *PageDataReader) Next2(pair *datatype.TimeValuePair) error {
+	if r.err != nil {
+		return r.takeErr()
+	}
+	if r.Descending {
+		*pair = *r.popLast()
+		return nil
+	}
 	pair.Timestamp = r.TimeDecoder.NextInt64()
 	pair.Value = r.ValueDecoder.Next()
+	if r.err = r.decodeErr(); r.err != nil {
+		return r.takeErr()
+	}
 	return nil
 	//return &datatype.TimeValuePair{Timestamp: r.TimeDecoder.Next().(int64), Value: r.ValueDecoder.Next()}, nil
 }
 
 func (r *PageDataReader) Next() (*datatype.TimeValuePair, error) {
-	// TODO: catch errors
-	return &datatype.TimeValuePair{Timestamp: r.TimeDecoder.Next().(int64), Value: r.ValueDecoder.Next()}, nil
+	if r.err != nil {
+		return nil, r.takeErr()
+	}
+	if r.Descending {
+		return r.popLast(), nil
+	}
+	pair := &datatype.TimeValuePair{Timestamp: r.TimeDecoder.NextInt64(), Value: r.ValueDecoder.Next()}
+	if r.err = r.decodeErr(); r.err != nil {
+		return nil, r.takeErr()
+	}
+	return pair, nil
+}
+
+// takeErr returns the error the page cannot be decoded with and drops the rest of the page.
+func (r *PageDataReader) takeErr() error {
+	err := r.err
+	r.err = nil
+	r.pairs = r.pairs[:0]
+	r.TimeDecoder.Init(nil)
+	r.ValueDecoder.Init(nil)
+	return err
 }
 
 func (r *PageDataReader) Skip() {
@@ -47,10 +116,18 @@ func (r *PageDataReader) Skip() {
 func (r *PageDataReader) Close() {
 }
 
+func (r *PageDataReader) popLast() *datatype.TimeValuePair {
+	last := r.pairs[len(r.pairs)-1]
+	r.pairs[len(r.pairs)-1] = nil
+	r.pairs = r.pairs[:len(r.pairs)-1]
+	return last
+}
+
 func NewPageDataReader(dataType constant.TSDataType,
 	valueDecoder decoder.Decoder,
-	timeDecoder decoder.Decoder) *PageDataReader {
+	timeDecoder decoder.Decoder, descending bool) *PageDataReader {
 	return &PageDataReader{DataType: dataType,
 		ValueDecoder: valueDecoder,
-		TimeDecoder:  timeDecoder}
+		TimeDecoder:  timeDecoder,
+		Descending:   descending}
 }
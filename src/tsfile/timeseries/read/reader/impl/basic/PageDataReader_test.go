@@ -0,0 +1,101 @@
+package basic
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"tsfile/common/constant"
+	"tsfile/common/utils"
+	"tsfile/encoding/decoder"
+	"tsfile/encoding/encoder"
+)
+
+// fuzzWork is the number of points read from a fuzzed page at most, a page decoding to up to 8 points per byte.
+const fuzzWork = 10000
+
+// encodePage returns a page of 30 points of the data type at timestamps 1 to 30, its values encoded with the encoding,
+// nil if it does not support the data type.
+func encodePage(encoding constant.TSEncoding, dataType constant.TSDataType) []byte {
+	valueEncoder, err := encoder.NewEncoder(int16(encoding), int16(dataType))
+	if err != nil {
+		return nil
+	}
+	timeEncoder := encoder.GetEncoder(int16(constant.TS_2DIFF), int16(constant.INT64))
+	times, values := new(bytes.Buffer), new(bytes.Buffer)
+	for i := int64(1); i <= 30; i++ {
+		timeEncoder.Encode(i, times)
+		switch dataType {
+		case constant.BOOLEAN:
+			valueEncoder.Encode(i%3 == 0, values)
+		case constant.INT32:
+			valueEncoder.Encode(int32(i*i), values)
+		case constant.INT64:
+			valueEncoder.Encode(i*1000, values)
+		case constant.FLOAT:
+			valueEncoder.Encode(float32(i)*1.5, values)
+		case constant.DOUBLE:
+			valueEncoder.Encode(float64(i)*0.25, values)
+		case constant.TEXT:
+			valueEncoder.Encode(fmt.Sprintf("v%d", i), values)
+		}
+	}
+	timeEncoder.Flush(times)
+	valueEncoder.Flush(values)
+	page := new(bytes.Buffer)
+	utils.WriteUnsignedVarInt(int32(times.Len()), page)
+	page.Write(times.Bytes())
+	page.Write(values.Bytes())
+	return page.Bytes()
+}
+
+func TestPageDataReader(t *testing.T) {
+	for _, descending := range []bool{false, true} {
+		valueDecoder, _ := decoder.NewDecoder(constant.PLAIN, constant.INT32)
+		pageReader := NewPageDataReader(constant.INT32, valueDecoder, decoder.NewLongDeltaDecoder(constant.INT64),
+			descending)
+		pageReader.Read(encodePage(constant.PLAIN, constant.INT32))
+		var times []int64
+		for pageReader.HasNext() {
+			pair, err := pageReader.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pair.Value != int32(pair.Timestamp*pair.Timestamp) {
+				t.Fatal(fmt.Sprintf("Unexpected point %+v", pair))
+			}
+			times = append(times, pair.Timestamp)
+		}
+		if len(times) != 30 || (times[0] == 1) == descending {
+			t.Fatal(fmt.Sprintf("Expected 30 points, descending %v, got %v", descending, times))
+		}
+	}
+}
+
+func FuzzPageDataReader(f *testing.F) {
+	// the pages of every encoding of every data type
+	for encoding := constant.PLAIN; encoding <= constant.GORILLA; encoding++ {
+		for dataType := constant.BOOLEAN; dataType <= constant.TEXT; dataType++ {
+			if page := encodePage(encoding, dataType); page != nil {
+				f.Add(byte(encoding), byte(dataType), page)
+			}
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, encoding byte, dataType byte, page []byte) {
+		valueDecoder, err := decoder.NewDecoder(constant.TSEncoding(encoding%8), constant.TSDataType(dataType%6))
+		if err != nil {
+			return
+		}
+		points := 0
+		for _, descending := range []bool{false, true} {
+			pageReader := NewPageDataReader(constant.TSDataType(dataType%6), valueDecoder,
+				decoder.NewLongDeltaDecoder(constant.INT64), descending)
+			pageReader.Read(page)
+			for ; points < fuzzWork && pageReader.HasNext(); points++ {
+				if _, err := pageReader.Next(); err != nil {
+					break
+				}
+			}
+		}
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"math"
 	"tsfile/timeseries/read/datatype"
 	"tsfile/timeseries/read/reader"
-	"tsfile/common/log"
 	"errors"
 )
 
@@ -16,10 +15,14 @@ type RowRecordReader struct {
 	row       *datatype.RowRecord
 	currTime  int64
 	exhausted bool
+	// err is the error HasNext failed with, returned by the next call to Next
+	err error
+	// descending merges series read in descending time order, taking the latest timestamp first
+	descending bool
 }
 
-func NewRecordReader(paths []string, readerMap map[string]reader.TimeValuePairReader) *RowRecordReader {
-	ret := &RowRecordReader{paths: paths, readerMap: readerMap}
+func NewRecordReader(paths []string, readerMap map[string]reader.TimeValuePairReader, descending bool) *RowRecordReader {
+	ret := &RowRecordReader{paths: paths, readerMap: readerMap, descending: descending}
 	ret.row = datatype.NewRowRecordWithPaths(paths)
 	ret.cacheList = make([]*datatype.TimeValuePair, len(paths))
 	ret.currTime = math.MaxInt64
@@ -39,13 +42,21 @@ func (r *RowRecordReader) fillCache() error {
 			}
 			r.cacheList[i] = tv
 		}
-		if r.cacheList[i] != nil && r.cacheList[i].Timestamp < r.currTime {
+		if r.cacheList[i] != nil && r.before(r.cacheList[i].Timestamp) {
 			r.currTime = r.cacheList[i].Timestamp
 		}
 	}
 	return nil
 }
 
+// before tells whether a timestamp comes before the current one, which is math.MaxInt64 while no timestamp is cached.
+func (r *RowRecordReader) before(timestamp int64) bool {
+	if r.descending && r.currTime != math.MaxInt64 {
+		return timestamp > r.currTime
+	}
+	return timestamp < r.currTime
+}
+
 func (r *RowRecordReader) fillRow() {
 	// fill the row cache using column caches
 	for i, _ := range r.paths {
@@ -60,13 +71,14 @@ func (r *RowRecordReader) fillRow() {
 }
 
 func (r *RowRecordReader) HasNext() bool {
-	if r.currTime != math.MaxInt64 {
+	if r.currTime != math.MaxInt64 || r.err != nil {
 		return true
 	}
 	err := r.fillCache()
 	if err != nil {
-		log.Error("Cannot read next record ", err)
+		r.err = err
 		r.exhausted = true
+		return true
 	} else if r.currTime == math.MaxInt64 {
 		r.exhausted = true
 	}
@@ -78,6 +90,11 @@ func (r *RowRecordReader) HasNext() bool {
 	overhead. You can only copy the values in the RowRecord instead of copying the pointer of the return value.
 */
 func (r *RowRecordReader) Next() (*datatype.RowRecord, error) {
+	if r.err != nil {
+		err := r.err
+		r.err = nil
+		return nil, err
+	}
 	if r.exhausted {
 		return nil, errors.New("RowRecord exhausted")
 	}
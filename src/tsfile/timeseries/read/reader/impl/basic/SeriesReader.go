@@ -3,6 +3,9 @@ package basic
 import (
 	"errors"
 	"tsfile/common/constant"
+	"tsfile/common/memory"
+	"tsfile/common/utils"
+	"tsfile/compress"
 	"tsfile/encoding/decoder"
 	"tsfile/timeseries/read"
 	"tsfile/timeseries/read/datatype"
@@ -14,12 +17,21 @@ type SeriesReader struct {
 	PageIndex int
 	PageLimit int
 	// Offsets and Sizes of every page of this series in a file
-	Offsets    []int64
-	Sizes      []int
-	FileReader *read.TsFileSequenceReader
-	PageReader reader.TimeValuePairReader
-	DType      constant.TSDataType
-	Encoding   constant.TSEncoding
+	Offsets []int64
+	Sizes   []int
+	// Compressions and Encodings of every page, taken from the header of the chunk the page belongs to
+	Compressions []constant.CompressionType
+	Encodings    []constant.TSEncoding
+	FileReader   *read.TsFileSequenceReader
+	PageReader   reader.TimeValuePairReader
+	DType        constant.TSDataType
+	// Descending walks the pages from the last one to the first one and every page backwards
+	Descending bool
+	// Budget, if not nil, accounts the buffers of the page being read
+	Budget   *memory.Budget
+	reserved int64
+	// err is the error the next page failed to be read with in HasNext, returned by the next call to Next
+	err error
 }
 
 func (r *SeriesReader) Read(data []byte) {
@@ -31,27 +43,43 @@ func (r *SeriesReader) Skip() {
 }
 
 func (r *SeriesReader) HasNext() bool {
+	if r.err != nil {
+		return true
+	}
 	if r.PageReader != nil {
 		if r.PageReader.HasNext() {
 			return true
 		} else if r.PageIndex < r.PageLimit-1 {
-			r.nextPageReader()
+			if err := r.nextPageReader(); err != nil {
+				r.err = err
+				return true
+			}
 			return r.HasNext()
 		} else {
 			return false
 		}
 	} else if r.PageIndex < r.PageLimit-1 {
-		r.nextPageReader()
+		if err := r.nextPageReader(); err != nil {
+			r.err = err
+			return true
+		}
 		return r.HasNext()
 	}
 	return false
 }
 
 func (r *SeriesReader) Next() (*datatype.TimeValuePair, error) {
+	if r.err != nil {
+		// the pages after the failing one are not read
+		err := r.err
+		r.err = nil
+		r.PageIndex = r.PageLimit
+		return nil, err
+	}
 	if r.PageReader.HasNext() {
 		ret, err := r.PageReader.Next()
 		if err != nil {
-			return nil, err
+			return nil, r.Corrupted(err)
 		}
 		return ret, nil
 	} else {
@@ -64,14 +92,112 @@ func (r *SeriesReader) Next() (*datatype.TimeValuePair, error) {
 }
 
 func (r *SeriesReader) Close() {
-	r.PageReader.Close()
+	if r.PageReader != nil {
+		r.PageReader.Close()
+	}
 	r.PageReader = nil
 	r.PageIndex = r.PageLimit
 	r.FileReader = nil
+	r.Budget.Release(r.reserved)
+	r.reserved = 0
 }
 
-func NewSeriesReader(offsets []int64, sizes []int, reader *read.TsFileSequenceReader, dType constant.TSDataType, encoding constant.TSEncoding) *SeriesReader {
-	return &SeriesReader{-1, len(offsets), offsets, sizes, reader, nil, dType, encoding}
+// NewSeriesReader creates a reader of the given pages, which are in ascending time order. If descending is set, the
+// pages are read in reverse so the points come in descending time order.
+func NewSeriesReader(offsets []int64, sizes []int, compressions []constant.CompressionType,
+	encodings []constant.TSEncoding, reader *read.TsFileSequenceReader, dType constant.TSDataType,
+	descending bool) *SeriesReader {
+	if descending {
+		offsets, sizes, compressions = reverseOffsets(offsets), reverseSizes(sizes), reverseCompressions(compressions)
+		encodings = reverseEncodings(encodings)
+	}
+	return &SeriesReader{PageIndex: -1, PageLimit: len(offsets), Offsets: offsets, Sizes: sizes, Compressions: compressions,
+		Encodings: encodings, FileReader: reader, DType: dType, Descending: descending}
+}
+
+func reverseOffsets(offsets []int64) []int64 {
+	reversed := make([]int64, len(offsets))
+	for i, offset := range offsets {
+		reversed[len(offsets)-1-i] = offset
+	}
+	return reversed
+}
+
+func reverseSizes(sizes []int) []int {
+	reversed := make([]int, len(sizes))
+	for i, size := range sizes {
+		reversed[len(sizes)-1-i] = size
+	}
+	return reversed
+}
+
+func reverseCompressions(compressions []constant.CompressionType) []constant.CompressionType {
+	if compressions == nil {
+		return nil
+	}
+	reversed := make([]constant.CompressionType, len(compressions))
+	for i, compression := range compressions {
+		reversed[len(compressions)-1-i] = compression
+	}
+	return reversed
+}
+
+func reverseEncodings(encodings []constant.TSEncoding) []constant.TSEncoding {
+	reversed := make([]constant.TSEncoding, len(encodings))
+	for i, encoding := range encodings {
+		reversed[len(encodings)-1-i] = encoding
+	}
+	return reversed
+}
+
+// NewValueDecoder returns a decoder of the values of the index-th page, with the encoding of its chunk.
+func (r *SeriesReader) NewValueDecoder(index int) (decoder.Decoder, error) {
+	valueDecoder, err := decoder.NewDecoder(r.Encodings[index], r.DType)
+	if err != nil {
+		return nil, r.Corrupted(err)
+	}
+	return valueDecoder, nil
+}
+
+// ReadPageData reads the raw bytes of the index-th page and decompresses them with the compression of its chunk. The
+// buffers of the previous page are given back to the budget, those of this page are accounted until the next one is
+// read or the reader is closed.
+func (r *SeriesReader) ReadPageData(index int) ([]byte, error) {
+	r.Budget.Release(r.reserved)
+	r.reserved = 0
+	if err := r.Budget.Reserve(int64(r.Sizes[index])); err != nil {
+		return nil, err
+	}
+	r.reserved = int64(r.Sizes[index])
+	data, err := r.FileReader.ReadRaw(r.Offsets[index], r.Sizes[index])
+	if err != nil {
+		return nil, err
+	}
+	compression := constant.UNCOMPRESSED
+	if r.Compressions != nil {
+		compression = r.Compressions[index]
+	}
+	if compression == constant.UNCOMPRESSED {
+		return data, nil
+	}
+	decompressor, err := compress.NewDecompressor(compression)
+	if err != nil {
+		return nil, r.Corrupted(err)
+	}
+	decompressed, err := decompressor.Decompress(data)
+	if err != nil {
+		return nil, r.Corrupted(err)
+	}
+	if err := r.Budget.Reserve(int64(len(decompressed))); err != nil {
+		return nil, err
+	}
+	r.reserved += int64(len(decompressed))
+	return decompressed, nil
+}
+
+// Corrupted returns a *utils.CorruptedError of the current page failing with err.
+func (r *SeriesReader) Corrupted(err error) error {
+	return &utils.CorruptedError{Structure: "page", Offset: r.Offsets[r.PageIndex], Err: err}
 }
 
 func (r *SeriesReader) hasNextPageReader() bool {
@@ -83,11 +209,17 @@ func (r *SeriesReader) nextPageReader() error {
 	if r.PageIndex >= r.PageLimit {
 		return errors.New("page exhausted")
 	}
-	r.PageReader = NewPageDataReader(r.DType,
-		decoder.CreateDecoder(r.Encoding, r.DType),
-		decoder.NewLongDeltaDecoder(constant.INT64))
+	data, err := r.ReadPageData(r.PageIndex)
+	if err != nil {
+		return err
+	}
+	valueDecoder, err := r.NewValueDecoder(r.PageIndex)
+	if err != nil {
+		return err
+	}
+	r.PageReader = NewPageDataReader(r.DType, valueDecoder, decoder.NewLongDeltaDecoder(constant.INT64), r.Descending)
 	//r.PageReader = &PageDataReader{DataType: r.DType, ValueDecoder: decoder.CreateDecoder(r.Encoding, r.DType),
 	//	TimeDecoder: decoder.NewLongDeltaDecoder(constant.INT64)}
-	r.PageReader.Read(r.FileReader.ReadRaw(r.Offsets[r.PageIndex], r.Sizes[r.PageIndex]))
+	r.PageReader.Read(data)
 	return nil
 }
@@ -0,0 +1,40 @@
+package seek
+
+import (
+	"context"
+	"tsfile/timeseries/read/datatype"
+	"tsfile/timeseries/read/reader"
+)
+
+// ContextSeekableSeriesReader is the seekable counterpart of basic.ContextSeriesReader, Seek failing once the context
+// is done.
+type ContextSeekableSeriesReader struct {
+	reader.ISeekableTimeValuePairReader
+	ctx context.Context
+}
+
+func NewContextSeekableSeriesReader(ctx context.Context,
+	r reader.ISeekableTimeValuePairReader) *ContextSeekableSeriesReader {
+	return &ContextSeekableSeriesReader{ISeekableTimeValuePairReader: r, ctx: ctx}
+}
+
+func (r *ContextSeekableSeriesReader) HasNext() bool {
+	return r.ctx.Err() == nil && r.ISeekableTimeValuePairReader.HasNext()
+}
+
+func (r *ContextSeekableSeriesReader) Next() (*datatype.TimeValuePair, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.ISeekableTimeValuePairReader.Next()
+}
+
+func (r *ContextSeekableSeriesReader) Skip() {
+	if r.ctx.Err() == nil {
+		r.ISeekableTimeValuePairReader.Skip()
+	}
+}
+
+func (r *ContextSeekableSeriesReader) Seek(timestamp int64) bool {
+	return r.ctx.Err() == nil && r.ISeekableTimeValuePairReader.Seek(timestamp)
+}
@@ -0,0 +1,40 @@
+package seek
+
+import (
+	"tsfile/timeseries/read/datatype"
+	"tsfile/timeseries/read/reader"
+	"tsfile/timeseries/read/reader/impl/basic"
+)
+
+// MergeSeekableSeriesReader is a MergeSeriesReader that can also seek, the reader earliest in the list holding the
+// timestamp giving the point. Seek and Next read the same underlying readers, so they are not meant to be mixed.
+type MergeSeekableSeriesReader struct {
+	*basic.MergeSeriesReader
+
+	readers []reader.ISeekableTimeValuePairReader
+	current *datatype.TimeValuePair
+}
+
+func NewMergeSeekableSeriesReader(readers []reader.ISeekableTimeValuePairReader, descending bool) *MergeSeekableSeriesReader {
+	plainReaders := make([]reader.TimeValuePairReader, len(readers))
+	for i, r := range readers {
+		plainReaders[i] = r
+	}
+	return &MergeSeekableSeriesReader{MergeSeriesReader: basic.NewMergeSeriesReader(plainReaders, descending),
+		readers: readers}
+}
+
+func (r *MergeSeekableSeriesReader) Seek(timestamp int64) bool {
+	r.current = nil
+	for _, rd := range r.readers {
+		if rd.Seek(timestamp) {
+			r.current = rd.Current()
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MergeSeekableSeriesReader) Current() *datatype.TimeValuePair {
+	return r.current
+}
@@ -2,9 +2,9 @@ package seek
 
 import (
 	"math"
+	"tsfile/common/memory"
 	"tsfile/timeseries/read/datatype"
 	"tsfile/timeseries/read/reader"
-	"tsfile/common/log"
 	"errors"
 )
 
@@ -16,6 +16,10 @@ type SeekableRowReader struct {
 	current   *datatype.RowRecord
 	currTime  int64
 	exhausted bool
+	// budget accounts cacheList, which holds a decoded point per path
+	budget *memory.Budget
+	// err is the error HasNext failed with, returned by the next call to Next
+	err error
 }
 
 func (r *SeekableRowReader) Current() *datatype.RowRecord {
@@ -38,9 +42,15 @@ func (r *SeekableRowReader) Seek(timestamp int64) bool {
 	return hasRecord
 }
 
-func NewSeekableRowReader(paths []string, readerMap map[string]reader.ISeekableTimeValuePairReader) *SeekableRowReader {
+// NewSeekableRowReader creates a reader of the rows of the given paths. budget may be nil, a failed reservation being
+// reported through the context of the budget.
+func NewSeekableRowReader(paths []string, readerMap map[string]reader.ISeekableTimeValuePairReader,
+	budget *memory.Budget) *SeekableRowReader {
+	if budget.Reserve(int64(len(paths))*memory.PairSize) != nil {
+		budget = nil
+	}
 	ret := &SeekableRowReader{paths, readerMap, make([]*datatype.TimeValuePair, len(paths)),
-		datatype.NewRowRecordWithPaths(paths), math.MaxInt64, false}
+		datatype.NewRowRecordWithPaths(paths), math.MaxInt64, false, budget, nil}
 	return ret
 }
 
@@ -75,13 +85,14 @@ func (r *SeekableRowReader) fillRow() {
 }
 
 func (r *SeekableRowReader) HasNext() bool {
-	if r.currTime != math.MaxInt64 {
+	if r.currTime != math.MaxInt64 || r.err != nil {
 		return true
 	}
 	err := r.fillCache()
 	if err != nil {
-		log.Error("RowRecord exhausted")
+		r.err = err
 		r.exhausted = true
+		return true
 	} else if r.current.Timestamp() == math.MaxInt64 {
 		r.exhausted = true
 	}
@@ -93,6 +104,11 @@ func (r *SeekableRowReader) HasNext() bool {
 	overhead. You can only copy the values in the RowRecord instead of copying the pointer of the return value.
 */
 func (r *SeekableRowReader) Next() (*datatype.RowRecord, error) {
+	if r.err != nil {
+		err := r.err
+		r.err = nil
+		return nil, err
+	}
 	if r.exhausted {
 		return nil, errors.New("RowRecord exhausted")
 	}
@@ -119,4 +135,6 @@ func (r *SeekableRowReader) Close() {
 			r.readerMap[path].Close()
 		}
 	}
+	r.budget.Release(int64(len(r.paths)) * memory.PairSize)
+	r.budget = nil
 }
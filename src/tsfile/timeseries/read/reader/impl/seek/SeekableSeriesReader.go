@@ -17,42 +17,51 @@ type SeekableSeriesReader struct {
 	pageHeaders []*header.PageHeader
 	current     *datatype.TimeValuePair
 	exhausted   bool
+	// err is the error the next page failed to be read with in HasNext, returned by the next call to Next
+	err error
 }
 
 func (r *SeekableSeriesReader) Seek(timestamp int64) bool {
 
-	// seek the page that may contain the given timestamp
-	pageChanged := false
-	if r.PageIndex == -1 {
-		r.PageIndex = 0
-		pageChanged = true
-	}
-	for r.PageIndex < r.PageLimit &&
-		!(r.pageHeaders[r.PageIndex].Min_timestamp() <= timestamp && timestamp <= r.pageHeaders[r.PageIndex].Max_timestamp()) {
-		r.PageIndex++
-		pageChanged = true
-	}
-	if pageChanged {
-		if r.PageIndex < r.PageLimit {
-			r.PageIndex--
-			r.nextPageReader()
-		} else {
+	// seek the page that may contain the given timestamp, i.e. the first one not ending before it
+	index := r.PageIndex
+	if index == -1 {
+		index = 0
+	}
+	for index < r.PageLimit && r.before(r.pageEnd(index), timestamp) {
+		index++
+	}
+	// stay on the current page if the timestamp is after the last page or between two pages, so that later seeks can
+	// still find their pages
+	if index >= r.PageLimit || r.before(timestamp, r.pageStart(index)) {
+		return false
+	}
+	if index != r.PageIndex {
+		r.PageIndex = index - 1
+		if err := r.nextPageReader(); err != nil {
+			log.Error("cannot read page: %v", err)
 			return false
 		}
+		r.current = nil
 	}
 
 	// seek within this page
 	if r.current == nil {
-		if r.HasNext() {
-			r.Next()
-		} else {
+		if !r.HasNext() {
+			return false
+		}
+		if _, err := r.Next(); err != nil {
+			log.Error("cannot read page: %v", err)
 			return false
 		}
 	}
 	for {
-		if r.current.Timestamp < timestamp {
+		if r.before(r.current.Timestamp, timestamp) {
 			if r.HasNext() {
-				r.Next()
+				if _, err := r.Next(); err != nil {
+					log.Error("cannot read page: %v", err)
+					return false
+				}
 				continue
 			} else {
 				return false
@@ -65,13 +74,45 @@ func (r *SeekableSeriesReader) Seek(timestamp int64) bool {
 	}
 }
 
+// before tells whether a comes before b in the order the series is read.
+func (r *SeekableSeriesReader) before(a int64, b int64) bool {
+	if r.Descending {
+		return a > b
+	}
+	return a < b
+}
+
+// pageStart and pageEnd give the first and the last timestamp of the index-th page in the order the series is read.
+func (r *SeekableSeriesReader) pageStart(index int) int64 {
+	if r.Descending {
+		return r.pageHeaders[index].Max_timestamp()
+	}
+	return r.pageHeaders[index].Min_timestamp()
+}
+
+func (r *SeekableSeriesReader) pageEnd(index int) int64 {
+	if r.Descending {
+		return r.pageHeaders[index].Min_timestamp()
+	}
+	return r.pageHeaders[index].Max_timestamp()
+}
+
 func (r *SeekableSeriesReader) Current() *datatype.TimeValuePair {
 	return r.current
 }
 
-func NewSeekableSeriesReader(offsets []int64, sizes []int, reader *read.TsFileSequenceReader, pageHeaders []*header.PageHeader, dType constant.TSDataType, encoding constant.TSEncoding) *SeekableSeriesReader {
-	return &SeekableSeriesReader{&basic.SeriesReader{-1, len(offsets),
-		offsets, sizes, reader, nil, dType, encoding}, pageHeaders, nil, false}
+func NewSeekableSeriesReader(offsets []int64, sizes []int, compressions []constant.CompressionType,
+	encodings []constant.TSEncoding, reader *read.TsFileSequenceReader, pageHeaders []*header.PageHeader,
+	dType constant.TSDataType, descending bool) *SeekableSeriesReader {
+	if descending {
+		reversed := make([]*header.PageHeader, len(pageHeaders))
+		for i, pageHeader := range pageHeaders {
+			reversed[len(pageHeaders)-1-i] = pageHeader
+		}
+		pageHeaders = reversed
+	}
+	return &SeekableSeriesReader{basic.NewSeriesReader(offsets, sizes, compressions, encodings, reader, dType, descending),
+		pageHeaders, nil, false, nil}
 }
 
 func (r *SeekableSeriesReader) hasNextPageReader() bool {
@@ -83,16 +124,26 @@ func (r *SeekableSeriesReader) nextPageReader() error {
 	if r.PageIndex >= r.PageLimit {
 		return errors.New("page exhausted")
 	}
+	data, err := r.ReadPageData(r.PageIndex)
+	if err != nil {
+		return err
+	}
 	//r.PageReader = &SeekablePageDataReader{&basic.PageDataReader{DataType: r.DType, ValueDecoder: decoder.CreateDecoder(r.Encoding, r.DType),
 	//	TimeDecoder: decoder.NewLongDeltaDecoder(constant.INT64)}, nil}
-	r.PageReader = basic.NewPageDataReader(r.DType,
-		decoder.CreateDecoder(r.Encoding, r.DType),
-		decoder.NewLongDeltaDecoder(constant.INT64))
-	r.PageReader.Read(r.FileReader.ReadRaw(r.Offsets[r.PageIndex], r.Sizes[r.PageIndex]))
+	valueDecoder, err := r.NewValueDecoder(r.PageIndex)
+	if err != nil {
+		return err
+	}
+	r.PageReader = basic.NewPageDataReader(r.DType, valueDecoder, decoder.NewLongDeltaDecoder(constant.INT64),
+		r.Descending)
+	r.PageReader.Read(data)
 	return nil
 }
 
 func (r *SeekableSeriesReader) HasNext() bool {
+	if r.err != nil {
+		return true
+	}
 	if r.exhausted {
 		return false
 	}
@@ -101,34 +152,44 @@ func (r *SeekableSeriesReader) HasNext() bool {
 			return true
 		} else if r.PageIndex < r.PageLimit-1 {
 			if err := r.nextPageReader(); err != nil {
-				log.Error("cannot read next page", err)
-				r.exhausted = true
-				return false
+				r.err = err
+				return true
 			}
 			return r.HasNext()
 		} else {
 			return false
 		}
 	} else if r.PageIndex < r.PageLimit-1 {
-		r.nextPageReader()
+		if err := r.nextPageReader(); err != nil {
+			r.err = err
+			return true
+		}
 		return r.HasNext()
 	}
 	return false
 }
 
 func (r *SeekableSeriesReader) Next() (*datatype.TimeValuePair, error) {
+	if r.err != nil {
+		err := r.err
+		r.err = nil
+		r.exhausted = true
+		return nil, err
+	}
 	if r.exhausted {
 		return nil, errors.New("series exhausted")
 	}
 	if r.PageReader.HasNext() {
 		tv, err := r.PageReader.Next()
 		if err != nil {
-			return nil, err
+			return nil, r.Corrupted(err)
 		}
 		r.current = tv
 		return r.current, nil
 	} else {
-		r.nextPageReader()
+		if err := r.nextPageReader(); err != nil {
+			return nil, err
+		}
 		return r.Next()
 	}
 }
@@ -9,6 +9,7 @@ package fileSchema
  */
 
 import (
+	"tsfile/common/conf"
 	"tsfile/file/metadata"
 	"tsfile/timeseries/write/sensorDescriptor"
 )
@@ -19,6 +20,7 @@ type FileSchema struct {
 	currentMaxByteSizeInOneRow int
 	tsMetaData                 map[string]*metadata.TimeSeriesMetaData
 	sensorDataTypeMap          map[string]int16
+	config                     *conf.WriterConfig
 }
 
 func (f *FileSchema) AddTimeSeriesMetaData(sensorId string, tsDataType int16) {
@@ -51,17 +53,25 @@ func (f *FileSchema) Registermeasurement(sd *sensorDescriptor.SensorDescriptor)
 	f.indexSensorDataType(sd.GetSensorId(), sd.GetTsDataType())
 	f.AddTimeSeriesMetaData(sd.GetSensorId(), sd.GetTsDataType())
 	// todo fileschema.java line:178
-	if sd.GetTimeEncoder() != nil && sd.GetValueEncoder() != nil {
-		f.enlargeMaxByteSizeInOneRow(sd.GetTimeEncoder().GetOneItemMaxSize() + sd.GetValueEncoder().GetOneItemMaxSize())
+	timeEncoder, timeErr := sd.NewTimeEncoder(f.config)
+	valueEncoder, valueErr := sd.NewValueEncoder(f.config)
+	if timeErr == nil && valueErr == nil {
+		f.enlargeMaxByteSizeInOneRow(timeEncoder.GetOneItemMaxSize() + valueEncoder.GetOneItemMaxSize())
 	}
 	return true
 }
 
 func New() (*FileSchema, error) {
+	return NewWithConfig(conf.DefaultWriterConfig())
+}
+
+// NewWithConfig returns a schema estimating the size of the rows with the encoders of config.
+func NewWithConfig(config *conf.WriterConfig) (*FileSchema, error) {
 	return &FileSchema{
 		sensorDescriptorMap:  make(map[string]*sensorDescriptor.SensorDescriptor),
 		additionalProperties: make(map[string]string),
 		tsMetaData:           make(map[string]*metadata.TimeSeriesMetaData),
 		sensorDataTypeMap:    make(map[string]int16),
+		config:               config,
 	}, nil
 }
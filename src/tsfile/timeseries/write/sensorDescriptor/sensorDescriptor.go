@@ -9,6 +9,7 @@ package sensorDescriptor
  */
 
 import (
+	"fmt"
 	"tsfile/common/conf"
 	"tsfile/common/constant"
 	"tsfile/compress"
@@ -69,6 +70,20 @@ func (s *SensorDescriptor) GetValueEncoder() encoder.Encoder {
 	return encoder.GetEncoder(s.GetTsEncoding(), s.GetTsDataType())
 }
 
+// NewTimeEncoder returns an encoder of the timestamps of the sensor written with config.
+func (s *SensorDescriptor) NewTimeEncoder(config *conf.WriterConfig) (encoder.Encoder, error) {
+	encoding, ok := constant.LookupEncoding(config.TimeSeriesEncoder)
+	if !ok {
+		return nil, fmt.Errorf("unknown time series encoder %s", config.TimeSeriesEncoder)
+	}
+	return encoder.NewEncoderWithConfig(int16(encoding), int16(constant.INT64), config)
+}
+
+// NewValueEncoder returns an encoder of the values of the sensor written with config.
+func (s *SensorDescriptor) NewValueEncoder(config *conf.WriterConfig) (encoder.Encoder, error) {
+	return encoder.NewEncoderWithConfig(s.GetTsEncoding(), s.GetTsDataType(), config)
+}
+
 func (s *SensorDescriptor) Close() bool {
 	return true
 }
@@ -98,3 +113,20 @@ func NewWithCompress(sId string, tdt constant.TSDataType, te constant.TSEncoding
 		timeCount:          -1,
 	}, nil
 }
+
+// NewWithConfig returns a descriptor of a sensor whose values are encoded and compressed with the value encoder and
+// compressor of config, an error if they are unknown or the encoder does not support the data type.
+func NewWithConfig(sId string, tdt constant.TSDataType, config *conf.WriterConfig) (*SensorDescriptor, error) {
+	te, ok := constant.LookupEncoding(config.ValueEncoder)
+	if !ok {
+		return nil, fmt.Errorf("unknown value encoder %s", config.ValueEncoder)
+	}
+	tct, ok := constant.LookupCompression(config.Compressor)
+	if !ok {
+		return nil, fmt.Errorf("unknown compressor %s", config.Compressor)
+	}
+	if _, err := encoder.NewEncoderWithConfig(int16(te), int16(tdt), config); err != nil {
+		return nil, err
+	}
+	return NewWithCompress(sId, tdt, te, tct)
+}
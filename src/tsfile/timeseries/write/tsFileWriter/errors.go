@@ -0,0 +1,85 @@
+package tsFileWriter
+
+import (
+	"errors"
+	"fmt"
+	"tsfile/common/constant"
+)
+
+// ErrWriterClosed is returned by the calls to a TsFileWriter after Close.
+var ErrWriterClosed = errors.New("tsfile writer is closed")
+
+// UnknownSensorError rejects a record holding a data point of a sensor that was not added to the writer.
+type UnknownSensorError struct {
+	DeviceId string
+	SensorId string
+}
+
+func (e *UnknownSensorError) Error() string {
+	return fmt.Sprintf("unknown sensor %s of device %s", e.SensorId, e.DeviceId)
+}
+
+// TypeMismatchError rejects a record holding a data point whose value is not of the data type of its sensor.
+type TypeMismatchError struct {
+	SensorId string
+	DataType constant.TSDataType
+	Value    interface{}
+}
+
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("value %v of type %T does not match the data type %s of sensor %s", e.Value, e.Value,
+		e.DataType, e.SensorId)
+}
+
+// SensorError rejects a sensor that cannot be added to the writer, e.g. one already added with another data type or
+// whose encoding does not support its data type.
+type SensorError struct {
+	SensorId string
+	Err      error
+}
+
+func (e *SensorError) Error() string {
+	return fmt.Sprintf("invalid sensor %s: %v", e.SensorId, e.Err)
+}
+
+func (e *SensorError) Unwrap() error {
+	return e.Err
+}
+
+// IOError reports a failure to open, write or close the file, Op being one of "open", "write" and "close". Once
+// writing failed, the file is incomplete and every later call returns the error.
+type IOError struct {
+	Op  string
+	Err error
+}
+
+func (e *IOError) Error() string {
+	return fmt.Sprintf("tsfile %s: %v", e.Op, e.Err)
+}
+
+func (e *IOError) Unwrap() error {
+	return e.Err
+}
+
+// checkValue returns a TypeMismatchError unless value is of the Go type of dataType.
+func checkValue(sensorId string, dataType constant.TSDataType, value interface{}) error {
+	var ok bool
+	switch dataType {
+	case constant.BOOLEAN:
+		_, ok = value.(bool)
+	case constant.INT32:
+		_, ok = value.(int32)
+	case constant.INT64:
+		_, ok = value.(int64)
+	case constant.FLOAT:
+		_, ok = value.(float32)
+	case constant.DOUBLE:
+		_, ok = value.(float64)
+	case constant.TEXT:
+		_, ok = value.(string)
+	}
+	if !ok {
+		return &TypeMismatchError{SensorId: sensorId, DataType: dataType, Value: value}
+	}
+	return nil
+}
@@ -15,6 +15,7 @@ import (
 	"tsfile/common/log"
 	"tsfile/compress"
 	"tsfile/file/header"
+	"tsfile/file/metadata"
 	"tsfile/file/metadata/statistics"
 	"tsfile/timeseries/write/sensorDescriptor"
 )
@@ -90,12 +91,12 @@ func (p *PageWriter) WritePageHeaderAndDataIntoBuff(dataBuffer *bytes.Buffer, va
 	return 0
 }
 
-func (p *PageWriter) WriteAllPagesOfSeriesToTsFile(tsFileIoWriter *TsFileIoWriter, seriesStatistics statistics.Statistics, numOfPage int) int64 {
+func (p *PageWriter) WriteAllPagesOfSeriesToTsFile(tsFileIoWriter *TsFileIoWriter, seriesStatistics statistics.Statistics, extendedStatistics *metadata.ExtendedStatistics, numOfPage int) int64 {
 	if p.minTimestamp == -1 {
 		log.Error("Write page error, minTime: %s, maxTime: %s")
 	}
 	// write trunk header to file
-	chunkHeaderSize := tsFileIoWriter.StartFlushChunk(p.desc, p.desc.GetCompresstionType(), p.desc.GetTsDataType(), p.desc.GetTsEncoding(), seriesStatistics, p.maxTimestamp, p.minTimestamp, p.pageBuf.Len(), numOfPage)
+	chunkHeaderSize := tsFileIoWriter.StartFlushChunk(p.desc, p.desc.GetCompresstionType(), p.desc.GetTsDataType(), p.desc.GetTsEncoding(), seriesStatistics, extendedStatistics, p.maxTimestamp, p.minTimestamp, p.pageBuf.Len(), numOfPage)
 	preSize := tsFileIoWriter.GetPos()
 	// write all pages to file
 	tsFileIoWriter.WriteBytesToFile(p.pageBuf)
@@ -109,6 +110,7 @@ func (p *PageWriter) WriteAllPagesOfSeriesToTsFile(tsFileIoWriter *TsFileIoWrite
 
 func (p *PageWriter) Reset() {
 	p.minTimestamp = -1
+	p.maxTimestamp = -1
 	p.pageBuf.Reset()
 	p.totalValueCount = 0
 	return
@@ -127,8 +129,10 @@ func (p *PageWriter) GetCurrentDataSize() int {
 
 func NewPageWriter(sd *sensorDescriptor.SensorDescriptor) (*PageWriter, error) {
 	return &PageWriter{
-		desc:       sd,
-		compressor: sd.GetCompressor(),
-		pageBuf:    bytes.NewBuffer([]byte{}),
+		desc:         sd,
+		compressor:   sd.GetCompressor(),
+		pageBuf:      bytes.NewBuffer([]byte{}),
+		maxTimestamp: -1,
+		minTimestamp: -1,
 	}, nil
 }
@@ -9,6 +9,7 @@ package tsFileWriter
  */
 
 import (
+	"tsfile/common/conf"
 	"tsfile/common/log"
 	_ "tsfile/common/utils"
 	"tsfile/file/header"
@@ -20,7 +21,7 @@ type RowGroupWriter struct {
 	dataSeriesWriters map[string]*SeriesWriter
 }
 
-func (r *RowGroupWriter) AddSeriesWriter(sd *sensorDescriptor.SensorDescriptor, pageSize int) {
+func (r *RowGroupWriter) AddSeriesWriter(sd *sensorDescriptor.SensorDescriptor, config *conf.WriterConfig) {
 	//start_edit wangcan 2018-10-15
 	//if contain, _ := utils.MapContains(r.dataSeriesWriters, sd.GetSensorId()); !contain {
 	_, contain := r.dataSeriesWriters[sd.GetSensorId()]
@@ -30,7 +31,7 @@ func (r *RowGroupWriter) AddSeriesWriter(sd *sensorDescriptor.SensorDescriptor,
 		pw, _ := NewPageWriter(sd)
 
 		// new serieswrite
-		r.dataSeriesWriters[sd.GetSensorId()], _ = NewSeriesWriter(r.deviceId, sd, pw, pageSize)
+		r.dataSeriesWriters[sd.GetSensorId()], _ = NewSeriesWriter(r.deviceId, sd, pw, config)
 		//sw, _ := NewSeriesWriter(r.deviceId, sd, pw, pageSize)
 		//r.dataSeriesWriters[sd.GetSensorId()] = sw
 		//start_edit wangcan 2018-10-15
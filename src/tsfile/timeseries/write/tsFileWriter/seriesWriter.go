@@ -14,6 +14,7 @@ import (
 	"tsfile/common/constant"
 	"tsfile/common/log"
 	"tsfile/file/header"
+	"tsfile/file/metadata"
 	"tsfile/file/metadata/statistics"
 	"tsfile/timeseries/write/sensorDescriptor"
 )
@@ -36,6 +37,8 @@ type SeriesWriter struct {
 	/*statistics on a page. It will be reset after calling */
 	pageStatistics             statistics.Statistics
 	seriesStatistics           statistics.Statistics
+	extendedStatistics         *metadata.ExtendedStatistics // on a chunk, nil unless extendedDigest is set
+	extendedDigest             bool
 	time                       int64
 	minTimestamp               int64
 	sensorDescriptor           sensorDescriptor.SensorDescriptor
@@ -78,6 +81,9 @@ func (s *SeriesWriter) Write(t int64, data *DataPoint) bool {
 	s.valueCount = s.valueCount + 1
 	// statistics ignore here, if necessary, Statistics.java
 	s.pageStatistics.UpdateStats(data.value)
+	if s.extendedStatistics != nil {
+		s.extendedStatistics.Update(data.value)
+	}
 
 	if s.minTimestamp == -1 {
 		s.minTimestamp = t
@@ -89,15 +95,27 @@ func (s *SeriesWriter) Write(t int64, data *DataPoint) bool {
 
 func (s *SeriesWriter) WriteToFileWriter(tsFileIoWriter *TsFileIoWriter) {
 	// write all pages in the same chunk to file
-	s.pageWriter.WriteAllPagesOfSeriesToTsFile(tsFileIoWriter, s.seriesStatistics, s.numOfPages)
+	s.pageWriter.WriteAllPagesOfSeriesToTsFile(tsFileIoWriter, s.seriesStatistics, s.extendedStatistics, s.numOfPages)
 	// reset pageWriter
 	s.pageWriter.Reset()
 	// reset series_statistics
 	s.seriesStatistics = statistics.GetStatsByType(s.tsDataType)
+	s.extendedStatistics = newExtendedStatistics(s.tsDataType, s.extendedDigest)
+}
+
+// newExtendedStatistics returns nil unless extendedDigest is set and the data type is numeric.
+func newExtendedStatistics(tsDataType int16, extendedDigest bool) *metadata.ExtendedStatistics {
+	switch constant.TSDataType(tsDataType) {
+	case constant.INT32, constant.INT64, constant.FLOAT, constant.DOUBLE:
+		if extendedDigest {
+			return metadata.NewExtendedStatistics()
+		}
+	}
+	return nil
 }
 
 func (s *SeriesWriter) checkPageSizeAndMayOpenNewpage() {
-	if s.valueCount == conf.MaxNumberOfPointsInPage {
+	if s.valueCount == s.pageCountUpperBound {
 		//log.Info("current line count reaches the upper bound, write page %s", s.sensorDescriptor)
 		// write data to buffer
 		s.WritePage()
@@ -192,7 +210,14 @@ func (s *SeriesWriter) WritePage() {
 		pageWriter.totalValueCount += int64(valueCount)
 	}
 
-	// pageStatistics
+	// fold the page into the chunk statistics and time range written to the chunk metadata
+	s.seriesStatistics.Merge(s.pageStatistics)
+	if pageWriter.minTimestamp == -1 || s.minTimestamp < pageWriter.minTimestamp {
+		pageWriter.minTimestamp = s.minTimestamp
+	}
+	if s.time > pageWriter.maxTimestamp {
+		pageWriter.maxTimestamp = s.time
+	}
 	s.numOfPages += 1
 
 	s.minTimestamp = -1
@@ -207,20 +232,26 @@ func (s *SeriesWriter) ResetPageStatistics() {
 	return
 }
 
-func NewSeriesWriter(dId string, d *sensorDescriptor.SensorDescriptor, pw *PageWriter, pst int) (*SeriesWriter, error) {
-	vw, _ := NewValueWriter(d)
+// NewSeriesWriter returns a writer of the pages of a sensor, whose size and encoders are those of config.
+func NewSeriesWriter(dId string, d *sensorDescriptor.SensorDescriptor, pw *PageWriter, config *conf.WriterConfig) (*SeriesWriter, error) {
+	vw, err := NewValueWriter(d, config)
+	if err != nil {
+		return nil, err
+	}
 	return &SeriesWriter{
 		deviceId:                   dId,
 		desc:                       d,
 		pageWriter:                 pw,
-		psThres:                    pst,
-		pageCountUpperBound:        conf.MaxNumberOfPointsInPage,
+		psThres:                    config.PageSizeInByte,
+		pageCountUpperBound:        config.MaxNumberOfPointsInPage,
 		minimumRecordCountForCheck: 1,
 		valueCountForNextSizeCheck: 1,
 		numOfPages:                 0,
 		tsDataType:                 d.GetTsDataType(),
 		seriesStatistics:           statistics.GetStatsByType(d.GetTsDataType()),
 		pageStatistics:             statistics.GetStatsByType(d.GetTsDataType()),
+		extendedStatistics:         newExtendedStatistics(d.GetTsDataType(), config.ExtendedDigest),
+		extendedDigest:             config.ExtendedDigest,
 		valueWriter:                *vw,
 		minTimestamp:               -1,
 		valueCount:                 0,
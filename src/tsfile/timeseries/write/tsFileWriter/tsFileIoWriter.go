@@ -10,6 +10,7 @@ package tsFileWriter
 
 import (
 	"bytes"
+	"io"
 	"os"
 	"tsfile/common/conf"
 	"tsfile/common/log"
@@ -21,8 +22,15 @@ import (
 	"tsfile/timeseries/write/sensorDescriptor"
 )
 
+// TsFileIoWriter writes the bytes of a TsFile to an io.Writer, keeping track of the position itself so that the writer
+// need not be seekable.
 type TsFileIoWriter struct {
-	tsIoFile                *os.File
+	tsIoFile io.Writer
+	// file is the file opened by NewTsFileIoWriter, nil if the writer was given
+	file *os.File
+	pos  int64
+	// err is the first error writing failed with, after which nothing more is written
+	err                     error
 	memBuf                  *bytes.Buffer
 	currentRowGroupMetaData *metadata.RowGroupMetaData
 	currentChunkMetaData    *metadata.ChunkMetaData
@@ -32,20 +40,44 @@ type TsFileIoWriter struct {
 }
 
 const (
-	MAXVALUE = "max_value"
-	MINVALUE = "min_value"
-	FIRST    = "first"
-	SUM      = "sum"
-	LAST     = "last"
+	MAXVALUE = metadata.MAX_VALUE
+	MINVALUE = metadata.MIN_VALUE
+	FIRST    = metadata.FIRST
+	SUM      = metadata.SUM
+	LAST     = metadata.LAST
 )
 
+// GetTsIoFile returns the file opened by NewTsFileIoWriter, nil if the writer was given to NewTsFileIoWriterTo.
 func (t *TsFileIoWriter) GetTsIoFile() *os.File {
-	return t.tsIoFile
+	return t.file
 }
 
+// GetPos returns the number of bytes written so far, plus the size of the file opened by NewTsFileIoWriter before.
 func (t *TsFileIoWriter) GetPos() int64 {
-	currentPos, _ := t.tsIoFile.Seek(0, os.SEEK_CUR)
-	return currentPos
+	return t.pos
+}
+
+func (t *TsFileIoWriter) write(data []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	n, err := t.tsIoFile.Write(data)
+	t.pos += int64(n)
+	t.err = err
+	return n, err
+}
+
+// Err returns the first error writing failed with, nil if none did.
+func (t *TsFileIoWriter) Err() error {
+	return t.err
+}
+
+// Close closes the file opened by NewTsFileIoWriter. A writer given to NewTsFileIoWriterTo is left open.
+func (t *TsFileIoWriter) Close() error {
+	if t.file == nil {
+		return nil
+	}
+	return t.file.Close()
 }
 
 func (t *TsFileIoWriter) EndChunk(size int64, totalValueCount int64) {
@@ -126,10 +158,7 @@ func min(x, y int64) int64 {
 }
 
 func (t *TsFileIoWriter) WriteMagic() int {
-	n, err := t.tsIoFile.Write([]byte(conf.MAGIC_STRING))
-	if err != nil {
-		log.Error("write start magic to file err: ", err)
-	}
+	n, _ := t.write([]byte(conf.MAGIC_STRING))
 	return n
 }
 
@@ -150,8 +179,10 @@ func (t *TsFileIoWriter) StartFlushRowGroup(deviceId string, rowGroupSize int64,
 	return header.GetRowGroupSerializedSize(deviceId)
 }
 
+// StartFlushChunk writes the header of a chunk and starts its metadata, whose digest has the statistics of the chunk
+// and its extended statistics if they are not nil.
 func (t *TsFileIoWriter) StartFlushChunk(sd *sensorDescriptor.SensorDescriptor, compressionType int16,
-	tsDataType int16, encodingType int16, statistics statistics.Statistics,
+	tsDataType int16, encodingType int16, statistics statistics.Statistics, extendedStatistics *metadata.ExtendedStatistics,
 	maxTimestamp int64, minTimestamp int64, pageBufSize int, numOfPages int) int {
 	t.currentChunkMetaData, _ = metadata.NewTimeSeriesChunkMetaData(sd.GetSensorId(), t.GetPos(), minTimestamp, maxTimestamp)
 	chunkHeader, _ := header.NewChunkHeader(sd.GetSensorId(), pageBufSize, tsDataType, compressionType, encodingType, numOfPages, 0)
@@ -197,6 +228,10 @@ func (t *TsFileIoWriter) StartFlushChunk(sd *sensorDescriptor.SensorDescriptor,
 	statisticsMap[MAXVALUE] = &max
 
 	tsDigest.SetStatistics(statisticsMap)
+	// extended statistics are under keys of their own, which older readers ignore
+	if extendedStatistics != nil && extendedStatistics.Count > 0 {
+		tsDigest.SetExtendedStatistics(extendedStatistics)
+	}
 	t.currentChunkMetaData.SetDigest(tsDigest)
 	return header.GetChunkSerializedSize(sd.GetSensorId())
 }
@@ -206,19 +241,28 @@ func (t *TsFileIoWriter) WriteBytesToFile(buf *bytes.Buffer) {
 	timeSlice := make([]byte, buf.Len())
 	//把buf的内容读入到timeSlice内,因为timeSlice容量为timeSize,所以只读了timeSize个过来
 	buf.Read(timeSlice)
-	t.tsIoFile.Write(timeSlice)
+	t.write(timeSlice)
 	return
 }
 
 func NewTsFileIoWriter(file string) (*TsFileIoWriter, error) {
 	newFile, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
 	if err != nil {
-		log.Info("open file:%s failed.", file)
+		return nil, &IOError{Op: "open", Err: err}
 	}
 
+	t := NewTsFileIoWriterTo(newFile)
+	t.file = newFile
+	// the file is appended to
+	t.pos, _ = newFile.Seek(0, io.SeekEnd)
+	return t, nil
+}
+
+// NewTsFileIoWriterTo returns a TsFileIoWriter writing to w, e.g. a buffer, a pipe or an HTTP response.
+func NewTsFileIoWriterTo(w io.Writer) *TsFileIoWriter {
 	return &TsFileIoWriter{
-		tsIoFile:            newFile,
+		tsIoFile:            w,
 		memBuf:              bytes.NewBuffer([]byte{}),
 		rowGroupMetaDataSli: make([]*metadata.RowGroupMetaData, 0),
-	}, nil
+	}
 }
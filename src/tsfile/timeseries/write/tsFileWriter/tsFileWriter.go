@@ -9,8 +9,11 @@ package tsFileWriter
  */
 
 import (
+	"fmt"
+	"io"
 	_ "time"
 	"tsfile/common/conf"
+	"tsfile/common/constant"
 	"tsfile/common/log"
 	"tsfile/timeseries/write/fileSchema"
 	"tsfile/timeseries/write/sensorDescriptor"
@@ -29,14 +32,26 @@ type TsFileWriter struct {
 	lastGroupDevice            *RowGroupWriter
 	lastSeriesWriter           *SeriesWriter
 	lastSessorId               string
+	closed                     bool
+	config                     *conf.WriterConfig
 }
 
-func (t *TsFileWriter) AddSensor(sd *sensorDescriptor.SensorDescriptor) []byte {
-	if _, ok := t.schema.GetSensorDescriptiorMap()[sd.GetSensorId()]; !ok {
-		t.schema.GetSensorDescriptiorMap()[sd.GetSensorId()] = sd
-	} else {
-		log.Info("the given sensor has exist!")
+// AddSensor registers a sensor the records written may hold data points of. Adding a sensor again replaces its
+// encoding and compression for the next row groups, but changing its data type returns a SensorError, as does an
+// encoding the data type does not support.
+func (t *TsFileWriter) AddSensor(sd *sensorDescriptor.SensorDescriptor) error {
+	if t.closed {
+		return ErrWriterClosed
+	}
+	if existing, ok := t.schema.GetSensorDescriptiorMap()[sd.GetSensorId()]; ok &&
+		existing.GetTsDataType() != sd.GetTsDataType() {
+		return &SensorError{SensorId: sd.GetSensorId(), Err: fmt.Errorf("already added with data type %s",
+			constant.TSDataType(existing.GetTsDataType()))}
+	}
+	if _, err := sd.NewValueEncoder(t.config); err != nil {
+		return &SensorError{SensorId: sd.GetSensorId(), Err: err}
 	}
+	t.schema.GetSensorDescriptiorMap()[sd.GetSensorId()] = sd
 	t.schema.Registermeasurement(sd)
 	t.oneRowMaxSize = t.schema.GetCurrentRowMaxSize()
 	//if t.primaryRowGroupSize <= int64(t.oneRowMaxSize) {
@@ -46,6 +61,30 @@ func (t *TsFileWriter) AddSensor(sd *sensorDescriptor.SensorDescriptor) []byte {
 
 	// flush rowgroup
 	t.checkMemorySizeAndMayFlushGroup()
+	return t.ioError()
+}
+
+// ioError returns the IOError writing failed with, if it did.
+func (t *TsFileWriter) ioError() error {
+	if err := t.tsFileIoWriter.Err(); err != nil {
+		return &IOError{Op: "write", Err: err}
+	}
+	return nil
+}
+
+// checkRecord returns an UnknownSensorError or a TypeMismatchError for the first data point of tr that cannot be
+// written.
+func (t *TsFileWriter) checkRecord(tr *TsRecord) error {
+	sensors := t.schema.GetSensorDescriptiorMap()
+	for _, v := range tr.GetDataPointSli() {
+		sd, ok := sensors[v.GetSensorId()]
+		if !ok {
+			return &UnknownSensorError{DeviceId: tr.GetDeviceId(), SensorId: v.GetSensorId()}
+		}
+		if err := checkValue(v.GetSensorId(), constant.TSDataType(sd.GetTsDataType()), v.value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -102,9 +141,26 @@ func (t *TsFileWriter) reset() {
 	for k, _ := range t.groupDevices {
 		delete(t.groupDevices, k)
 	}
+	// the cached writers belong to the flushed row groups
+	t.lastGroupDevice = nil
+	t.lastSeriesWriter = nil
+	t.lastSessorId = ""
 }
 
-func (t *TsFileWriter) Write(tr *TsRecord) bool {
+// Write adds a record. A record holding a data point of an unknown sensor or of a value not of the data type of its
+// sensor is rejected as a whole, with an UnknownSensorError or a TypeMismatchError. Records may be flushed to the file,
+// whose failure is returned as an IOError.
+func (t *TsFileWriter) Write(tr *TsRecord) error {
+	if t.closed {
+		return ErrWriterClosed
+	}
+	if err := t.ioError(); err != nil {
+		return err
+	}
+	if err := t.checkRecord(tr); err != nil {
+		return err
+	}
+
 	// write data here
 	//gd, ok := t.checkIsDeviceExist(tr, t.schema)
 	//tsCurNew2 := time.Now()
@@ -153,7 +209,7 @@ func (t *TsFileWriter) Write(tr *TsRecord) bool {
 					// new pagewriter
 					pw, _ := NewPageWriter(sensorDescriptor)
 					// new serieswrite
-					dataSW, _ = NewSeriesWriter(strDeviceID, sensorDescriptor, pw, conf.PageSizeInByte)
+					dataSW, _ = NewSeriesWriter(strDeviceID, sensorDescriptor, pw, t.config)
 					gd.dataSeriesWriters[sessorID] = dataSW
 					ok = true
 				}
@@ -184,6 +240,9 @@ func (t *TsFileWriter) Write(tr *TsRecord) bool {
 				dataSW.valueCount++
 				// statistics ignore here, if necessary, Statistics.java
 				dataSW.pageStatistics.UpdateStats(valueInterface)
+				if dataSW.extendedStatistics != nil {
+					dataSW.extendedStatistics.Update(valueInterface)
+				}
 
 				if dataSW.minTimestamp == -1 {
 					dataSW.minTimestamp = timeST
@@ -191,7 +250,7 @@ func (t *TsFileWriter) Write(tr *TsRecord) bool {
 				//tsCurNew2 = time.Now()
 				// check page size and write page data to buffer
 				//dataSW.checkPageSizeAndMayOpenNewpage()
-				if dataSW.valueCount == conf.MaxNumberOfPointsInPage {
+				if dataSW.valueCount == dataSW.pageCountUpperBound {
 					dataSW.WritePage()
 				} else if dataSW.valueCount >= dataSW.valueCountForNextSizeCheck {
 					currentColumnSize := valueWriter.GetCurrentMemSize()
@@ -206,10 +265,18 @@ func (t *TsFileWriter) Write(tr *TsRecord) bool {
 		}
 	}
 	t.recordCount++
-	return t.checkMemorySizeAndMayFlushGroup()
+	t.checkMemorySizeAndMayFlushGroup()
+	return t.ioError()
 }
 
-func (t *TsFileWriter) Close() bool {
+// Close flushes the records and writes the metadata at the end of the file, then closes the file opened by
+// NewTsFileWriter. It returns an IOError if writing or closing the file failed.
+func (t *TsFileWriter) Close() error {
+	if t.closed {
+		return ErrWriterClosed
+	}
+	t.closed = true
+
 	// finished write file, and write magic string at file tail
 	//t.tsFileIoWriter.WriteMagic()
 	//t.tsFileIoWriter.tsIoFile.Write([]byte("\n"))
@@ -221,7 +288,11 @@ func (t *TsFileWriter) Close() bool {
 	t.lastGroupDevice = nil
 	t.lastSeriesWriter = nil
 	t.lastSessorId = ""
-	return true
+	writeErr := t.ioError()
+	if err := t.tsFileIoWriter.Close(); err != nil && writeErr == nil {
+		return &IOError{Op: "close", Err: err}
+	}
+	return writeErr
 }
 
 func (t *TsFileWriter) checkMemorySizeAndMayFlushGroup() bool {
@@ -282,7 +353,7 @@ func (t *TsFileWriter) checkIsDeviceExist(tr *TsRecord, schema *fileSchema.FileS
 		//	t.groupDevices[tr.GetDeviceId()].AddSeriesWriter(schemaSensorDescriptorMap[v.GetSensorId()], conf.PageSizeInByte)
 		sensorDescriptor, bExistSensorDesc := schemaSensorDescriptorMap[v.GetSensorId()]
 		if bExistSensorDesc {
-			groupDevice.AddSeriesWriter(sensorDescriptor, conf.PageSizeInByte)
+			groupDevice.AddSeriesWriter(sensorDescriptor, t.config)
 		} else {
 			log.Error("input sensor is invalid: ", v.GetSensorId())
 		}
@@ -291,25 +362,67 @@ func (t *TsFileWriter) checkIsDeviceExist(tr *TsRecord, schema *fileSchema.FileS
 	return groupDevice, true
 }
 
-func NewTsFileWriter(file string) (*TsFileWriter, error) {
-	// file schema
-	fs, fsErr := fileSchema.New()
-	if fsErr != nil {
-		log.Error("init fileSchema failed.")
+// NewTsFileWriter returns a TsFileWriter appending a TsFile to the named file, creating it if needed, with the
+// settings of config if given, see conf.WriterConfig, those of conf.DefaultWriterConfig otherwise. It returns a
+// conf.ConfigError if the config is invalid and an IOError if the file cannot be opened.
+func NewTsFileWriter(file string, config ...*conf.WriterConfig) (*TsFileWriter, error) {
+	c, err := writerConfig(config)
+	if err != nil {
+		return nil, err
 	}
-
 	// tsFileIoWriter
 	tfiWriter, tfiwErr := NewTsFileIoWriter(file)
 	if tfiwErr != nil {
-		log.Error("init tsFileWriter error = ", tfiwErr)
+		return nil, tfiwErr
+	}
+	return newTsFileWriter(tfiWriter, c)
+}
+
+// NewTsFileWriterTo returns a TsFileWriter streaming the TsFile to w, which needs not be seekable, e.g. a buffer, a
+// pipe, an HTTP response or a compression stream. Close writes the metadata at the end of the file but leaves w open.
+// The config is that of NewTsFileWriter.
+func NewTsFileWriterTo(w io.Writer, config ...*conf.WriterConfig) (*TsFileWriter, error) {
+	c, err := writerConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return newTsFileWriter(NewTsFileIoWriterTo(w), c)
+}
+
+// writerConfig returns a validated copy of the config given, if any, so that changing it does not affect the writer.
+func writerConfig(config []*conf.WriterConfig) (*conf.WriterConfig, error) {
+	var c conf.WriterConfig
+	switch len(config) {
+	case 0:
+		c = *conf.DefaultWriterConfig()
+	case 1:
+		c = *config[0]
+	default:
+		return nil, fmt.Errorf("expected at most one writer config, got %d", len(config))
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func newTsFileWriter(tfiWriter *TsFileIoWriter, config *conf.WriterConfig) (*TsFileWriter, error) {
+	// file schema
+	fs, fsErr := fileSchema.NewWithConfig(config)
+	if fsErr != nil {
+		log.Error("init fileSchema failed.")
 	}
 
 	// write start magic
 	tfiWriter.WriteMagic()
+	if err := tfiWriter.Err(); err != nil {
+		tfiWriter.Close()
+		return nil, &IOError{Op: "write", Err: err}
+	}
 
 	// init rowGroupSizeThreshold
-	var prgs int64 = int64(conf.GroupSizeInByte)
-	rgst := int64(conf.GroupSizeInByte) - prgs
+	var prgs int64 = int64(config.GroupSizeInByte)
+	rgst := int64(config.GroupSizeInByte) - prgs
 
 	return &TsFileWriter{
 		tsFileIoWriter:             tfiWriter,
@@ -319,5 +432,6 @@ func NewTsFileWriter(file string) (*TsFileWriter, error) {
 		primaryRowGroupSize:        prgs,
 		rowGroupSizeThreshold:      rgst,
 		groupDevices:               make(map[string]*RowGroupWriter),
+		config:                     config,
 	}, nil
 }
@@ -0,0 +1,406 @@
+package tsFileWriter_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"tsfile/common/conf"
+	"tsfile/common/constant"
+	"tsfile/timeseries/filter"
+	"tsfile/timeseries/filter/operator"
+	"tsfile/timeseries/query"
+	"tsfile/timeseries/query/engine"
+	"tsfile/timeseries/read"
+	"tsfile/timeseries/write/sensorDescriptor"
+	"tsfile/timeseries/write/tsFileWriter"
+)
+
+var tempFilePath = "temp_TsFile"
+
+// openEngine opens the TsFile written at path for querying.
+func openEngine(path string, t *testing.T) *engine.Engine {
+	f := new(read.TsFileSequenceReader)
+	if err := f.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	e := new(engine.Engine)
+	if err := e.Open(f); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestWriteTo(t *testing.T) {
+	/*
+		Assumed data layout, streamed through a pipe, 10 points per page:
+		root.d0.s0 : [1,1], [2,2], ..., [100,100]
+		root.d0.s1 : [1,0.5], [2,1.0], ..., [100,50.0]
+	*/
+	pagePoints := conf.MaxNumberOfPointsInPage
+	conf.MaxNumberOfPointsInPage = 10
+	defer func() {
+		conf.MaxNumberOfPointsInPage = pagePoints
+		os.Remove(tempFilePath)
+	}()
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		writer, err := tsFileWriter.NewTsFileWriterTo(pipeWriter)
+		if err != nil {
+			pipeWriter.CloseWithError(err)
+			return
+		}
+		des, _ := sensorDescriptor.New("s0", constant.INT32, constant.RLE)
+		writer.AddSensor(des)
+		des, _ = sensorDescriptor.NewWithCompress("s1", constant.DOUBLE, constant.PLAIN, constant.SNAPPY)
+		writer.AddSensor(des)
+		for t := int64(1); t <= 100; t++ {
+			record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
+			pt, _ := tsFileWriter.NewInt("s0", constant.INT32, int32(t))
+			record.AddTuple(pt)
+			pt, _ = tsFileWriter.NewDouble("s1", constant.DOUBLE, float64(t)*0.5)
+			record.AddTuple(pt)
+			writer.Write(record)
+		}
+		if err := writer.Close(); err != nil {
+			pipeWriter.CloseWithError(err)
+			return
+		}
+		pipeWriter.Close()
+	}()
+	data, err := ioutil.ReadAll(pipeReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(conf.MAGIC_STRING)) || !bytes.HasSuffix(data, []byte(conf.MAGIC_STRING)) {
+		t.Fatal("Expected the TsFile to start and end with the magic string")
+	}
+
+	// the offsets in the metadata are those of the streamed bytes
+	if err := ioutil.WriteFile(tempFilePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := openEngine(tempFilePath, t)
+	defer e.Close()
+	results, err := e.Aggregate("root.d0.s1", []constant.AggregationType{constant.COUNT, constant.SUM}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0] != int64(100) || results[1] != 2525.0 {
+		t.Fatal(fmt.Sprintf("Expected 100 points summing to 2525 got %v", results))
+	}
+	exp := new(query.QueryExpression)
+	exp.SetSelectPaths([]string{"root.d0.s0"})
+	exp.SetFilter(filter.NewRowRecordValFilter("root.d0.s0", &operator.IntGtEqFilter{Ref: 99}))
+	var values []interface{}
+	dataSet := e.Query(exp)
+	for dataSet.HasNext() {
+		record, err := dataSet.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, record.Values()...)
+	}
+	if len(values) != 2 || values[0] != int32(99) || values[1] != int32(100) {
+		t.Fatal(fmt.Sprintf("Expected the last 2 points got %v", values))
+	}
+}
+
+type limitedWriter struct {
+	n int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		w.n = 0
+		return 0, errors.New("disk full")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestWriterErrors(t *testing.T) {
+	defer os.Remove(tempFilePath)
+	os.Remove(tempFilePath)
+	writer, err := tsFileWriter.NewTsFileWriter(tempFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	des, _ := sensorDescriptor.New("s0", constant.DOUBLE, constant.GORILLA)
+	if err := writer.AddSensor(des); err != nil {
+		t.Fatal(err)
+	}
+	var sensorErr *tsFileWriter.SensorError
+	des, _ = sensorDescriptor.New("s0", constant.INT32, constant.RLE)
+	if err := writer.AddSensor(des); !errors.As(err, &sensorErr) || sensorErr.SensorId != "s0" {
+		t.Fatal(fmt.Sprintf("Expected a SensorError for a data type change got %v", err))
+	}
+	des, _ = sensorDescriptor.New("s1", constant.INT32, constant.GORILLA)
+	if err := writer.AddSensor(des); !errors.As(err, &sensorErr) || sensorErr.SensorId != "s1" {
+		t.Fatal(fmt.Sprintf("Expected a SensorError for an unsupported encoding got %v", err))
+	}
+
+	var unknownErr *tsFileWriter.UnknownSensorError
+	record, _ := tsFileWriter.NewTsRecordUseTimestamp(1, "root.d0")
+	pt, _ := tsFileWriter.NewInt("s1", constant.INT32, 1)
+	record.AddTuple(pt)
+	if err := writer.Write(record); !errors.As(err, &unknownErr) || unknownErr.SensorId != "s1" {
+		t.Fatal(fmt.Sprintf("Expected an UnknownSensorError got %v", err))
+	}
+	var mismatchErr *tsFileWriter.TypeMismatchError
+	record, _ = tsFileWriter.NewTsRecordUseTimestamp(1, "root.d0")
+	pt, _ = tsFileWriter.NewInt("s0", constant.INT32, 1)
+	record.AddTuple(pt)
+	if err := writer.Write(record); !errors.As(err, &mismatchErr) || mismatchErr.DataType != constant.DOUBLE {
+		t.Fatal(fmt.Sprintf("Expected a TypeMismatchError got %v", err))
+	}
+	record, _ = tsFileWriter.NewTsRecordUseTimestamp(2, "root.d0")
+	pt, _ = tsFileWriter.NewDouble("s0", constant.DOUBLE, 2.5)
+	record.AddTuple(pt)
+	if err := writer.Write(record); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Write(record); err != tsFileWriter.ErrWriterClosed {
+		t.Fatal(fmt.Sprintf("Expected ErrWriterClosed got %v", err))
+	}
+	if err := writer.Close(); err != tsFileWriter.ErrWriterClosed {
+		t.Fatal(fmt.Sprintf("Expected ErrWriterClosed got %v", err))
+	}
+
+	// only the rejected records are missing
+	e := openEngine(tempFilePath, t)
+	results, err := e.Aggregate("root.d0.s0", []constant.AggregationType{constant.COUNT, constant.SUM}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Close()
+	if results[0] != int64(1) || results[1] != 2.5 {
+		t.Fatal(fmt.Sprintf("Expected the only accepted point got %v", results))
+	}
+
+	// a writer failing once the magic string is written
+	var ioErr *tsFileWriter.IOError
+	writer, err = tsFileWriter.NewTsFileWriterTo(&limitedWriter{len(conf.MAGIC_STRING)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	des, _ = sensorDescriptor.New("s0", constant.INT64, constant.TS_2DIFF)
+	writer.AddSensor(des)
+	for t := int64(1); t <= 10; t++ {
+		record, _ = tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
+		pt, _ = tsFileWriter.NewLong("s0", constant.INT64, t)
+		record.AddTuple(pt)
+		writer.Write(record)
+	}
+	if err := writer.Close(); !errors.As(err, &ioErr) || ioErr.Err.Error() != "disk full" {
+		t.Fatal(fmt.Sprintf("Expected an IOError got %v", err))
+	}
+	if _, err := tsFileWriter.NewTsFileWriterTo(&limitedWriter{0}); !errors.As(err, &ioErr) {
+		t.Fatal(fmt.Sprintf("Expected an IOError got %v", err))
+	}
+}
+
+func TestWriterConfig(t *testing.T) {
+	/*
+		Assumed data layout, written concurrently by a hot and an archival writer:
+		root.d0.s0 : [1,0.125], [2,0.25], ..., [100,12.5]
+		hot: 10 points per page, RLE, SNAPPY, extended digest
+		archival: a single page, GORILLA, UNCOMPRESSED
+	*/
+	hot := conf.DefaultWriterConfig()
+	hot.MaxNumberOfPointsInPage, hot.ValueEncoder, hot.Compressor = 10, "RLE", "SNAPPY"
+	hot.FloatPrecision, hot.ExtendedDigest = 3, true
+	archival := conf.DefaultWriterConfig()
+	archival.MaxNumberOfPointsInPage, archival.ValueEncoder, archival.Compressor = 1000, "GORILLA", "UNCOMPRESSED"
+	pagePoints := conf.MaxNumberOfPointsInPage
+
+	configs := []*conf.WriterConfig{hot, archival}
+	files := make([]*bytes.Buffer, len(configs))
+	errs := make([]error, len(configs))
+	var wg sync.WaitGroup
+	for i, config := range configs {
+		files[i] = new(bytes.Buffer)
+		wg.Add(1)
+		go func(i int, config *conf.WriterConfig) {
+			defer wg.Done()
+			writer, err := tsFileWriter.NewTsFileWriterTo(files[i], config)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			des, err := sensorDescriptor.NewWithConfig("s0", constant.DOUBLE, config)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			writer.AddSensor(des)
+			for t := int64(1); t <= 100; t++ {
+				record, _ := tsFileWriter.NewTsRecordUseTimestamp(t, "root.d0")
+				pt, _ := tsFileWriter.NewDouble("s0", constant.DOUBLE, float64(t)*0.125)
+				record.AddTuple(pt)
+				if errs[i] = writer.Write(record); errs[i] != nil {
+					return
+				}
+			}
+			errs[i] = writer.Close()
+		}(i, config)
+	}
+	wg.Wait()
+	if conf.MaxNumberOfPointsInPage != pagePoints {
+		t.Fatal("Expected the writers to leave the package settings unchanged")
+	}
+
+	expected := []struct {
+		pages       int
+		encoding    constant.TSEncoding
+		compression constant.CompressionType
+		extended    bool
+	}{{10, constant.RLE, constant.SNAPPY, true}, {1, constant.GORILLA, constant.UNCOMPRESSED, false}}
+	for i, e := range expected {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		f := new(read.TsFileSequenceReader)
+		if err := f.OpenBytes(files[i].Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		fileMeta, err := f.ReadFileMetadata()
+		if err != nil {
+			t.Fatal(err)
+		}
+		queryEngine := new(engine.Engine)
+		if err := queryEngine.Open(f); err != nil {
+			t.Fatal(err)
+		}
+		chunk := fileMeta.DeviceMap()["root.d0"].GetRowGroups()[0].GetChunkMetaDataSli()[0]
+		chunkHeader, err := f.ReadChunkHeaderAt(chunk.FileOffsetOfCorrespondingData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunkHeader.GetNumberOfPages() != e.pages || chunkHeader.GetEncodingType() != e.encoding ||
+			chunkHeader.GetCompressionType() != e.compression {
+			t.Fatal(fmt.Sprintf("Expected %d pages encoded with %s and compressed with %s got %d, %s and %s", e.pages,
+				e.encoding, e.compression, chunkHeader.GetNumberOfPages(), chunkHeader.GetEncodingType(),
+				chunkHeader.GetCompressionType()))
+		}
+		if _, ok := chunk.GetDigest().GetExtendedStatistics(); ok != e.extended {
+			t.Fatal(fmt.Sprintf("Expected extended statistics %v got %v", e.extended, ok))
+		}
+		results, err := queryEngine.Aggregate("root.d0.s0", []constant.AggregationType{constant.COUNT, constant.SUM}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0] != int64(100) || results[1] != 631.25 {
+			t.Fatal(fmt.Sprintf("Expected 100 points summing to 631.25 got %v", results))
+		}
+		queryEngine.Close()
+	}
+
+	// loaders, the keys missing keeping their default value
+	config, err := conf.LoadProperties(strings.NewReader(
+		"# hot\npage_size_in_byte=1024\nmax_number_of_points_in_page = 20\ncompressor=SNAPPY\nother_key=x\n"))
+	if err != nil || config.PageSizeInByte != 1024 || config.MaxNumberOfPointsInPage != 20 ||
+		config.Compressor != "SNAPPY" || config.GroupSizeInByte != conf.GroupSizeInByte {
+		t.Fatal(fmt.Sprintf("Unexpected properties config %+v, %v", config, err))
+	}
+	config, err = conf.LoadJSON(strings.NewReader(
+		`{"max_number_of_points_in_page": 30, "value_encoder": "TS_2DIFF", "extended_digest": true}`))
+	if err != nil || config.MaxNumberOfPointsInPage != 30 || config.ValueEncoder != "TS_2DIFF" ||
+		!config.ExtendedDigest || config.PageSizeInByte != conf.PageSizeInByte {
+		t.Fatal(fmt.Sprintf("Unexpected JSON config %+v, %v", config, err))
+	}
+	yaml := "---\n# archival\nmax_number_of_points_in_page: 40\ncompressor: 'SNAPPY'\nfloat_precision: 4 # digits\n"
+	config, err = conf.LoadYAML(strings.NewReader(yaml))
+	if err != nil || config.MaxNumberOfPointsInPage != 40 || config.Compressor != "SNAPPY" ||
+		config.FloatPrecision != 4 {
+		t.Fatal(fmt.Sprintf("Unexpected YAML config %+v, %v", config, err))
+	}
+	configPath := "temp_writer_config.yml"
+	if err := ioutil.WriteFile(configPath, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(configPath)
+	if config, err = conf.LoadWriterConfig(configPath); err != nil || config.MaxNumberOfPointsInPage != 40 {
+		t.Fatal(fmt.Sprintf("Unexpected YAML file config %+v, %v", config, err))
+	}
+	if _, err = conf.LoadWriterConfig("temp_writer_config.toml"); err == nil {
+		t.Fatal("Expected an unknown config format to fail")
+	}
+	t.Setenv("TSFILE_MAX_NUMBER_OF_POINTS_IN_PAGE", "50")
+	t.Setenv("TSFILE_EXTENDED_DIGEST", "true")
+	config, err = conf.LoadEnv("TSFILE_")
+	if err != nil || config.MaxNumberOfPointsInPage != 50 || !config.ExtendedDigest {
+		t.Fatal(fmt.Sprintf("Unexpected environment config %+v, %v", config, err))
+	}
+
+	// validation names the invalid key
+	invalid := []struct {
+		load  func() (*conf.WriterConfig, error)
+		key   string
+		cause error
+	}{
+		{func() (*conf.WriterConfig, error) {
+			return conf.LoadProperties(strings.NewReader("page_size_in_byte=0"))
+		},
+			"page_size_in_byte", nil},
+		{func() (*conf.WriterConfig, error) { return conf.LoadProperties(strings.NewReader("float_precision=x")) },
+			"float_precision", nil},
+		{func() (*conf.WriterConfig, error) { return conf.LoadJSON(strings.NewReader(`{"compressor": "GZIP"}`)) },
+			"compressor", nil},
+		{func() (*conf.WriterConfig, error) {
+			return conf.LoadJSON(strings.NewReader(`{"group_size_in_byte": "x"}`))
+		},
+			"group_size_in_byte", nil},
+		{func() (*conf.WriterConfig, error) { return conf.LoadJSON(strings.NewReader(`{"page_size": 1}`)) }, "", nil},
+		{func() (*conf.WriterConfig, error) {
+			return conf.LoadYAML(strings.NewReader("time_series_encoder: GORILLA"))
+		}, "time_series_encoder", nil},
+		{func() (*conf.WriterConfig, error) { return conf.LoadYAML(strings.NewReader("page_size: 1")) }, "page_size",
+			conf.ErrUnknownKey},
+		{func() (*conf.WriterConfig, error) {
+			return conf.LoadYAML(strings.NewReader("writer:\n  compressor: SNAPPY"))
+		},
+			"", nil},
+		{func() (*conf.WriterConfig, error) {
+			os.Setenv("TSFILE_PAGE_SIZE_IN_BYTE", "x")
+			defer os.Unsetenv("TSFILE_PAGE_SIZE_IN_BYTE")
+			return conf.LoadEnv("TSFILE_")
+		}, "TSFILE_PAGE_SIZE_IN_BYTE", nil},
+	}
+	for i, c := range invalid {
+		var configErr *conf.ConfigError
+		config, err := c.load()
+		if err == nil || config != nil {
+			t.Fatal(fmt.Sprintf("Expected config %d to be invalid got %+v", i, config))
+		}
+		if c.key != "" && (!errors.As(err, &configErr) || configErr.Key != c.key) {
+			t.Fatal(fmt.Sprintf("Expected config %d to be rejected for %s got %v", i, c.key, err))
+		}
+		if c.cause != nil && !errors.Is(err, c.cause) {
+			t.Fatal(fmt.Sprintf("Expected config %d to be rejected with %v got %v", i, c.cause, err))
+		}
+	}
+
+	var configErr *conf.ConfigError
+	invalidConfig := conf.DefaultWriterConfig()
+	invalidConfig.MaxNumberOfPointsInPage = 0
+	if _, err := tsFileWriter.NewTsFileWriterTo(new(bytes.Buffer), invalidConfig); !errors.As(err, &configErr) ||
+		configErr.Key != "max_number_of_points_in_page" {
+		t.Fatal(fmt.Sprintf("Expected the writer to reject the config got %v", err))
+	}
+	if _, err := tsFileWriter.NewTsFileWriterTo(new(bytes.Buffer), hot, archival); err == nil {
+		t.Fatal("Expected the writer to reject two configs")
+	}
+	if _, err := sensorDescriptor.NewWithConfig("s0", constant.INT32, archival); err == nil {
+		t.Fatal("Expected GORILLA not to encode INT32 values")
+	}
+}
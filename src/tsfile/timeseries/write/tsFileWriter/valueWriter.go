@@ -155,13 +155,21 @@ func (v *ValueWriter) Reset() {
 	return
 }
 
-func NewValueWriter(d *sensorDescriptor.SensorDescriptor) (*ValueWriter, error) {
+func NewValueWriter(d *sensorDescriptor.SensorDescriptor, config *conf.WriterConfig) (*ValueWriter, error) {
+	timeEncoder, err := d.NewTimeEncoder(config)
+	if err != nil {
+		return nil, err
+	}
+	valueEncoder, err := d.NewValueEncoder(config)
+	if err != nil {
+		return nil, err
+	}
 	return &ValueWriter{
 		//sensorId:sId,
 		timeBuf:      bytes.NewBuffer([]byte{}),
 		valueBuf:     bytes.NewBuffer([]byte{}),
 		desc:         d,
-		timeEncoder:  d.GetTimeEncoder(),
-		valueEncoder: d.GetValueEncoder(),
+		timeEncoder:  timeEncoder,
+		valueEncoder: valueEncoder,
 	}, nil
 }